@@ -0,0 +1,20 @@
+// Command snapshot-schema prints the JSON Schema for the current snapshot
+// version. Run "make schema" to refresh schemas/ after changing the
+// snapshot types.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+)
+
+func main() {
+	data, err := snapshot.JSONSchema()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println(string(data))
+}
@@ -1,11 +1,13 @@
 package brew
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net"
 	"os"
 	"os/exec"
+	"regexp"
 	"strings"
 	"sync"
 	"syscall"
@@ -66,6 +68,132 @@ func GetInstalledPackages() (formulae map[string]bool, casks map[string]bool, er
 	return
 }
 
+// GetLeaves returns installed formulae that no other installed formula
+// depends on, as reported by 'brew leaves'.
+func GetLeaves() (map[string]bool, error) {
+	out, err := exec.Command("brew", "leaves").Output()
+	if err != nil {
+		return nil, err
+	}
+	leaves := make(map[string]bool)
+	for _, name := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if name != "" {
+			leaves[name] = true
+		}
+	}
+	return leaves, nil
+}
+
+// PackageInfo identifies an installed formula or cask precisely enough to
+// reinstall it.
+type PackageInfo struct {
+	Name    string
+	Tap     string
+	Version string
+	// Apps are the app bundles a cask installs, relative to /Applications
+	// unless absolute.
+	Apps []string
+}
+
+// GetPackageInfo looks up the tap and installed version of each package,
+// keyed by the name it was given as.
+func GetPackageInfo(names []string, cask bool) (map[string]PackageInfo, error) {
+	info := make(map[string]PackageInfo, len(names))
+	if len(names) == 0 {
+		return info, nil
+	}
+
+	kind := "--formula"
+	if cask {
+		kind = "--cask"
+	}
+	args := append([]string{"info", "--json=v2", kind}, names...)
+	output, err := exec.Command("brew", args...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Formulae []struct {
+			Name      string `json:"name"`
+			FullName  string `json:"full_name"`
+			Tap       string `json:"tap"`
+			Installed []struct {
+				Version string `json:"version"`
+			} `json:"installed"`
+		} `json:"formulae"`
+		Casks []struct {
+			Token     string            `json:"token"`
+			FullToken string            `json:"full_token"`
+			Tap       string            `json:"tap"`
+			Installed string            `json:"installed"`
+			Artifacts []json.RawMessage `json:"artifacts"`
+		} `json:"casks"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]PackageInfo)
+	for _, f := range result.Formulae {
+		pkg := PackageInfo{Name: f.Name, Tap: f.Tap}
+		if len(f.Installed) > 0 {
+			pkg.Version = f.Installed[len(f.Installed)-1].Version
+		}
+		found[f.Name] = pkg
+		found[f.FullName] = pkg
+	}
+	for _, c := range result.Casks {
+		pkg := PackageInfo{Name: c.Token, Tap: c.Tap, Version: c.Installed, Apps: caskApps(c.Artifacts)}
+		found[c.Token] = pkg
+		found[c.FullToken] = pkg
+	}
+	for _, name := range names {
+		if pkg, ok := found[name]; ok {
+			info[name] = pkg
+		}
+	}
+	return info, nil
+}
+
+// caskApps pulls app bundle names out of cask artifacts, which look like
+// {"app": ["Foo.app", {"target": "Bar.app"}]}; a target renames the app
+// before it.
+func caskApps(artifacts []json.RawMessage) []string {
+	var apps []string
+	for _, raw := range artifacts {
+		var artifact struct {
+			App []json.RawMessage `json:"app"`
+		}
+		if json.Unmarshal(raw, &artifact) != nil {
+			continue
+		}
+		for _, entry := range artifact.App {
+			var name string
+			if json.Unmarshal(entry, &name) == nil {
+				apps = append(apps, name)
+				continue
+			}
+			var opts struct {
+				Target string `json:"target"`
+			}
+			if json.Unmarshal(entry, &opts) == nil && opts.Target != "" && len(apps) > 0 {
+				apps[len(apps)-1] = opts.Target
+			}
+		}
+	}
+	return apps
+}
+
+// Prefix returns the Homebrew installation prefix.
+func Prefix() (string, error) {
+	out, err := exec.Command("brew", "--prefix").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
 func ListOutdated() ([]OutdatedPackage, error) {
 	cmd := exec.Command("brew", "outdated", "--json")
 	output, err := cmd.Output()
@@ -154,14 +282,19 @@ func InstallTaps(taps []string, dryRun bool) error {
 
 	ui.Info(fmt.Sprintf("Adding %d Homebrew taps...", len(taps)))
 
+	var failed []string
 	for _, tap := range taps {
 		cmd := exec.Command("brew", "tap", tap)
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 		if err := cmd.Run(); err != nil {
 			ui.Warn(fmt.Sprintf("Failed to tap %s: %v", tap, err))
+			failed = append(failed, tap)
 		}
 	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d taps failed: %s", len(failed), strings.Join(failed, ", "))
+	}
 	return nil
 }
 
@@ -316,9 +449,31 @@ func InstallWithProgress(cliPkgs, caskPkgs []string, dryRun bool) error {
 
 	handleFailedJobs(allFailed)
 
+	if len(allFailed) > 0 {
+		installErr := &InstallError{}
+		for _, f := range allFailed {
+			if f.isCask {
+				installErr.Casks = append(installErr.Casks, f.name)
+			} else {
+				installErr.Formulae = append(installErr.Formulae, f.name)
+			}
+		}
+		return installErr
+	}
 	return nil
 }
 
+// InstallError is returned by InstallWithProgress when some packages could
+// not be installed; every other package was installed or already present.
+type InstallError struct {
+	Formulae []string
+	Casks    []string
+}
+
+func (e *InstallError) Error() string {
+	return fmt.Sprintf("%d packages failed to install", len(e.Formulae)+len(e.Casks))
+}
+
 func handleFailedJobs(failed []failedJob) {
 	if len(failed) == 0 {
 		return
@@ -650,6 +805,145 @@ func Cleanup() error {
 	return exec.Command("brew", "cleanup").Run()
 }
 
+// freedPattern matches the summary 'brew cleanup' ends with, in both its
+// dry-run ("would free") and real ("has freed") forms.
+var freedPattern = regexp.MustCompile(`(?:would free|has freed) approximately (\S+) of disk space`)
+
+// CleanupReclaimable runs 'brew cleanup --dry-run' and returns how much
+// space a cleanup would free as brew words it, e.g. "1.2GB", or "" if
+// there is nothing to clean.
+func CleanupReclaimable() (string, error) {
+	out, err := exec.Command("brew", "cleanup", "--dry-run").Output()
+	if err != nil {
+		return "", err
+	}
+	if m := freedPattern.FindStringSubmatch(string(out)); m != nil {
+		return m[1], nil
+	}
+	return "", nil
+}
+
+// CleanupReport runs 'brew cleanup' and returns how much space it freed,
+// or "" if brew didn't say.
+func CleanupReport() (string, error) {
+	out, err := exec.Command("brew", "cleanup").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("brew cleanup: %s", strings.TrimSpace(string(out)))
+	}
+	if m := freedPattern.FindStringSubmatch(string(out)); m != nil {
+		return m[1], nil
+	}
+	return "", nil
+}
+
+// AutoremoveCandidates returns the formulae 'brew autoremove' would
+// remove: dependencies nothing installed needs any more.
+func AutoremoveCandidates() ([]string, error) {
+	out, err := exec.Command("brew", "autoremove", "--dry-run").Output()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "==>") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, nil
+}
+
+// Autoremove removes orphaned dependencies.
+func Autoremove(dryRun bool) error {
+	if dryRun {
+		ui.Info("Would remove orphaned dependencies:")
+		fmt.Println("    brew autoremove")
+		return nil
+	}
+	cmd := exec.Command("brew", "autoremove")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// ListTaps returns the taps currently added.
+func ListTaps() ([]string, error) {
+	out, err := exec.Command("brew", "tap").Output()
+	if err != nil {
+		return nil, err
+	}
+	var taps []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			taps = append(taps, line)
+		}
+	}
+	return taps, nil
+}
+
+// GetInstalledByTap returns the short names of installed formulae and
+// casks, grouped by the tap they came from.
+func GetInstalledByTap() (map[string][]string, error) {
+	out, err := exec.Command("brew", "info", "--json=v2", "--installed").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Formulae []struct {
+			Name string `json:"name"`
+			Tap  string `json:"tap"`
+		} `json:"formulae"`
+		Casks []struct {
+			Token string `json:"token"`
+			Tap   string `json:"tap"`
+		} `json:"casks"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, err
+	}
+
+	byTap := make(map[string][]string)
+	for _, f := range result.Formulae {
+		byTap[f.Tap] = append(byTap[f.Tap], f.Name)
+	}
+	for _, c := range result.Casks {
+		byTap[c.Tap] = append(byTap[c.Tap], c.Token)
+	}
+	return byTap, nil
+}
+
+func Untap(taps []string, dryRun bool) error {
+	if len(taps) == 0 {
+		return nil
+	}
+
+	if dryRun {
+		ui.Info("Would remove taps:")
+		for _, t := range taps {
+			fmt.Printf("    brew untap %s\n", t)
+		}
+		return nil
+	}
+
+	var failed []string
+	for _, tap := range taps {
+		cmd := exec.Command("brew", "untap", tap)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			ui.Warn(fmt.Sprintf("Failed to untap %s: %s", tap, strings.TrimSpace(string(output))))
+			failed = append(failed, tap)
+		} else {
+			ui.Success(fmt.Sprintf("  ✔ Untapped %s", tap))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d taps failed to untap", len(failed))
+	}
+	return nil
+}
+
 func CheckNetwork() error {
 	hosts := []string{"github.com:443", "raw.githubusercontent.com:443"}
 	for _, host := range hosts {
@@ -677,10 +971,61 @@ func CheckDiskSpace() (float64, error) {
 	return availableGB, nil
 }
 
+// Remediation is a suggested fix for a 'brew doctor' finding. Args is the
+// brew command that applies it, or nil when it has to be done by hand
+// (for example because it needs sudo).
+type Remediation struct {
+	Suggestion string
+	Args       []string
+}
+
+// doctorRules maps lowercased 'brew doctor' output to a remediation.
+var doctorRules = []struct {
+	match func(output string) bool
+	fix   Remediation
+}{
+	{containsAny("unbrewed header files"), Remediation{Suggestion: "Run: sudo rm -rf /usr/local/include"}},
+	{containsAny("unbrewed dylibs"), Remediation{Suggestion: "Run: brew doctor --list-checks and review linked libraries"}},
+	{func(o string) bool { return strings.Contains(o, "homebrew/core") && strings.Contains(o, "tap") },
+		Remediation{Suggestion: "Run: brew untap homebrew/core homebrew/cask", Args: []string{"untap", "homebrew/core", "homebrew/cask"}}},
+	{containsAny("git origin remote"), Remediation{Suggestion: "Run: brew update-reset", Args: []string{"update-reset"}}},
+	{containsAny("broken symlinks"), Remediation{Suggestion: "Run: brew cleanup --prune=all", Args: []string{"cleanup", "--prune=all"}}},
+	{containsAny("outdated xcode", "command line tools"), Remediation{Suggestion: "Run: xcode-select --install"}},
+	{containsAny("uncommitted modifications"), Remediation{Suggestion: "Run: brew update-reset", Args: []string{"update-reset"}}},
+	{containsAny("permission"), Remediation{Suggestion: "Run: sudo chown -R $(whoami) $(brew --prefix)/*"}},
+}
+
+func containsAny(phrases ...string) func(string) bool {
+	return func(s string) bool {
+		for _, p := range phrases {
+			if strings.Contains(s, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 func DoctorDiagnose() ([]string, error) {
+	remediations, err := DoctorRemediations()
+	if err != nil {
+		return nil, err
+	}
+	var suggestions []string
+	for _, r := range remediations {
+		suggestions = append(suggestions, r.Suggestion)
+	}
+	return suggestions, nil
+}
+
+// DoctorRemediations runs 'brew doctor' and returns a remediation for each
+// known finding. It returns nil when Homebrew is ready to brew.
+func DoctorRemediations() ([]Remediation, error) {
 	cmd := exec.Command("brew", "doctor")
 	output, err := cmd.CombinedOutput()
-	if err != nil {
+	// brew doctor exits non-zero when it has warnings, so only fail when
+	// it printed nothing to diagnose.
+	if err != nil && len(bytes.TrimSpace(output)) == 0 {
 		return nil, fmt.Errorf("brew doctor failed: %w", err)
 	}
 	outputStr := string(output)
@@ -689,39 +1034,35 @@ func DoctorDiagnose() ([]string, error) {
 		return nil, nil
 	}
 
-	var suggestions []string
+	var remediations []Remediation
 	lowerOutput := strings.ToLower(outputStr)
 
-	if strings.Contains(lowerOutput, "unbrewed header files") {
-		suggestions = append(suggestions, "Run: sudo rm -rf /usr/local/include")
-	}
-	if strings.Contains(lowerOutput, "unbrewed dylibs") {
-		suggestions = append(suggestions, "Run: brew doctor --list-checks and review linked libraries")
-	}
-	if strings.Contains(lowerOutput, "homebrew/core") && strings.Contains(lowerOutput, "tap") {
-		suggestions = append(suggestions, "Run: brew untap homebrew/core homebrew/cask")
-	}
-	if strings.Contains(lowerOutput, "git origin remote") {
-		suggestions = append(suggestions, "Run: brew update-reset")
-	}
-	if strings.Contains(lowerOutput, "broken symlinks") {
-		suggestions = append(suggestions, "Run: brew cleanup --prune=all")
-	}
-	if strings.Contains(lowerOutput, "outdated xcode") || strings.Contains(lowerOutput, "command line tools") {
-		suggestions = append(suggestions, "Run: xcode-select --install")
-	}
-	if strings.Contains(lowerOutput, "uncommitted modifications") {
-		suggestions = append(suggestions, "Run: brew update-reset")
-	}
-	if strings.Contains(lowerOutput, "permission") {
-		suggestions = append(suggestions, "Run: sudo chown -R $(whoami) $(brew --prefix)/*")
+	for _, rule := range doctorRules {
+		if rule.match(lowerOutput) {
+			remediations = append(remediations, rule.fix)
+		}
 	}
 
-	if len(suggestions) == 0 && !strings.Contains(outputStr, "ready to brew") {
-		suggestions = append(suggestions, "Run 'brew doctor' to see full diagnostic output")
+	if len(remediations) == 0 {
+		remediations = append(remediations, Remediation{Suggestion: "Run 'brew doctor' to see full diagnostic output"})
 	}
 
-	return suggestions, nil
+	return remediations, nil
+}
+
+// RunRemediation applies r. It fails for remediations that must be done
+// by hand.
+func RunRemediation(r Remediation) error {
+	if len(r.Args) == 0 {
+		return fmt.Errorf("no automatic fix for %q", r.Suggestion)
+	}
+	cmd := exec.Command("brew", r.Args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("brew %s: %w", strings.Join(r.Args, " "), err)
+	}
+	return nil
 }
 
 func PreInstallChecks(packageCount int) error {
@@ -1,6 +1,7 @@
 package brew
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -38,6 +39,125 @@ func TestGetInstalledPackages_ParsesOutput(t *testing.T) {
 	assert.True(t, casks["firefox"])
 }
 
+func TestGetLeaves_ParsesOutput(t *testing.T) {
+	setupFakeBrew(t, "#!/bin/sh\n"+
+		"if [ \"$1\" = \"leaves\" ]; then\n"+
+		"  echo git\n"+
+		"  echo user/tap/tool\n"+
+		"  exit 0\n"+
+		"fi\n"+
+		"exit 1\n")
+
+	leaves, err := GetLeaves()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"git": true, "user/tap/tool": true}, leaves)
+}
+
+func TestGetPackageInfo_ParsesJSON(t *testing.T) {
+	setupFakeBrew(t, "#!/bin/sh\n"+
+		"if [ \"$1\" = \"info\" ] && [ \"$3\" = \"--formula\" ]; then\n"+
+		"  echo '{\"formulae\":[{\"name\":\"tool\",\"full_name\":\"user/tap/tool\",\"tap\":\"user/tap\",\"installed\":[{\"version\":\"1.0\"},{\"version\":\"1.1\"}]}],\"casks\":[]}'\n"+
+		"  exit 0\n"+
+		"fi\n"+
+		"if [ \"$1\" = \"info\" ] && [ \"$3\" = \"--cask\" ]; then\n"+
+		"  echo '{\"formulae\":[],\"casks\":[{\"token\":\"slack\",\"full_token\":\"slack\",\"tap\":\"homebrew/cask\",\"installed\":\"4.41\"}]}'\n"+
+		"  exit 0\n"+
+		"fi\n"+
+		"exit 1\n")
+
+	formulae, err := GetPackageInfo([]string{"user/tap/tool"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, PackageInfo{Name: "tool", Tap: "user/tap", Version: "1.1"}, formulae["user/tap/tool"])
+
+	casks, err := GetPackageInfo([]string{"slack"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, PackageInfo{Name: "slack", Tap: "homebrew/cask", Version: "4.41"}, casks["slack"])
+
+	empty, err := GetPackageInfo(nil, false)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestCaskApps(t *testing.T) {
+	artifacts := []json.RawMessage{
+		json.RawMessage(`{"uninstall":[{"quit":"com.example"}]}`),
+		json.RawMessage(`{"app":["Foo.app","Bar.app",{"target":"Renamed.app"}]}`),
+		json.RawMessage(`["not an object"]`),
+	}
+	assert.Equal(t, []string{"Foo.app", "Renamed.app"}, caskApps(artifacts))
+	assert.Empty(t, caskApps(nil))
+}
+
+func TestCleanupReclaimable_ParsesSummary(t *testing.T) {
+	setupFakeBrew(t, "#!/bin/sh\n"+
+		"if [ \"$1\" = \"cleanup\" ] && [ \"$2\" = \"--dry-run\" ]; then\n"+
+		"  echo 'Would remove: /Users/me/Library/Caches/Homebrew/wget--1.21.bottle.tar.gz (1.5MB)'\n"+
+		"  echo '==> This operation would free approximately 1.2GB of disk space.'\n"+
+		"  exit 0\n"+
+		"fi\n"+
+		"if [ \"$1\" = \"cleanup\" ]; then\n"+
+		"  echo 'Removing: /Users/me/Library/Caches/Homebrew/wget--1.21.bottle.tar.gz... (1.5MB)'\n"+
+		"  echo '==> This operation has freed approximately 1.2GB of disk space.'\n"+
+		"  exit 0\n"+
+		"fi\n"+
+		"exit 1\n")
+
+	size, err := CleanupReclaimable()
+	require.NoError(t, err)
+	assert.Equal(t, "1.2GB", size)
+
+	freed, err := CleanupReport()
+	require.NoError(t, err)
+	assert.Equal(t, "1.2GB", freed)
+}
+
+func TestCleanupReclaimable_NothingToClean(t *testing.T) {
+	setupFakeBrew(t, "#!/bin/sh\nexit 0\n")
+
+	size, err := CleanupReclaimable()
+	require.NoError(t, err)
+	assert.Empty(t, size)
+}
+
+func TestAutoremoveCandidates_ParsesOutput(t *testing.T) {
+	setupFakeBrew(t, "#!/bin/sh\n"+
+		"if [ \"$1\" = \"autoremove\" ] && [ \"$2\" = \"--dry-run\" ]; then\n"+
+		"  echo '==> Would autoremove 2 unneeded formulae:'\n"+
+		"  echo libfoo\n"+
+		"  echo python@3.11\n"+
+		"  exit 0\n"+
+		"fi\n"+
+		"exit 1\n")
+
+	orphans, err := AutoremoveCandidates()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"libfoo", "python@3.11"}, orphans)
+}
+
+func TestListTapsAndInstalledByTap(t *testing.T) {
+	setupFakeBrew(t, "#!/bin/sh\n"+
+		"if [ \"$1\" = \"tap\" ]; then\n"+
+		"  printf 'homebrew/services\\nuser/tools\\n'\n"+
+		"  exit 0\n"+
+		"fi\n"+
+		"if [ \"$1\" = \"info\" ] && [ \"$3\" = \"--installed\" ]; then\n"+
+		"  echo '{\"formulae\":[{\"name\":\"git\",\"tap\":\"homebrew/core\"},{\"name\":\"tool\",\"tap\":\"user/tools\"}],\"casks\":[{\"token\":\"toolapp\",\"tap\":\"user/tools\"}]}'\n"+
+		"  exit 0\n"+
+		"fi\n"+
+		"exit 1\n")
+
+	taps, err := ListTaps()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"homebrew/services", "user/tools"}, taps)
+
+	byTap, err := GetInstalledByTap()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"homebrew/core": {"git"},
+		"user/tools":    {"tool", "toolapp"},
+	}, byTap)
+}
+
 func TestListOutdated_ParsesJSON(t *testing.T) {
 	setupFakeBrew(t, "#!/bin/sh\n"+
 		"if [ \"$1\" = \"outdated\" ] && [ \"$2\" = \"--json\" ]; then\n"+
@@ -70,6 +190,34 @@ func TestDoctorDiagnose_Suggestions(t *testing.T) {
 	assert.Contains(t, suggestions, "Run: brew cleanup --prune=all")
 }
 
+func TestDoctorRemediations_WarningsExitNonZero(t *testing.T) {
+	setupFakeBrew(t, "#!/bin/sh\n"+
+		"if [ \"$1\" = \"doctor\" ]; then\n"+
+		"  echo 'Warning: broken symlinks detected'\n"+
+		"  echo 'Warning: unbrewed header files were found'\n"+
+		"  exit 1\n"+
+		"fi\n"+
+		"exit 0\n")
+
+	remediations, err := DoctorRemediations()
+	require.NoError(t, err)
+	require.Len(t, remediations, 2)
+	assert.Nil(t, remediations[0].Args, "sudo fixes are left to the user")
+	assert.Equal(t, []string{"cleanup", "--prune=all"}, remediations[1].Args)
+}
+
+func TestRunRemediation(t *testing.T) {
+	callsFile := filepath.Join(t.TempDir(), "calls")
+	setupFakeBrew(t, "#!/bin/sh\necho \"$*\" >> "+callsFile+"\n")
+
+	require.NoError(t, RunRemediation(Remediation{Suggestion: "Run: brew update-reset", Args: []string{"update-reset"}}))
+	assert.Error(t, RunRemediation(Remediation{Suggestion: "Run: xcode-select --install"}))
+
+	data, err := os.ReadFile(callsFile)
+	require.NoError(t, err)
+	assert.Equal(t, "update-reset\n", string(data))
+}
+
 func TestUpdateAndCleanup_UsesBrew(t *testing.T) {
 	setupFakeBrew(t, "#!/bin/sh\n"+
 		"if [ \"$1\" = \"update\" ]; then\n"+
@@ -2,6 +2,7 @@ package cleaner
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/openbootdotdev/openboot/internal/brew"
 	"github.com/openbootdotdev/openboot/internal/npm"
@@ -13,40 +14,100 @@ type CleanResult struct {
 	ExtraFormulae []string
 	ExtraCasks    []string
 	ExtraNpm      []string
+	// Protected lists extras kept because they match the protect list.
+	Protected []string
+
+	// The rest are only filled in for the categories asked for in Options.
+
+	// ExtraTaps are taps no package left after the clean comes from.
+	ExtraTaps []string
+	// Orphans are dependencies nothing needs any more. 'brew autoremove'
+	// may find more once the extra formulae are gone.
+	Orphans []string
+	// StalePlugins are Oh-My-Zsh plugins enabled in ~/.zshrc but not in
+	// the source.
+	StalePlugins []string
+	BrewCache    *CacheCleanup
+	NpmCache     *CacheCleanup
+}
+
+// CacheCleanup is a cache clean will clear.
+type CacheCleanup struct {
+	// Size is what brew says a cleanup would free, or how big the npm
+	// cache is, as display text.
+	Size string
 }
 
 func (r *CleanResult) TotalExtra() int {
 	return len(r.ExtraFormulae) + len(r.ExtraCasks) + len(r.ExtraNpm)
 }
 
-func DiffFromSnapshot(snap *snapshot.Snapshot) (*CleanResult, error) {
-	desiredFormulae := toSet(snap.Packages.Formulae)
-	desiredCasks := toSet(snap.Packages.Casks)
-	desiredNpm := toSet(snap.Packages.Npm)
+// Empty reports whether there is nothing at all to clean.
+func (r *CleanResult) Empty() bool {
+	return r.TotalExtra() == 0 && !r.HasExtras()
+}
+
+// HasExtras reports whether any category besides packages has work.
+func (r *CleanResult) HasExtras() bool {
+	return len(r.ExtraTaps)+len(r.Orphans)+len(r.StalePlugins) > 0 || r.BrewCache != nil || r.NpmCache != nil
+}
 
-	return diff(desiredFormulae, desiredCasks, desiredNpm)
+// desired is what a clean source says should stay installed.
+type desired struct {
+	formulae, casks, npm, taps map[string]bool
+	// plugins is nil when the source says nothing about Oh-My-Zsh.
+	plugins map[string]bool
 }
 
-func DiffFromLists(formulae, casks, npmPkgs []string) (*CleanResult, error) {
-	return diff(toSet(formulae), toSet(casks), toSet(npmPkgs))
+func DiffFromSnapshot(snap *snapshot.Snapshot, opts Options) (*CleanResult, error) {
+	d := desired{
+		formulae: toSet(snap.Packages.Formulae),
+		casks:    toSet(snap.Packages.Casks),
+		npm:      toSet(snap.Packages.Npm),
+		taps:     toSet(snap.Packages.Taps),
+	}
+	if snap.Shell.OhMyZsh {
+		d.plugins = toSet(snap.Shell.Plugins)
+	}
+	return diff(d, opts)
 }
 
-func diff(desiredFormulae, desiredCasks, desiredNpm map[string]bool) (*CleanResult, error) {
+func DiffFromLists(formulae, casks, npmPkgs, taps []string, opts Options) (*CleanResult, error) {
+	return diff(desired{
+		formulae: toSet(formulae),
+		casks:    toSet(casks),
+		npm:      toSet(npmPkgs),
+		taps:     toSet(taps),
+	}, opts)
+}
+
+func diff(d desired, opts Options) (*CleanResult, error) {
 	result := &CleanResult{}
 
-	installedFormulae, installedCasks, err := brew.GetInstalledPackages()
+	_, installedCasks, err := brew.GetInstalledPackages()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get installed brew packages: %w", err)
 	}
 
-	for pkg := range installedFormulae {
-		if !desiredFormulae[pkg] {
+	// Only leaves are candidates: removing a dependency would break
+	// whatever needs it, and brew refuses to anyway.
+	leaves, err := brew.GetLeaves()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get brew leaves: %w", err)
+	}
+
+	desiredShort := make(map[string]bool, len(d.formulae))
+	for pkg := range d.formulae {
+		desiredShort[shortName(pkg)] = true
+	}
+	for pkg := range leaves {
+		if !d.formulae[pkg] && !desiredShort[shortName(pkg)] {
 			result.ExtraFormulae = append(result.ExtraFormulae, pkg)
 		}
 	}
 
 	for pkg := range installedCasks {
-		if !desiredCasks[pkg] {
+		if !d.casks[pkg] {
 			result.ExtraCasks = append(result.ExtraCasks, pkg)
 		}
 	}
@@ -57,50 +118,95 @@ func diff(desiredFormulae, desiredCasks, desiredNpm map[string]bool) (*CleanResu
 			ui.Warn(fmt.Sprintf("Failed to check npm packages: %v", err))
 		} else {
 			for pkg := range installedNpm {
-				if !desiredNpm[pkg] {
+				if !d.npm[pkg] {
 					result.ExtraNpm = append(result.ExtraNpm, pkg)
 				}
 			}
 		}
 	}
 
+	sort.Strings(result.ExtraFormulae)
+	sort.Strings(result.ExtraCasks)
+	sort.Strings(result.ExtraNpm)
+
+	result.Protect(opts.Protect)
+	findExtras(result, d, opts)
 	return result, nil
 }
 
+// Execute removes the extras in result. Unless dryRun, it first saves a
+// Manifest of what it is about to remove and refuses to remove anything
+// if that fails, so every clean can be undone.
 func Execute(result *CleanResult, dryRun bool) error {
-	type uninstallOp struct {
-		label     string
-		pkgs      []string
-		uninstall func([]string, bool) error
+	if !dryRun && result.TotalExtra()+len(result.ExtraTaps)+len(result.StalePlugins) > 0 {
+		m := NewManifest(result)
+		if err := SaveManifest(m); err != nil {
+			return fmt.Errorf("nothing removed: %w", err)
+		}
+		ui.Muted(fmt.Sprintf("Saved clean record %s — undo with 'openboot clean --undo %s'", m.ID, m.ID))
 	}
 
-	ops := []uninstallOp{
+	type cleanOp struct {
+		label string
+		skip  bool
+		run   func() error
+	}
+
+	// Packages go first so the taps, orphans and cache they leave behind
+	// are cleaned up after them.
+	ops := []cleanOp{
+		{
+			label: "Removing extra formulae",
+			skip:  len(result.ExtraFormulae) == 0,
+			run:   func() error { return brew.Uninstall(result.ExtraFormulae, dryRun) },
+		},
+		{
+			label: "Removing extra casks",
+			skip:  len(result.ExtraCasks) == 0,
+			run:   func() error { return brew.UninstallCask(result.ExtraCasks, dryRun) },
+		},
+		{
+			label: "Removing extra npm packages",
+			skip:  len(result.ExtraNpm) == 0,
+			run:   func() error { return npm.Uninstall(result.ExtraNpm, dryRun) },
+		},
+		{
+			label: "Removing orphaned dependencies",
+			skip:  len(result.Orphans) == 0,
+			run:   func() error { return brew.Autoremove(dryRun) },
+		},
 		{
-			label:     "Removing extra formulae",
-			pkgs:      result.ExtraFormulae,
-			uninstall: brew.Uninstall,
+			label: "Removing unused taps",
+			skip:  len(result.ExtraTaps) == 0,
+			run:   func() error { return untapUnused(result.ExtraTaps, dryRun) },
 		},
 		{
-			label:     "Removing extra casks",
-			pkgs:      result.ExtraCasks,
-			uninstall: brew.UninstallCask,
+			label: "Disabling stale Oh-My-Zsh plugins",
+			skip:  len(result.StalePlugins) == 0,
+			run:   func() error { return disablePlugins(result.StalePlugins, dryRun) },
 		},
 		{
-			label:     "Removing extra npm packages",
-			pkgs:      result.ExtraNpm,
-			uninstall: npm.Uninstall,
+			label: "Cleaning the Homebrew cache",
+			skip:  result.BrewCache == nil,
+			run:   func() error { return cleanBrewCache(dryRun) },
+		},
+		{
+			label: "Cleaning the npm cache",
+			skip:  result.NpmCache == nil,
+			run:   func() error { return cleanNpmCache(dryRun) },
 		},
 	}
 
 	var errs []error
 	for _, op := range ops {
-		if len(op.pkgs) > 0 {
-			fmt.Println()
-			ui.Header(op.label)
-			fmt.Println()
-			if err := op.uninstall(op.pkgs, dryRun); err != nil {
-				errs = append(errs, fmt.Errorf("%s: %w", op.label, err))
-			}
+		if op.skip {
+			continue
+		}
+		fmt.Println()
+		ui.Header(op.label)
+		fmt.Println()
+		if err := op.run(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", op.label, err))
 		}
 	}
 
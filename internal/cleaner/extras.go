@@ -0,0 +1,222 @@
+package cleaner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openbootdotdev/openboot/internal/brew"
+	"github.com/openbootdotdev/openboot/internal/npm"
+	"github.com/openbootdotdev/openboot/internal/shell"
+	"github.com/openbootdotdev/openboot/internal/ui"
+)
+
+// Options tune a diff. The categories besides packages are each off
+// unless asked for.
+type Options struct {
+	// Protect holds patterns (see IsProtected) of packages and taps never
+	// to propose for removal.
+	Protect []string
+
+	// Taps untaps taps no remaining package comes from.
+	Taps bool
+	// Autoremove runs 'brew autoremove' for orphaned dependencies.
+	Autoremove bool
+	// BrewCache runs 'brew cleanup'.
+	BrewCache bool
+	// NpmCache garbage-collects stale npm cache entries.
+	NpmCache bool
+	// ShellPlugins disables Oh-My-Zsh plugins the source doesn't list.
+	ShellPlugins bool
+}
+
+// findExtras fills in the categories opts asks for. It runs after the
+// package diff, since which taps are unused depends on what is removed.
+// A category that can't be checked is skipped with a warning.
+func findExtras(result *CleanResult, d desired, opts Options) {
+	if opts.Taps {
+		taps, err := findUnusedTaps(result, d.taps)
+		if err != nil {
+			ui.Warn(fmt.Sprintf("Skipping taps: %v", err))
+		}
+		for _, tap := range taps {
+			if IsProtected(tap, opts.Protect) {
+				result.Protected = append(result.Protected, tap)
+			} else {
+				result.ExtraTaps = append(result.ExtraTaps, tap)
+			}
+		}
+	}
+
+	if opts.Autoremove {
+		orphans, err := brew.AutoremoveCandidates()
+		if err != nil {
+			ui.Warn(fmt.Sprintf("Skipping orphaned dependencies: %v", err))
+		}
+		result.Orphans = orphans
+	}
+
+	if opts.ShellPlugins {
+		if d.plugins == nil {
+			ui.Warn("Skipping shell plugins: the source doesn't record Oh-My-Zsh plugins")
+		} else {
+			enabled, err := shell.EnabledPlugins()
+			if err != nil {
+				ui.Warn(fmt.Sprintf("Skipping shell plugins: %v", err))
+			}
+			for _, p := range enabled {
+				if !d.plugins[p] {
+					result.StalePlugins = append(result.StalePlugins, p)
+				}
+			}
+		}
+	}
+
+	if opts.BrewCache {
+		size, err := brew.CleanupReclaimable()
+		if err != nil {
+			ui.Warn(fmt.Sprintf("Skipping the Homebrew cache: %v", err))
+		} else if size != "" || result.TotalExtra() > 0 {
+			// Removing packages can leave more behind than brew sees now.
+			result.BrewCache = &CacheCleanup{Size: size}
+		}
+	}
+
+	if opts.NpmCache {
+		if dir, err := npm.CacheDir(); err != nil {
+			ui.Warn(fmt.Sprintf("Skipping the npm cache: %v", err))
+		} else if u, ok := measurePaths(dir); ok && u.Size > 0 {
+			result.NpmCache = &CacheCleanup{Size: ui.FormatSize(u.Size)}
+		}
+	}
+}
+
+// findUnusedTaps returns the taps that no installed package will come from
+// once result's extras are removed. Homebrew's own taps and the ones the
+// source lists are always kept; official taps like homebrew/services
+// provide commands rather than packages.
+func findUnusedTaps(result *CleanResult, desiredTaps map[string]bool) ([]string, error) {
+	taps, err := brew.ListTaps()
+	if err != nil {
+		return nil, err
+	}
+	byTap, err := brew.GetInstalledByTap()
+	if err != nil {
+		return nil, err
+	}
+
+	removing := make(map[string]bool)
+	for _, pkg := range append(append([]string{}, result.ExtraFormulae...), result.ExtraCasks...) {
+		removing[shortName(pkg)] = true
+	}
+
+	var unused []string
+	for _, tap := range taps {
+		if desiredTaps[tap] || strings.HasPrefix(tap, "homebrew/") {
+			continue
+		}
+		used := false
+		for _, pkg := range byTap[tap] {
+			if !removing[pkg] {
+				used = true
+				break
+			}
+		}
+		if !used {
+			unused = append(unused, tap)
+		}
+	}
+	sort.Strings(unused)
+	return unused, nil
+}
+
+// untapUnused untaps taps, first re-checking which still have packages
+// installed, in case some of the removals before it failed.
+func untapUnused(taps []string, dryRun bool) error {
+	if !dryRun {
+		byTap, err := brew.GetInstalledByTap()
+		if err != nil {
+			return err
+		}
+		var still []string
+		for _, tap := range taps {
+			if len(byTap[tap]) > 0 {
+				ui.Muted(fmt.Sprintf("  Keeping %s: still used by %s", tap, strings.Join(byTap[tap], ", ")))
+				continue
+			}
+			still = append(still, tap)
+		}
+		taps = still
+	}
+	return brew.Untap(taps, dryRun)
+}
+
+func disablePlugins(stale []string, dryRun bool) error {
+	enabled, err := shell.EnabledPlugins()
+	if err != nil {
+		return err
+	}
+	drop := toSet(stale)
+	var keep []string
+	for _, p := range enabled {
+		if !drop[p] {
+			keep = append(keep, p)
+		}
+	}
+	if err := shell.SetPlugins(keep, dryRun); err != nil {
+		return err
+	}
+	if !dryRun {
+		ui.Success(fmt.Sprintf("  ✔ Disabled %s", strings.Join(stale, ", ")))
+	}
+	return nil
+}
+
+func enablePlugins(plugins []string, dryRun bool) error {
+	enabled, err := shell.EnabledPlugins()
+	if err != nil {
+		return err
+	}
+	have := toSet(enabled)
+	for _, p := range plugins {
+		if !have[p] {
+			enabled = append(enabled, p)
+		}
+	}
+	return shell.SetPlugins(enabled, dryRun)
+}
+
+func cleanBrewCache(dryRun bool) error {
+	if dryRun {
+		ui.Info("Would clean the Homebrew cache:")
+		fmt.Println("    brew cleanup")
+		return nil
+	}
+	freed, err := brew.CleanupReport()
+	if err != nil {
+		return err
+	}
+	if freed == "" {
+		ui.Success("  ✔ Homebrew cache already clean")
+	} else {
+		ui.Success(fmt.Sprintf("  ✔ Freed about %s", freed))
+	}
+	return nil
+}
+
+func cleanNpmCache(dryRun bool) error {
+	dir, err := npm.CacheDir()
+	if err != nil {
+		return err
+	}
+	before, _ := measurePaths(dir)
+	if err := npm.VerifyCache(dryRun); err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+	after, _ := measurePaths(dir)
+	ui.Success(fmt.Sprintf("  ✔ Freed %s", ui.FormatSize(max(before.Size-after.Size, 0))))
+	return nil
+}
@@ -0,0 +1,191 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// extrasBrewScript fakes a machine with oldtool (from user/old) and tool
+// (from user/keep) installed. Once anything is uninstalled, oldtool is
+// reported gone.
+const extrasBrewScript = `#!/bin/sh
+echo "brew $*" >> "$CALLS_FILE"
+case "$1 $2 $3" in
+  "list --formula -1") printf 'git\noldtool\ntool\n' ;;
+  "list --cask -1") ;;
+  "leaves  ") printf 'git\nuser/old/oldtool\nuser/keep/tool\n' ;;
+  "tap  ") printf 'homebrew/services\nuser/keep\nuser/mine\nuser/old\nuser/wanted\n' ;;
+  "info --json=v2 --installed")
+    if [ -e "$STATE_DIR/removed" ]; then
+      echo '{"formulae":[{"name":"git","tap":"homebrew/core"},{"name":"tool","tap":"user/keep"}],"casks":[]}'
+    else
+      echo '{"formulae":[{"name":"git","tap":"homebrew/core"},{"name":"oldtool","tap":"user/old"},{"name":"tool","tap":"user/keep"}],"casks":[]}'
+    fi ;;
+  "info --json=v2 --formula") echo '{"formulae":[],"casks":[]}' ;;
+  "autoremove --dry-run ") printf '==> Would autoremove 1 unneeded formulae:\nlibold\n' ;;
+  "cleanup --dry-run ") echo '==> This operation would free approximately 310.5MB of disk space.' ;;
+  "cleanup  ") echo '==> This operation has freed approximately 320MB of disk space.' ;;
+  uninstall*) touch "$STATE_DIR/removed" ;;
+esac
+exit 0
+`
+
+const extrasNpmScript = `#!/bin/sh
+echo "npm $*" >> "$CALLS_FILE"
+case "$1 $2" in
+  "config get") echo "$STATE_DIR/npm-cache" ;;
+  "cache verify") rm -f "$STATE_DIR/npm-cache/stale" ;;
+esac
+exit 0
+`
+
+func setupExtrasMachine(t *testing.T) (calls func() []string, zshrc string) {
+	t.Helper()
+	bin := t.TempDir()
+	state := t.TempDir()
+	home := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "brew"), []byte(extrasBrewScript), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "npm"), []byte(extrasNpmScript), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(state, "npm-cache"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(state, "npm-cache", "stale"), make([]byte, 2000), 0644))
+	zshrc = filepath.Join(home, ".zshrc")
+	require.NoError(t, os.WriteFile(zshrc, []byte("plugins=(git docker kubectl)\n"), 0644))
+
+	callsFile := filepath.Join(state, "calls")
+	t.Setenv("CALLS_FILE", callsFile)
+	t.Setenv("STATE_DIR", state)
+	t.Setenv("HOME", home)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+"/usr/bin"+string(os.PathListSeparator)+"/bin")
+
+	return func() []string {
+		data, err := os.ReadFile(callsFile)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		require.NoError(t, err)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}, zshrc
+}
+
+func extrasSnapshot() *snapshot.Snapshot {
+	return &snapshot.Snapshot{
+		Packages: snapshot.PackageSnapshot{
+			Formulae: []string{"git", "tool"},
+			Taps:     []string{"user/wanted"},
+		},
+		Shell: snapshot.ShellSnapshot{OhMyZsh: true, Plugins: []string{"git"}},
+	}
+}
+
+var allExtras = Options{
+	Protect:      []string{"user/mine"},
+	Taps:         true,
+	Autoremove:   true,
+	BrewCache:    true,
+	NpmCache:     true,
+	ShellPlugins: true,
+}
+
+func TestDiffFromSnapshot_Extras(t *testing.T) {
+	setupExtrasMachine(t)
+
+	result, err := DiffFromSnapshot(extrasSnapshot(), allExtras)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"user/old/oldtool"}, result.ExtraFormulae)
+	assert.Equal(t, []string{"user/old"}, result.ExtraTaps, "user/keep still has tool; homebrew/* and wanted taps are kept")
+	assert.Equal(t, []string{"user/mine"}, result.Protected)
+	assert.Equal(t, []string{"libold"}, result.Orphans)
+	assert.Equal(t, []string{"docker", "kubectl"}, result.StalePlugins)
+	require.NotNil(t, result.BrewCache)
+	assert.Equal(t, "310.5MB", result.BrewCache.Size)
+	require.NotNil(t, result.NpmCache)
+	assert.Equal(t, "2.0 kB", result.NpmCache.Size)
+	assert.True(t, result.HasExtras())
+}
+
+func TestDiffFromSnapshot_ExtrasOffByDefault(t *testing.T) {
+	calls, _ := setupExtrasMachine(t)
+
+	result, err := DiffFromSnapshot(extrasSnapshot(), Options{})
+	require.NoError(t, err)
+
+	assert.False(t, result.HasExtras())
+	for _, c := range calls() {
+		assert.NotContains(t, c, "autoremove")
+		assert.NotContains(t, c, "cleanup")
+		assert.NotContains(t, c, "npm config")
+	}
+}
+
+func TestDiffFromSnapshot_SkipsPluginsWithoutOhMyZsh(t *testing.T) {
+	setupExtrasMachine(t)
+	snap := extrasSnapshot()
+	snap.Shell = snapshot.ShellSnapshot{}
+
+	result, err := DiffFromSnapshot(snap, Options{ShellPlugins: true})
+	require.NoError(t, err)
+	assert.Empty(t, result.StalePlugins)
+}
+
+func TestExecute_Extras(t *testing.T) {
+	calls, zshrc := setupExtrasMachine(t)
+	result, err := DiffFromSnapshot(extrasSnapshot(), allExtras)
+	require.NoError(t, err)
+
+	require.NoError(t, Execute(result, false))
+
+	log := calls()
+	assert.Contains(t, log, "brew uninstall user/old/oldtool")
+	assert.Contains(t, log, "brew autoremove")
+	assert.Contains(t, log, "brew untap user/old")
+	assert.NotContains(t, log, "brew untap user/mine")
+	assert.Contains(t, log, "brew cleanup")
+	assert.Contains(t, log, "npm cache verify")
+
+	content, err := os.ReadFile(zshrc)
+	require.NoError(t, err)
+	assert.Equal(t, "plugins=(git)\n", string(content))
+
+	runs, err := ListRuns()
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, []string{"user/old"}, runs[0].Taps)
+	assert.Equal(t, []string{"docker", "kubectl"}, runs[0].ShellPlugins)
+
+	require.NoError(t, Undo(runs[0], false))
+	assert.Contains(t, calls(), "brew tap user/old")
+	content, err = os.ReadFile(zshrc)
+	require.NoError(t, err)
+	assert.Equal(t, "plugins=(git docker kubectl)\n", string(content))
+}
+
+func TestExecute_ExtrasDryRun(t *testing.T) {
+	calls, zshrc := setupExtrasMachine(t)
+	result, err := DiffFromSnapshot(extrasSnapshot(), allExtras)
+	require.NoError(t, err)
+	before := len(calls())
+
+	require.NoError(t, Execute(result, true))
+
+	for _, c := range calls()[before:] {
+		assert.NotRegexp(t, `^brew (uninstall|untap|autoremove$|cleanup$)|^npm cache verify`, c)
+	}
+	content, err := os.ReadFile(zshrc)
+	require.NoError(t, err)
+	assert.Equal(t, "plugins=(git docker kubectl)\n", string(content))
+}
+
+func TestUntapUnused_KeepsTapsStillInUse(t *testing.T) {
+	calls, _ := setupExtrasMachine(t)
+
+	// Nothing was uninstalled, so user/old still has oldtool.
+	require.NoError(t, untapUnused([]string{"user/old"}, false))
+	assert.NotContains(t, calls(), "brew untap user/old")
+}
@@ -0,0 +1,272 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openbootdotdev/openboot/internal/brew"
+	"github.com/openbootdotdev/openboot/internal/npm"
+	"github.com/openbootdotdev/openboot/internal/ui"
+)
+
+// runIDFormat names clean records after the time they were saved, so IDs
+// sort chronologically.
+const runIDFormat = "20060102-150405"
+
+// LatestRun resolves to the most recent clean that hasn't been undone.
+const LatestRun = "latest"
+
+// RemovedPackage is one package a clean removed.
+type RemovedPackage struct {
+	Name    string `json:"name"`
+	Tap     string `json:"tap,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// Manifest records what a clean run removed, so it can be undone. It is
+// written before anything is uninstalled.
+type Manifest struct {
+	ID        string           `json:"id"`
+	CreatedAt time.Time        `json:"created_at"`
+	Formulae  []RemovedPackage `json:"formulae,omitempty"`
+	Casks     []RemovedPackage `json:"casks,omitempty"`
+	Npm       []RemovedPackage `json:"npm,omitempty"`
+	Taps      []string         `json:"taps,omitempty"`
+	// ShellPlugins are Oh-My-Zsh plugins the run disabled.
+	ShellPlugins []string   `json:"shell_plugins,omitempty"`
+	UndoneAt     *time.Time `json:"undone_at,omitempty"`
+}
+
+// Total is the number of packages the run removed. Taps and plugins
+// aren't counted.
+func (m *Manifest) Total() int {
+	return len(m.Formulae) + len(m.Casks) + len(m.Npm)
+}
+
+// RunsDir returns where clean records are kept.
+func RunsDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".openboot", "clean")
+}
+
+// NewManifest describes result with the tap and version of every package.
+// Versions that can't be looked up are left blank rather than failing the
+// clean.
+func NewManifest(result *CleanResult) *Manifest {
+	m := &Manifest{CreatedAt: time.Now()}
+
+	brewPackages := func(names []string, cask bool) []RemovedPackage {
+		info, err := brew.GetPackageInfo(names, cask)
+		if err != nil {
+			ui.Warn(fmt.Sprintf("Failed to look up versions: %v", err))
+		}
+		pkgs := make([]RemovedPackage, 0, len(names))
+		for _, name := range names {
+			pkg := RemovedPackage{Name: name}
+			if i, ok := info[name]; ok {
+				pkg.Tap = i.Tap
+				pkg.Version = i.Version
+			}
+			pkgs = append(pkgs, pkg)
+		}
+		return pkgs
+	}
+	if len(result.ExtraFormulae) > 0 {
+		m.Formulae = brewPackages(result.ExtraFormulae, false)
+	}
+	if len(result.ExtraCasks) > 0 {
+		m.Casks = brewPackages(result.ExtraCasks, true)
+	}
+
+	if len(result.ExtraNpm) > 0 {
+		versions, err := npm.GetInstalledVersions()
+		if err != nil {
+			ui.Warn(fmt.Sprintf("Failed to look up npm versions: %v", err))
+		}
+		for _, name := range result.ExtraNpm {
+			m.Npm = append(m.Npm, RemovedPackage{Name: name, Version: versions[name]})
+		}
+	}
+
+	m.Taps = result.ExtraTaps
+	m.ShellPlugins = result.StalePlugins
+	return m
+}
+
+// SaveManifest writes m under RunsDir, assigning it an ID if it has none.
+func SaveManifest(m *Manifest) error {
+	dir := RunsDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if m.ID == "" {
+		m.ID = newRunID(dir, m.CreatedAt)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode clean record: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, m.ID+".json"), data, 0600); err != nil {
+		return fmt.Errorf("failed to write clean record: %w", err)
+	}
+	return nil
+}
+
+// ListRuns returns saved clean records, newest first.
+func ListRuns() ([]*Manifest, error) {
+	dir := RunsDir()
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clean records: %w", err)
+	}
+
+	var runs []*Manifest
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read clean record: %w", err)
+		}
+		var m Manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("failed to parse clean record %s: %w", f.Name(), err)
+		}
+		runs = append(runs, &m)
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID > runs[j].ID })
+	return runs, nil
+}
+
+// ResolveRun finds the clean record for ref, which may be "latest", a full
+// ID or an unambiguous ID prefix. "latest" skips runs already undone.
+func ResolveRun(ref string) (*Manifest, error) {
+	runs, err := ListRuns()
+	if err != nil {
+		return nil, err
+	}
+	if ref == LatestRun || ref == "" {
+		for _, m := range runs {
+			if m.UndoneAt == nil {
+				return m, nil
+			}
+		}
+		return nil, fmt.Errorf("no clean runs to undo")
+	}
+
+	var matches []*Manifest
+	for _, m := range runs {
+		if m.ID == ref {
+			return m, nil
+		}
+		if strings.HasPrefix(m.ID, ref) {
+			matches = append(matches, m)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no clean run with ID %s", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("clean run ID %s is ambiguous (%d matches)", ref, len(matches))
+	}
+}
+
+// Undo reinstalls everything m removed, adding back the taps they came
+// from and re-enabling shell plugins. Homebrew installs its current
+// version; npm packages are pinned to the recorded one. Orphaned
+// dependencies removed by 'brew autoremove' aren't recorded and come back
+// only with whatever needs them. A successful undo is recorded in m.
+func Undo(m *Manifest, dryRun bool) error {
+	var taps, formulae, casks, npmPkgs []string
+	seenTap := make(map[string]bool)
+	addTap := func(tap string) {
+		if tap == "" || tap == "homebrew/core" || tap == "homebrew/cask" || seenTap[tap] {
+			return
+		}
+		seenTap[tap] = true
+		taps = append(taps, tap)
+	}
+
+	for _, tap := range m.Taps {
+		addTap(tap)
+	}
+	for _, pkg := range m.Formulae {
+		addTap(pkg.Tap)
+		formulae = append(formulae, pkg.Name)
+	}
+	for _, pkg := range m.Casks {
+		addTap(pkg.Tap)
+		casks = append(casks, pkg.Name)
+	}
+	for _, pkg := range m.Npm {
+		if pkg.Version != "" {
+			npmPkgs = append(npmPkgs, pkg.Name+"@"+pkg.Version)
+		} else {
+			npmPkgs = append(npmPkgs, pkg.Name)
+		}
+	}
+
+	type installOp struct {
+		label   string
+		pkgs    []string
+		install func([]string, bool) error
+	}
+	ops := []installOp{
+		{label: "Restoring taps", pkgs: taps, install: brew.InstallTaps},
+		{label: "Restoring formulae", pkgs: formulae, install: brew.Install},
+		{label: "Restoring casks", pkgs: casks, install: brew.InstallCask},
+		{label: "Restoring npm packages", pkgs: npmPkgs, install: npm.Install},
+		{label: "Re-enabling shell plugins", pkgs: m.ShellPlugins, install: enablePlugins},
+	}
+
+	var errs []error
+	for _, op := range ops {
+		if len(op.pkgs) > 0 {
+			fmt.Println()
+			ui.Header(op.label)
+			fmt.Println()
+			if err := op.install(op.pkgs, dryRun); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", op.label, err))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d undo steps had failures", len(errs))
+	}
+	if dryRun {
+		return nil
+	}
+
+	now := time.Now()
+	m.UndoneAt = &now
+	return SaveManifest(m)
+}
+
+// newRunID returns an unused ID for a clean saved at now.
+func newRunID(dir string, now time.Time) string {
+	base := now.Format(runIDFormat)
+	id := base
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filepath.Join(dir, id+".json")); os.IsNotExist(err) {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+}
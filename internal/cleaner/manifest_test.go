@@ -0,0 +1,194 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeBrewScript = "#!/bin/sh\n" +
+	"echo \"brew $*\" >> \"$CALLS_FILE\"\n" +
+	"if [ \"$1\" = \"uninstall\" ]; then\n" +
+	"  for f in \"$HOME\"/.openboot/clean/*.json; do [ -e \"$f\" ] && echo recorded >> \"$CALLS_FILE\"; done\n" +
+	"fi\n" +
+	"if [ \"$1 $3\" = \"info --formula\" ]; then\n" +
+	"  echo '{\"formulae\":[{\"name\":\"tool\",\"full_name\":\"user/tap/tool\",\"tap\":\"user/tap\",\"installed\":[{\"version\":\"1.2.0\"}]},{\"name\":\"jq\",\"full_name\":\"jq\",\"tap\":\"homebrew/core\",\"installed\":[{\"version\":\"1.7.1\"}]}],\"casks\":[]}'\n" +
+	"fi\n" +
+	"if [ \"$1 $3\" = \"info --cask\" ]; then\n" +
+	"  echo '{\"formulae\":[],\"casks\":[{\"token\":\"slack\",\"full_token\":\"slack\",\"tap\":\"homebrew/cask\",\"installed\":\"4.41.105\"}]}'\n" +
+	"fi\n" +
+	"exit 0\n"
+
+const fakeNpmScript = "#!/bin/sh\n" +
+	"echo \"npm $*\" >> \"$CALLS_FILE\"\n" +
+	"if [ \"$1\" = \"list\" ] && [ \"$4\" = \"--json\" ]; then\n" +
+	"  echo '{\"dependencies\":{\"typescript\":{\"version\":\"5.4.5\"}}}'\n" +
+	"fi\n" +
+	"exit 0\n"
+
+// setupFakeTools puts fake brew and npm first on PATH, points HOME at a
+// temp dir and returns a function reading the commands they were run with.
+func setupFakeTools(t *testing.T) func() []string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "brew"), []byte(fakeBrewScript), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "npm"), []byte(fakeNpmScript), 0755))
+	calls := filepath.Join(dir, "calls")
+	t.Setenv("CALLS_FILE", calls)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PATH", dir)
+
+	return func() []string {
+		data, err := os.ReadFile(calls)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		require.NoError(t, err)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+}
+
+func TestExecute_SavesManifestBeforeRemoving(t *testing.T) {
+	calls := setupFakeTools(t)
+
+	err := Execute(&CleanResult{
+		ExtraFormulae: []string{"user/tap/tool", "jq"},
+		ExtraCasks:    []string{"slack"},
+		ExtraNpm:      []string{"typescript"},
+	}, false)
+	require.NoError(t, err)
+
+	log := calls()
+	first := -1
+	for i, c := range log {
+		if strings.HasPrefix(c, "brew uninstall") {
+			first = i
+			break
+		}
+	}
+	require.GreaterOrEqual(t, first, 0, "nothing was uninstalled: %v", log)
+	assert.Equal(t, "recorded", log[first+1], "manifest must exist before the first uninstall")
+	assert.Contains(t, log, "npm uninstall -g typescript")
+
+	runs, err := ListRuns()
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	m := runs[0]
+	assert.NotEmpty(t, m.ID)
+	assert.Equal(t, []RemovedPackage{
+		{Name: "user/tap/tool", Tap: "user/tap", Version: "1.2.0"},
+		{Name: "jq", Tap: "homebrew/core", Version: "1.7.1"},
+	}, m.Formulae)
+	assert.Equal(t, []RemovedPackage{{Name: "slack", Tap: "homebrew/cask", Version: "4.41.105"}}, m.Casks)
+	assert.Equal(t, []RemovedPackage{{Name: "typescript", Version: "5.4.5"}}, m.Npm)
+	assert.Nil(t, m.UndoneAt)
+}
+
+func TestExecute_DryRunSavesNothing(t *testing.T) {
+	setupFakeTools(t)
+
+	require.NoError(t, Execute(&CleanResult{ExtraFormulae: []string{"jq"}}, true))
+
+	runs, err := ListRuns()
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+}
+
+func TestExecute_RemovesNothingWithoutManifest(t *testing.T) {
+	calls := setupFakeTools(t)
+	// A file where the records directory should be makes saving fail.
+	require.NoError(t, os.MkdirAll(filepath.Dir(RunsDir()), 0755))
+	require.NoError(t, os.WriteFile(RunsDir(), nil, 0644))
+
+	err := Execute(&CleanResult{ExtraFormulae: []string{"jq"}}, false)
+	require.Error(t, err)
+
+	for _, c := range calls() {
+		assert.NotContains(t, c, "uninstall")
+	}
+}
+
+func TestUndo_ReinstallsRecordedPackages(t *testing.T) {
+	calls := setupFakeTools(t)
+	m := &Manifest{
+		CreatedAt: time.Now(),
+		Formulae: []RemovedPackage{
+			{Name: "user/tap/tool", Tap: "user/tap", Version: "1.2.0"},
+			{Name: "jq", Tap: "homebrew/core", Version: "1.7.1"},
+		},
+		Casks: []RemovedPackage{{Name: "slack", Tap: "homebrew/cask"}},
+		Npm: []RemovedPackage{
+			{Name: "typescript", Version: "5.4.5"},
+			{Name: "eslint"},
+		},
+	}
+	require.NoError(t, SaveManifest(m))
+
+	require.NoError(t, Undo(m, false))
+
+	log := calls()
+	assert.Contains(t, log, "brew tap user/tap")
+	assert.NotContains(t, log, "brew tap homebrew/core")
+	assert.Contains(t, log, "brew install user/tap/tool jq")
+	assert.Contains(t, log, "brew install --cask slack")
+	assert.Contains(t, log, "npm install -g typescript@5.4.5 eslint")
+
+	saved, err := ResolveRun(m.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, saved.UndoneAt)
+
+	_, err = ResolveRun(LatestRun)
+	assert.Error(t, err, "latest skips runs that were already undone")
+}
+
+func TestUndo_DryRunChangesNothing(t *testing.T) {
+	calls := setupFakeTools(t)
+	m := &Manifest{CreatedAt: time.Now(), Formulae: []RemovedPackage{{Name: "jq"}}}
+	require.NoError(t, SaveManifest(m))
+
+	require.NoError(t, Undo(m, true))
+
+	assert.Empty(t, calls())
+	saved, err := ResolveRun(m.ID)
+	require.NoError(t, err)
+	assert.Nil(t, saved.UndoneAt)
+}
+
+func TestResolveRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	_, err := ResolveRun(LatestRun)
+	assert.Error(t, err)
+
+	for _, id := range []string{"20240101-090000", "20240102-090000", "20240102-100000"} {
+		require.NoError(t, SaveManifest(&Manifest{ID: id, Formulae: []RemovedPackage{{Name: "jq"}}}))
+	}
+
+	m, err := ResolveRun(LatestRun)
+	require.NoError(t, err)
+	assert.Equal(t, "20240102-100000", m.ID)
+
+	m, err = ResolveRun("20240101")
+	require.NoError(t, err)
+	assert.Equal(t, "20240101-090000", m.ID)
+
+	_, err = ResolveRun("20240102")
+	assert.ErrorContains(t, err, "ambiguous")
+
+	_, err = ResolveRun("2023")
+	assert.ErrorContains(t, err, "no clean run")
+}
+
+func TestNewRunID_AvoidsCollisions(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+
+	assert.Equal(t, "20240102-030405", newRunID(dir, now))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "20240102-030405.json"), nil, 0600))
+	assert.Equal(t, "20240102-030405-2", newRunID(dir, now))
+}
@@ -0,0 +1,142 @@
+package cleaner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ProtectPath returns the file listing packages clean must never remove:
+// one glob pattern (as in path.Match) per line, with # comments.
+func ProtectPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".openboot", "clean-ignore")
+}
+
+// LoadProtected reads the protect list. A missing file is an empty list.
+func LoadProtected() ([]string, error) {
+	f, err := os.Open(ProtectPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read protect list: %w", err)
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read protect list: %w", err)
+	}
+	return patterns, ValidatePatterns(patterns)
+}
+
+// AddProtected appends patterns to the protect list, skipping ones that
+// are already there. It returns the patterns that were added.
+func AddProtected(patterns ...string) ([]string, error) {
+	if err := ValidatePatterns(patterns); err != nil {
+		return nil, err
+	}
+	existing, err := LoadProtected()
+	if err != nil {
+		return nil, err
+	}
+	have := toSet(existing)
+
+	var added []string
+	for _, p := range patterns {
+		if !have[p] {
+			added = append(added, p)
+			have[p] = true
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ProtectPath()), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.OpenFile(ProtectPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open protect list: %w", err)
+	}
+	defer f.Close()
+	for _, p := range added {
+		if _, err := fmt.Fprintln(f, p); err != nil {
+			return nil, fmt.Errorf("failed to write protect list: %w", err)
+		}
+	}
+	return added, nil
+}
+
+// ValidatePatterns checks that every pattern is a valid glob.
+func ValidatePatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid protect pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// IsProtected reports whether name matches one of patterns. A formula
+// from a tap ("user/tap/name") also matches on its short name.
+func IsProtected(name string, patterns []string) bool {
+	candidates := []string{name}
+	if short := shortName(name); short != name {
+		candidates = append(candidates, short)
+	}
+	for _, p := range patterns {
+		for _, c := range candidates {
+			if ok, _ := path.Match(p, c); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Protect moves extras matching patterns out of the removal lists and
+// into r.Protected.
+func (r *CleanResult) Protect(patterns []string) {
+	if len(patterns) == 0 {
+		return
+	}
+	keep := func(items []string) []string {
+		var out []string
+		for _, item := range items {
+			if IsProtected(item, patterns) {
+				r.Protected = append(r.Protected, item)
+				continue
+			}
+			out = append(out, item)
+		}
+		return out
+	}
+	r.ExtraFormulae = keep(r.ExtraFormulae)
+	r.ExtraCasks = keep(r.ExtraCasks)
+	r.ExtraNpm = keep(r.ExtraNpm)
+	r.ExtraTaps = keep(r.ExtraTaps)
+}
+
+func shortName(name string) string {
+	if parts := strings.Split(name, "/"); len(parts) == 3 {
+		return parts[2]
+	}
+	return name
+}
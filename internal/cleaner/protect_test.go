@@ -0,0 +1,97 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtectList_Roundtrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	patterns, err := LoadProtected()
+	require.NoError(t, err)
+	assert.Empty(t, patterns)
+
+	added, err := AddProtected("ffmpeg", "font-*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ffmpeg", "font-*"}, added)
+
+	added, err = AddProtected("ffmpeg", "postgresql@*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"postgresql@*"}, added)
+
+	patterns, err = LoadProtected()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ffmpeg", "font-*", "postgresql@*"}, patterns)
+}
+
+func TestLoadProtected_SkipsCommentsAndBlankLines(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, os.MkdirAll(filepath.Dir(ProtectPath()), 0755))
+	require.NoError(t, os.WriteFile(ProtectPath(), []byte("# side projects\n\n  ffmpeg  \nimagemagick\n"), 0644))
+
+	patterns, err := LoadProtected()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ffmpeg", "imagemagick"}, patterns)
+}
+
+func TestAddProtected_RejectsInvalidPattern(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	_, err := AddProtected("[abc")
+	assert.Error(t, err)
+	_, statErr := os.Stat(ProtectPath())
+	assert.True(t, os.IsNotExist(statErr))
+}
+
+func TestIsProtected(t *testing.T) {
+	patterns := []string{"ffmpeg", "font-*", "python@3.*"}
+
+	assert.True(t, IsProtected("ffmpeg", patterns))
+	assert.True(t, IsProtected("font-fira-code", patterns))
+	assert.True(t, IsProtected("python@3.12", patterns))
+	assert.True(t, IsProtected("homebrew/cask-fonts/font-hack", patterns))
+	assert.False(t, IsProtected("python@2", patterns))
+	assert.False(t, IsProtected("ffmpegthumbnailer", patterns))
+	assert.False(t, IsProtected("ffmpeg", nil))
+}
+
+func TestCleanResult_Protect(t *testing.T) {
+	result := &CleanResult{
+		ExtraFormulae: []string{"ffmpeg", "jq"},
+		ExtraCasks:    []string{"font-hack", "slack"},
+		ExtraNpm:      []string{"typescript"},
+	}
+
+	result.Protect([]string{"ffmpeg", "font-*", "typescript"})
+
+	assert.Equal(t, []string{"jq"}, result.ExtraFormulae)
+	assert.Equal(t, []string{"slack"}, result.ExtraCasks)
+	assert.Empty(t, result.ExtraNpm)
+	assert.Equal(t, []string{"ffmpeg", "font-hack", "typescript"}, result.Protected)
+	assert.Equal(t, 2, result.TotalExtra())
+}
+
+func TestDiffFromLists_OnlyProposesLeaves(t *testing.T) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"case \"$1 $2\" in\n" +
+		"  \"list --formula\") printf 'git\\nopenssl@3\\nffmpeg\\ntool\\n' ;;\n" +
+		"  \"list --cask\") printf 'firefox\\nslack\\n' ;;\n" +
+		"  \"leaves \") printf 'git\\nffmpeg\\nuser/tap/tool\\n' ;;\n" +
+		"esac\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "brew"), []byte(script), 0755))
+	// Keep the real npm off PATH so only brew is compared.
+	t.Setenv("PATH", dir)
+
+	result, err := DiffFromLists([]string{"git", "tool"}, []string{"firefox"}, nil, nil, Options{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"ffmpeg"}, result.ExtraFormulae)
+	assert.Equal(t, []string{"slack"}, result.ExtraCasks)
+	assert.Empty(t, result.ExtraNpm)
+}
@@ -0,0 +1,101 @@
+package cleaner
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/openbootdotdev/openboot/internal/brew"
+	"github.com/openbootdotdev/openboot/internal/npm"
+)
+
+// Usage is how much disk an installed package takes and when it was last
+// installed or updated.
+type Usage struct {
+	Size     int64
+	Modified time.Time
+}
+
+// UsageReport holds the Usage of each extra in a CleanResult, by name.
+// Packages whose files can't be found are missing from it.
+type UsageReport struct {
+	Formulae map[string]Usage
+	Casks    map[string]Usage
+	Npm      map[string]Usage
+}
+
+// MeasureUsage finds each extra on disk: formulae in the Cellar, casks in
+// the Caskroom plus the apps they installed, npm packages in the global
+// root. Lookups that fail leave packages unmeasured rather than failing.
+func MeasureUsage(result *CleanResult) UsageReport {
+	report := UsageReport{
+		Formulae: make(map[string]Usage),
+		Casks:    make(map[string]Usage),
+		Npm:      make(map[string]Usage),
+	}
+
+	if len(result.ExtraFormulae)+len(result.ExtraCasks) > 0 {
+		if prefix, err := brew.Prefix(); err == nil {
+			for _, name := range result.ExtraFormulae {
+				if u, ok := measurePaths(filepath.Join(prefix, "Cellar", shortName(name))); ok {
+					report.Formulae[name] = u
+				}
+			}
+
+			info, _ := brew.GetPackageInfo(result.ExtraCasks, true)
+			for _, name := range result.ExtraCasks {
+				paths := []string{filepath.Join(prefix, "Caskroom", shortName(name))}
+				for _, app := range info[name].Apps {
+					if !filepath.IsAbs(app) {
+						app = filepath.Join("/Applications", app)
+					}
+					paths = append(paths, app)
+				}
+				if u, ok := measurePaths(paths...); ok {
+					report.Casks[name] = u
+				}
+			}
+		}
+	}
+
+	if len(result.ExtraNpm) > 0 {
+		if root, err := npm.GlobalRoot(); err == nil {
+			for _, name := range result.ExtraNpm {
+				if u, ok := measurePaths(filepath.Join(root, name)); ok {
+					report.Npm[name] = u
+				}
+			}
+		}
+	}
+
+	return report
+}
+
+// measurePaths adds up the size of every file under paths and takes the
+// newest modification time of the paths themselves. It reports false if
+// none of them exist.
+func measurePaths(paths ...string) (Usage, bool) {
+	var u Usage
+	found := false
+	for _, root := range paths {
+		info, err := os.Lstat(root)
+		if err != nil {
+			continue
+		}
+		found = true
+		if info.ModTime().After(u.Modified) {
+			u.Modified = info.ModTime()
+		}
+		_ = filepath.WalkDir(root, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if fi, err := d.Info(); err == nil {
+				u.Size += fi.Size()
+			}
+			return nil
+		})
+	}
+	return u, found
+}
@@ -0,0 +1,66 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasureUsage(t *testing.T) {
+	prefix := t.TempDir()
+	npmRoot := t.TempDir()
+	apps := t.TempDir()
+
+	write := func(path string, size int) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+	}
+	write(filepath.Join(prefix, "Cellar", "tool", "1.0", "bin", "tool"), 300)
+	write(filepath.Join(prefix, "Cellar", "tool", "1.0", "README"), 20)
+	write(filepath.Join(prefix, "Caskroom", "slack", "4.41", ".metadata"), 5)
+	write(filepath.Join(apps, "Slack.app", "Contents", "MacOS", "Slack"), 1000)
+	write(filepath.Join(npmRoot, "@scope", "pkg", "index.js"), 42)
+
+	installed := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(prefix, "Cellar", "tool"), installed, installed))
+
+	bin := t.TempDir()
+	// The cask's app is given as an absolute path so the test doesn't
+	// depend on /Applications.
+	brewScript := "#!/bin/sh\n" +
+		"if [ \"$1\" = \"--prefix\" ]; then echo '" + prefix + "'; exit 0; fi\n" +
+		"if [ \"$1\" = \"info\" ]; then\n" +
+		"  echo '{\"formulae\":[],\"casks\":[{\"token\":\"slack\",\"full_token\":\"slack\",\"artifacts\":[{\"uninstall\":[]},{\"app\":[\"" + filepath.Join(apps, "Slack.app") + "\"]}]}]}'\n" +
+		"  exit 0\n" +
+		"fi\n" +
+		"exit 1\n"
+	npmScript := "#!/bin/sh\n" +
+		"if [ \"$1\" = \"root\" ]; then echo '" + npmRoot + "'; exit 0; fi\n" +
+		"exit 1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "brew"), []byte(brewScript), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "npm"), []byte(npmScript), 0755))
+	t.Setenv("PATH", bin)
+
+	report := MeasureUsage(&CleanResult{
+		ExtraFormulae: []string{"user/tap/tool", "gone"},
+		ExtraCasks:    []string{"slack"},
+		ExtraNpm:      []string{"@scope/pkg"},
+	})
+	assert.Equal(t, Usage{Size: 320, Modified: installed}, withUTC(report.Formulae["user/tap/tool"]))
+	assert.NotContains(t, report.Formulae, "gone")
+	assert.Equal(t, int64(42), report.Npm["@scope/pkg"].Size)
+
+	assert.Equal(t, int64(1005), report.Casks["slack"].Size)
+
+	_, ok := measurePaths(filepath.Join(prefix, "missing"))
+	assert.False(t, ok)
+}
+
+func withUTC(u Usage) Usage {
+	u.Modified = u.Modified.UTC()
+	return u
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/openbootdotdev/openboot/internal/cleaner"
 	"github.com/openbootdotdev/openboot/internal/config"
 	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/openbootdotdev/openboot/internal/system"
 	"github.com/openbootdotdev/openboot/internal/ui"
 	"github.com/spf13/cobra"
 )
@@ -18,30 +19,140 @@ var cleanCmd = &cobra.Command{
 	Long: `Compare your system against a config or snapshot and remove extra packages.
 
 Sources (checked in order):
-  1. --from <file>         Compare against a snapshot file
+  1. --from <file|id>      Compare against a snapshot file or saved snapshot ID
   2. --user <username>     Compare against your openboot.dev config
-  3. Local snapshot         Compare against ~/.openboot/snapshot.json
+  3. Local snapshot         Compare against the latest in ~/.openboot/snapshots
+
+In a terminal, extras are listed in a checklist with their size on disk
+and when they were last modified; untick anything you want to keep, and
+optionally add the kept packages to the protect list.
+
+Every clean saves a record of what it removed under ~/.openboot/clean
+before uninstalling anything; --undo reinstalls those packages.
+
+Only formulae reported by 'brew leaves' are proposed for removal, so
+dependencies of other packages are never touched. Packages matching a
+pattern in ~/.openboot/clean-ignore or a --protect flag are kept.
+
+Optional categories, each shown in the preview and --dry-run:
+  --taps            Untap taps no remaining package comes from
+  --autoremove      Remove orphaned dependencies (brew autoremove)
+  --brew-cache      Clear old downloads and versions (brew cleanup)
+  --npm-cache       Garbage-collect stale npm cache entries
+  --shell-plugins   Disable Oh-My-Zsh plugins the snapshot doesn't list
 
 Examples:
   openboot clean                              Clean against local snapshot
   openboot clean --user myname                Clean against cloud config
   openboot clean --from my-setup.json         Clean against a snapshot file
-  openboot clean --dry-run                    Preview what would be removed`,
+  openboot clean --from 20240102-030405       Clean against a saved snapshot
+  openboot clean --dry-run                    Preview what would be removed
+  openboot clean --taps --brew-cache          Also untap unused taps and clear the cache
+  openboot clean --protect 'font-*'           Keep every font cask this run
+  openboot clean protect ffmpeg               Never propose ffmpeg for removal
+  openboot clean --undo                       Reinstall what the last clean removed
+  openboot clean --undo 20240102-030405       Reinstall what a given clean removed`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flags().Changed("undo") {
+			ref, _ := cmd.Flags().GetString("undo")
+			if len(args) > 1 {
+				return fmt.Errorf("--undo takes at most one run ID")
+			}
+			if len(args) == 1 {
+				ref = args[0]
+			}
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return runCleanUndo(ref, dryRun)
+		}
+		if len(args) > 0 {
+			return fmt.Errorf("unknown command %q for %q", args[0], cmd.CommandPath())
+		}
 		return runClean(cmd)
 	},
 }
 
 func init() {
-	cleanCmd.Flags().String("from", "", "snapshot file to compare against")
+	cleanCmd.Flags().String("from", "", "snapshot file or saved snapshot ID to compare against")
 	cleanCmd.Flags().String("user", "", "openboot.dev username/slug to compare against")
 	cleanCmd.Flags().Bool("dry-run", false, "preview changes without removing anything")
+	cleanCmd.Flags().String("undo", "", "reinstall the packages a previous clean removed (latest, or a run ID)")
+	cleanCmd.Flags().Lookup("undo").NoOptDefVal = cleaner.LatestRun
+	cleanCmd.Flags().StringArray("protect", nil, "package name or glob to keep, in addition to ~/.openboot/clean-ignore (repeatable)")
+	cleanCmd.Flags().Bool("taps", false, "also untap taps no remaining package comes from")
+	cleanCmd.Flags().Bool("autoremove", false, "also remove orphaned dependencies with brew autoremove")
+	cleanCmd.Flags().Bool("brew-cache", false, "also clear the Homebrew cache with brew cleanup")
+	cleanCmd.Flags().Bool("npm-cache", false, "also garbage-collect stale npm cache entries")
+	cleanCmd.Flags().Bool("shell-plugins", false, "also disable Oh-My-Zsh plugins the snapshot doesn't list")
+
+	cleanCmd.AddCommand(cleanProtectCmd)
+}
+
+var cleanProtectCmd = &cobra.Command{
+	Use:   "protect [package|glob]...",
+	Short: "Keep packages out of clean for good",
+	Long: `Add package names or glob patterns to ~/.openboot/clean-ignore.
+Matching packages are never proposed for removal by 'openboot clean' or
+'openboot status --fix'. Patterns use shell glob syntax (*, ?, [...]) and
+match a tap package by its full or short name.
+
+With no arguments, lists the current patterns.
+
+Examples:
+  openboot clean protect ffmpeg imagemagick
+  openboot clean protect 'font-*'
+  openboot clean protect`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCleanProtect(args)
+	},
+}
+
+func runCleanProtect(patterns []string) error {
+	if len(patterns) == 0 {
+		existing, err := cleaner.LoadProtected()
+		if err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			ui.Muted("No protected packages. Add some with 'openboot clean protect <package>'.")
+			return nil
+		}
+		for _, p := range existing {
+			fmt.Println(p)
+		}
+		return nil
+	}
+
+	added, err := cleaner.AddProtected(patterns...)
+	if err != nil {
+		return err
+	}
+	if len(added) == 0 {
+		ui.Muted("Already protected.")
+		return nil
+	}
+	ui.Success(fmt.Sprintf("Protected %s (%s)", strings.Join(added, ", "), cleaner.ProtectPath()))
+	return nil
 }
 
 func runClean(cmd *cobra.Command) error {
 	fromFile, _ := cmd.Flags().GetString("from")
 	user, _ := cmd.Flags().GetString("user")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	extraProtect, _ := cmd.Flags().GetStringArray("protect")
+
+	if err := cleaner.ValidatePatterns(extraProtect); err != nil {
+		return err
+	}
+	protected, err := cleaner.LoadProtected()
+	if err != nil {
+		return err
+	}
+	opts := cleaner.Options{Protect: append(protected, extraProtect...)}
+	opts.Taps, _ = cmd.Flags().GetBool("taps")
+	opts.Autoremove, _ = cmd.Flags().GetBool("autoremove")
+	opts.BrewCache, _ = cmd.Flags().GetBool("brew-cache")
+	opts.NpmCache, _ = cmd.Flags().GetBool("npm-cache")
+	opts.ShellPlugins, _ = cmd.Flags().GetBool("shell-plugins")
 
 	fmt.Println()
 	ui.Header("OpenBoot Clean")
@@ -53,69 +164,247 @@ func runClean(cmd *cobra.Command) error {
 	}
 
 	var result *cleaner.CleanResult
-	var err error
 
 	switch {
 	case fromFile != "":
-		result, err = cleanFromFile(fromFile)
+		result, err = cleanFromFile(fromFile, opts)
 	case user != "":
-		result, err = cleanFromRemote(user)
+		result, err = cleanFromRemote(user, opts)
 	default:
-		result, err = cleanFromLocalSnapshot()
+		result, err = cleanFromLocalSnapshot(opts)
 	}
 
 	if err != nil {
 		return err
 	}
 
-	if result.TotalExtra() == 0 {
+	if result.Empty() {
+		showProtected(result)
 		ui.Success("Your system is clean — no extra packages found.")
 		fmt.Println()
 		return nil
 	}
 
-	showCleanPreview(result)
+	switch {
+	case !dryRun && system.HasTTY():
+		if result.TotalExtra() > 0 {
+			var kept []string
+			var proceed bool
+			result, kept, proceed, err = selectCleanItems(result)
+			if err != nil {
+				return err
+			}
+			if !proceed {
+				ui.Muted("Clean cancelled.")
+				fmt.Println()
+				return nil
+			}
+			if len(kept) > 0 {
+				offerToProtect(kept)
+			}
+		}
+		if result.HasExtras() {
+			showExtrasPreview(result)
+			proceed, err := ui.Confirm("Clean these up too?", true)
+			if err != nil {
+				return err
+			}
+			if !proceed {
+				result = &cleaner.CleanResult{
+					ExtraFormulae: result.ExtraFormulae,
+					ExtraCasks:    result.ExtraCasks,
+					ExtraNpm:      result.ExtraNpm,
+				}
+			}
+		}
+		if result.Empty() {
+			ui.Muted("Nothing selected — no changes made.")
+			fmt.Println()
+			return nil
+		}
+	default:
+		showCleanPreview(result)
+		if !dryRun {
+			question := fmt.Sprintf("Remove %d packages?", result.TotalExtra())
+			if result.HasExtras() {
+				question = "Go ahead with this cleanup?"
+			}
+			proceed, err := ui.Confirm(question, false)
+			if err != nil {
+				return err
+			}
+			if !proceed {
+				ui.Muted("Clean cancelled.")
+				fmt.Println()
+				return nil
+			}
+		}
+	}
+
+	// Execute
+	if err := cleaner.Execute(result, dryRun); err != nil {
+		ui.Error(fmt.Sprintf("Some packages failed to remove: %v", err))
+	}
+
+	fmt.Println()
+	if dryRun {
+		ui.Muted("Dry run complete — no changes were made.")
+	} else {
+		ui.Success("Clean complete!")
+	}
+	fmt.Println()
+	return nil
+}
+
+// selectCleanItems lets the user untick extras in a checklist showing each
+// package's size and age. It returns the ticked extras and the names of
+// the unticked ones.
+func selectCleanItems(result *cleaner.CleanResult) (*cleaner.CleanResult, []string, bool, error) {
+	ui.Muted("Measuring disk usage...")
+	usage := cleaner.MeasureUsage(result)
+
+	selected := *result
+	lists := []struct {
+		group ui.CleanGroup
+		pkgs  []string
+		usage map[string]cleaner.Usage
+		into  *[]string
+	}{
+		{ui.CleanGroup{Name: "Formulae", Icon: "🍺"}, result.ExtraFormulae, usage.Formulae, &selected.ExtraFormulae},
+		{ui.CleanGroup{Name: "Casks", Icon: "📦"}, result.ExtraCasks, usage.Casks, &selected.ExtraCasks},
+		{ui.CleanGroup{Name: "NPM", Icon: "⬢"}, result.ExtraNpm, usage.Npm, &selected.ExtraNpm},
+	}
+
+	var groups []ui.CleanGroup
+	var targets []*[]string
+	for _, l := range lists {
+		if len(l.pkgs) == 0 {
+			continue
+		}
+		g := l.group
+		for _, pkg := range l.pkgs {
+			u := l.usage[pkg]
+			g.Items = append(g.Items, ui.CleanItem{Name: pkg, Size: u.Size, Modified: u.Modified})
+		}
+		groups = append(groups, g)
+		targets = append(targets, l.into)
+	}
+
+	remove, keep, confirmed, err := ui.RunCleanSelector(groups)
+	if err != nil || !confirmed {
+		return nil, nil, false, err
+	}
+
+	var kept []string
+	for i := range groups {
+		*targets[i] = remove[i]
+		kept = append(kept, keep[i]...)
+	}
+	return &selected, kept, true, nil
+}
+
+// offerToProtect asks whether packages the user chose to keep should stay
+// off future clean lists too.
+func offerToProtect(kept []string) {
+	add, err := ui.Confirm(fmt.Sprintf("Add the %d kept packages to your protect list so clean skips them next time?", len(kept)), false)
+	if err != nil || !add {
+		return
+	}
+	added, err := cleaner.AddProtected(kept...)
+	if err != nil {
+		ui.Warn(fmt.Sprintf("Failed to update protect list: %v", err))
+		return
+	}
+	if len(added) > 0 {
+		ui.Success(fmt.Sprintf("Protected %s", strings.Join(added, ", ")))
+	}
+	fmt.Println()
+}
+
+func runCleanUndo(ref string, dryRun bool) error {
+	fmt.Println()
+	ui.Header("OpenBoot Clean — Undo")
+	fmt.Println()
+
+	if dryRun {
+		ui.Muted("[DRY-RUN MODE — No packages will be installed]")
+		fmt.Println()
+	}
+
+	m, err := cleaner.ResolveRun(ref)
+	if err != nil {
+		return err
+	}
+
+	ui.Info(fmt.Sprintf("Clean run %s (%s) removed %d packages:", m.ID, m.CreatedAt.Local().Format("Jan 2, 2006 15:04"), m.Total()))
+	fmt.Println()
+	for _, group := range []struct {
+		label string
+		pkgs  []cleaner.RemovedPackage
+	}{
+		{"Formulae", m.Formulae},
+		{"Casks", m.Casks},
+		{"NPM", m.Npm},
+	} {
+		if len(group.pkgs) == 0 {
+			continue
+		}
+		names := make([]string, len(group.pkgs))
+		for i, pkg := range group.pkgs {
+			names[i] = pkg.Name
+			if pkg.Version != "" {
+				names[i] += " " + pkg.Version
+			}
+		}
+		ui.Info(fmt.Sprintf("  %s (%d):", group.label, len(group.pkgs)))
+		fmt.Printf("    %s\n", strings.Join(names, ", "))
+	}
+	fmt.Println()
+
+	if m.UndoneAt != nil {
+		ui.Warn(fmt.Sprintf("This run was already undone on %s.", m.UndoneAt.Local().Format("Jan 2, 2006 15:04")))
+		fmt.Println()
+	}
 
 	if !dryRun {
-		proceed, err := ui.Confirm(fmt.Sprintf("Remove %d packages?", result.TotalExtra()), false)
+		proceed, err := ui.Confirm(fmt.Sprintf("Reinstall %d packages?", m.Total()), true)
 		if err != nil {
 			return err
 		}
 		if !proceed {
-			ui.Muted("Clean cancelled.")
+			ui.Muted("Undo cancelled.")
 			fmt.Println()
 			return nil
 		}
 	}
 
-	// Execute
-	if err := cleaner.Execute(result, dryRun); err != nil {
-		ui.Error(fmt.Sprintf("Some packages failed to remove: %v", err))
+	if err := cleaner.Undo(m, dryRun); err != nil {
+		ui.Error(fmt.Sprintf("Some packages failed to reinstall: %v", err))
 	}
 
 	fmt.Println()
 	if dryRun {
 		ui.Muted("Dry run complete — no changes were made.")
 	} else {
-		ui.Success("Clean complete!")
+		ui.Success("Undo complete!")
 	}
 	fmt.Println()
 	return nil
 }
 
-func cleanFromFile(path string) (*cleaner.CleanResult, error) {
+func cleanFromFile(path string, opts cleaner.Options) (*cleaner.CleanResult, error) {
 	ui.Info(fmt.Sprintf("Comparing against snapshot: %s", path))
 	fmt.Println()
 
-	snap, err := snapshot.LoadFile(path)
+	snap, err := snapshot.LoadFile(resolveSnapshotPath(path))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load snapshot: %w", err)
 	}
 
-	return cleaner.DiffFromSnapshot(snap)
+	return cleaner.DiffFromSnapshot(snap, opts)
 }
 
-func cleanFromRemote(userSlug string) (*cleaner.CleanResult, error) {
+func cleanFromRemote(userSlug string, opts cleaner.Options) (*cleaner.CleanResult, error) {
 	ui.Info(fmt.Sprintf("Comparing against config: %s", userSlug))
 	fmt.Println()
 
@@ -129,11 +418,11 @@ func cleanFromRemote(userSlug string) (*cleaner.CleanResult, error) {
 		return nil, fmt.Errorf("failed to fetch remote config: %w", err)
 	}
 
-	return cleaner.DiffFromLists(rc.Packages, rc.Casks, rc.Npm)
+	return cleaner.DiffFromLists(rc.Packages, rc.Casks, rc.Npm, rc.Taps, opts)
 }
 
-func cleanFromLocalSnapshot() (*cleaner.CleanResult, error) {
-	ui.Info("Comparing against local snapshot (~/.openboot/snapshot.json)")
+func cleanFromLocalSnapshot(opts cleaner.Options) (*cleaner.CleanResult, error) {
+	ui.Info("Comparing against latest local snapshot")
 	fmt.Println()
 
 	snap, err := snapshot.LoadLocal()
@@ -141,24 +430,71 @@ func cleanFromLocalSnapshot() (*cleaner.CleanResult, error) {
 		return nil, fmt.Errorf("no local snapshot found — run 'openboot snapshot --local' first, or use --from or --user flags: %w", err)
 	}
 
-	return cleaner.DiffFromSnapshot(snap)
+	return cleaner.DiffFromSnapshot(snap, opts)
 }
 
 func showCleanPreview(result *cleaner.CleanResult) {
-	ui.Info(fmt.Sprintf("Found %d extra packages not in your config:", result.TotalExtra()))
+	if result.TotalExtra() > 0 {
+		ui.Info(fmt.Sprintf("Found %d extra packages not in your config:", result.TotalExtra()))
+		fmt.Println()
+
+		if len(result.ExtraFormulae) > 0 {
+			ui.Info(fmt.Sprintf("  Formulae (%d):", len(result.ExtraFormulae)))
+			fmt.Printf("    %s\n", strings.Join(result.ExtraFormulae, ", "))
+		}
+		if len(result.ExtraCasks) > 0 {
+			ui.Info(fmt.Sprintf("  Casks (%d):", len(result.ExtraCasks)))
+			fmt.Printf("    %s\n", strings.Join(result.ExtraCasks, ", "))
+		}
+		if len(result.ExtraNpm) > 0 {
+			ui.Info(fmt.Sprintf("  NPM (%d):", len(result.ExtraNpm)))
+			fmt.Printf("    %s\n", strings.Join(result.ExtraNpm, ", "))
+		}
+		fmt.Println()
+	}
+	showExtrasPreview(result)
+	showProtected(result)
+}
+
+// showExtrasPreview lists the optional categories that have work.
+func showExtrasPreview(result *cleaner.CleanResult) {
+	if !result.HasExtras() {
+		return
+	}
+	ui.Info("Also cleaning up:")
 	fmt.Println()
 
-	if len(result.ExtraFormulae) > 0 {
-		ui.Info(fmt.Sprintf("  Formulae (%d):", len(result.ExtraFormulae)))
-		fmt.Printf("    %s\n", strings.Join(result.ExtraFormulae, ", "))
+	if len(result.ExtraTaps) > 0 {
+		ui.Info(fmt.Sprintf("  Unused taps (%d):", len(result.ExtraTaps)))
+		fmt.Printf("    %s\n", strings.Join(result.ExtraTaps, ", "))
 	}
-	if len(result.ExtraCasks) > 0 {
-		ui.Info(fmt.Sprintf("  Casks (%d):", len(result.ExtraCasks)))
-		fmt.Printf("    %s\n", strings.Join(result.ExtraCasks, ", "))
+	if len(result.Orphans) > 0 {
+		ui.Info(fmt.Sprintf("  Orphaned dependencies (%d):", len(result.Orphans)))
+		fmt.Printf("    %s\n", strings.Join(result.Orphans, ", "))
 	}
-	if len(result.ExtraNpm) > 0 {
-		ui.Info(fmt.Sprintf("  NPM (%d):", len(result.ExtraNpm)))
-		fmt.Printf("    %s\n", strings.Join(result.ExtraNpm, ", "))
+	if len(result.StalePlugins) > 0 {
+		ui.Info(fmt.Sprintf("  Oh-My-Zsh plugins (%d):", len(result.StalePlugins)))
+		fmt.Printf("    %s\n", strings.Join(result.StalePlugins, ", "))
+	}
+	if result.BrewCache != nil {
+		ui.Info("  Homebrew cache:")
+		if result.BrewCache.Size != "" {
+			fmt.Printf("    frees about %s\n", result.BrewCache.Size)
+		} else {
+			fmt.Println("    whatever the removals above leave behind")
+		}
+	}
+	if result.NpmCache != nil {
+		ui.Info("  npm cache:")
+		fmt.Printf("    %s on disk; stale entries are garbage-collected\n", result.NpmCache.Size)
+	}
+	fmt.Println()
+}
+
+func showProtected(result *cleaner.CleanResult) {
+	if len(result.Protected) == 0 {
+		return
 	}
+	ui.Muted(fmt.Sprintf("Keeping %d protected: %s", len(result.Protected), strings.Join(result.Protected, ", ")))
 	fmt.Println()
 }
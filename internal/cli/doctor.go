@@ -1,88 +1,512 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 
+	"github.com/openbootdotdev/openboot/internal/auth"
 	"github.com/openbootdotdev/openboot/internal/brew"
+	"github.com/openbootdotdev/openboot/internal/config"
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/openbootdotdev/openboot/internal/system"
 	"github.com/openbootdotdev/openboot/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+// exitDoctorFailed is the exit code used when a check reaches the
+// --fail-on severity.
+const exitDoctorFailed = 2
+
 var doctorCmd = &cobra.Command{
 	Use:   "doctor",
 	Short: "Check system health and diagnose issues",
 	Long: `Run diagnostic checks on your development environment.
 
-Checks performed:
-- Homebrew installation and health
-- Git configuration
-- Shell configuration (Oh-My-Zsh)
-- Common development tools
-- Outdated packages`,
+Checks performed (select with --check, exclude with --skip):
+  network       Network connectivity
+  disk          Disk space
+  install       OpenBoot installation conflicts
+  path          Login shell PATH: duplicate and missing entries, binaries
+                shadowing Homebrew's, Intel and native Homebrew side by side
+  homebrew      Homebrew installation and health, outdated packages
+  git           Git installation and identity
+  shell         Shell configuration (Oh-My-Zsh, .zshrc)
+  tools         Common development tools
+  requirements  Team requirements from --requirements or --user
+
+Requirements come from the requirements section of a config file:
+
+  requirements:
+    commands:
+      - name: node
+        version: ">=20"        # also "^1.22", "~3.11", "3.12.x", ">=1.20 <2"
+    env: [GITHUB_TOKEN]
+    files: ["~/.ssh/id_ed25519"]
+
+Every result has a stable ID such as "homebrew.health" or "tools.jq";
+--check and --skip accept a group or a full ID.
+
+Exits with status 2 when any check reaches the --fail-on severity
+(info, warn or error; default error, or never).
+
+With --fix, problems that have an automatic fix (installing Homebrew,
+Git or missing tools, setting the git identity, removing a duplicate
+OpenBoot install, running brew cleanup and the safe 'brew doctor' fixes)
+are fixed after confirmation, then the checks run again and a before/after
+report is shown. --yes applies them without asking; the git identity then
+comes from OPENBOOT_GIT_NAME and OPENBOOT_GIT_EMAIL.
+
+Examples:
+  openboot doctor                             Run every check
+  openboot doctor --json                      Machine-readable results
+  openboot doctor --check homebrew,git        Only the Homebrew and Git checks
+  openboot doctor --skip network --fail-on warn
+  openboot doctor --requirements openboot.yaml  Check the team's requirements
+  openboot doctor --fix                       Fix what can be fixed automatically
+  openboot doctor --fix --yes                 Fix without prompting`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runDoctor()
+		return runDoctor(cmd)
 	},
 }
 
+func init() {
+	doctorCmd.Flags().Bool("json", false, "output the check results as JSON")
+	doctorCmd.Flags().StringSlice("check", nil, "only run these checks (group or result IDs)")
+	doctorCmd.Flags().StringSlice("skip", nil, "skip these checks (group or result IDs)")
+	doctorCmd.Flags().String("fail-on", "error", "exit non-zero when a check reaches this severity: info, warn, error or never")
+	doctorCmd.Flags().String("requirements", "", "config file (YAML or JSON) whose requirements section to check")
+	doctorCmd.Flags().String("user", "", "openboot.dev username/slug whose config requirements to check")
+	doctorCmd.Flags().Bool("fix", false, "apply automatic fixes for failed checks")
+	doctorCmd.Flags().BoolP("yes", "y", false, "with --fix, apply fixes without prompting")
+}
+
 type checkResult struct {
-	name    string
-	status  string
-	message string
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Fixable bool   `json:"fixable,omitempty"`
+
+	fix *doctorFix
+}
+
+// doctorFix is an automatic remediation for a failed check. unattended
+// is set for --yes, when the fix must not prompt.
+type doctorFix struct {
+	description string
+	run         func(unattended bool) error
+}
+
+// withFix returns r with an automatic fix attached.
+func (r checkResult) withFix(description string, run func(unattended bool) error) checkResult {
+	r.fix = &doctorFix{description: description, run: run}
+	r.Fixable = true
+	return r
+}
+
+// doctorCheck is a group of related checks. The IDs of its results are
+// its ID or start with its ID and a dot.
+type doctorCheck struct {
+	id  string
+	run func() []checkResult
+}
+
+var doctorChecks = []doctorCheck{
+	{id: "network", run: checkNetwork},
+	{id: "disk", run: checkDiskSpace},
+	{id: "install", run: checkInstallationConflicts},
+	{id: "path", run: checkPath},
+	{id: "homebrew", run: checkHomebrew},
+	{id: "git", run: checkGit},
+	{id: "shell", run: checkShell},
+	{id: "tools", run: checkTools},
+	{id: "requirements", run: checkRequirements},
+}
+
+// doctorRequirements are the team requirements checked by this run, from
+// --requirements or --user.
+var doctorRequirements *config.Requirements
+
+// checkSeverity orders statuses for --fail-on.
+var checkSeverity = map[string]int{"ok": 0, "info": 1, "warn": 2, "error": 3}
+
+// checkFilter holds the --check and --skip IDs.
+type checkFilter struct {
+	only []string
+	skip []string
+}
+
+func newCheckFilter(only, skip []string) (checkFilter, error) {
+	for _, id := range append(append([]string{}, only...), skip...) {
+		group, _, _ := strings.Cut(id, ".")
+		known := false
+		for _, c := range doctorChecks {
+			if c.id == group {
+				known = true
+				break
+			}
+		}
+		if !known {
+			ids := make([]string, len(doctorChecks))
+			for i, c := range doctorChecks {
+				ids[i] = c.id
+			}
+			return checkFilter{}, fmt.Errorf("unknown check %q (want one of %s, or a result ID within them)", id, strings.Join(ids, ", "))
+		}
+	}
+	return checkFilter{only: only, skip: skip}, nil
+}
+
+// runs reports whether any result of the group can be wanted.
+func (f checkFilter) runs(group string) bool {
+	for _, id := range f.skip {
+		if id == group {
+			return false
+		}
+	}
+	if len(f.only) == 0 {
+		return true
+	}
+	for _, id := range f.only {
+		if id == group || strings.HasPrefix(id, group+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (f checkFilter) wants(id string) bool {
+	if len(f.only) > 0 && !matchesCheckID(id, f.only) {
+		return false
+	}
+	return !matchesCheckID(id, f.skip)
+}
+
+// matchesCheckID reports whether id is one of ids or within one of them.
+func matchesCheckID(id string, ids []string) bool {
+	for _, want := range ids {
+		if id == want || strings.HasPrefix(id, want+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func runChecks(filter checkFilter) []checkResult {
+	// Fixes can change PATH, so it's looked up again on every run.
+	shellPath = nil
+	var results []checkResult
+	for _, c := range doctorChecks {
+		if !filter.runs(c.id) {
+			continue
+		}
+		for _, r := range c.run() {
+			if filter.wants(r.ID) {
+				results = append(results, r)
+			}
+		}
+	}
+	return results
 }
 
-func runDoctor() error {
+// checksFailed reports whether any result reaches failOn, which is a
+// status or "never".
+func checksFailed(results []checkResult, failOn string) bool {
+	threshold, ok := checkSeverity[failOn]
+	if !ok || failOn == "ok" {
+		return false
+	}
+	for _, r := range results {
+		if checkSeverity[r.Status] >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
+func runDoctor(cmd *cobra.Command) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	only, _ := cmd.Flags().GetStringSlice("check")
+	skip, _ := cmd.Flags().GetStringSlice("skip")
+	failOn, _ := cmd.Flags().GetString("fail-on")
+	fix, _ := cmd.Flags().GetBool("fix")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	if _, ok := checkSeverity[failOn]; (!ok || failOn == "ok") && failOn != "never" {
+		return fmt.Errorf("invalid --fail-on %q (want info, warn, error or never)", failOn)
+	}
+	if fix && jsonOutput {
+		return fmt.Errorf("--fix can't be combined with --json")
+	}
+	filter, err := newCheckFilter(only, skip)
+	if err != nil {
+		return err
+	}
+	requirementsFile, _ := cmd.Flags().GetString("requirements")
+	user, _ := cmd.Flags().GetString("user")
+	if doctorRequirements, err = loadRequirements(requirementsFile, user); err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		results := runChecks(filter)
+		if results == nil {
+			results = []checkResult{}
+		}
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal check results: %w", err)
+		}
+		fmt.Println(string(data))
+		return doctorExit(cmd, results, failOn)
+	}
+
 	fmt.Println()
 	ui.Header("OpenBoot Doctor")
 	fmt.Println()
 
-	var results []checkResult
-	var issues int
+	results := runChecks(filter)
+	issues := printCheckResults(results)
+
+	if filter.runs("homebrew") {
+		suggestions, _ := brew.DoctorDiagnose()
+		if len(suggestions) > 0 {
+			fmt.Println()
+			ui.Info("Suggested fixes:")
+			for _, s := range suggestions {
+				fmt.Printf("    %s\n", s)
+			}
+		}
+	}
+
+	if fix {
+		after, err := fixChecks(results, filter, yes)
+		if err != nil {
+			return err
+		}
+		return doctorExit(cmd, after, failOn)
+	}
+
+	fmt.Println()
+	if issues == 0 {
+		ui.Success("All checks passed! Your environment is healthy.")
+	} else {
+		ui.Muted(fmt.Sprintf("Found %d issue(s). Run 'openboot doctor --fix' or 'openboot' to fix them.", issues))
+	}
+	fmt.Println()
 
-	results = append(results, checkNetwork()...)
-	results = append(results, checkDiskSpace()...)
-	results = append(results, checkInstallationConflicts()...)
-	results = append(results, checkHomebrew()...)
-	results = append(results, checkGit()...)
-	results = append(results, checkShell()...)
-	results = append(results, checkTools()...)
+	return doctorExit(cmd, results, failOn)
+}
 
+// printCheckResults prints one line per result and returns how many are
+// warnings or errors.
+func printCheckResults(results []checkResult) int {
+	var issues int
 	for _, r := range results {
-		switch r.status {
+		switch r.Status {
 		case "ok":
-			fmt.Printf("  %s %s\n", ui.Green("✓"), r.name)
+			fmt.Printf("  %s %s\n", ui.Green("✓"), r.Name)
 		case "warn":
-			fmt.Printf("  %s %s: %s\n", ui.Yellow("!"), r.name, r.message)
+			fmt.Printf("  %s %s: %s\n", ui.Yellow("!"), r.Name, r.Message)
 			issues++
 		case "error":
-			fmt.Printf("  %s %s: %s\n", ui.Red("✗"), r.name, r.message)
+			fmt.Printf("  %s %s: %s\n", ui.Red("✗"), r.Name, r.Message)
 			issues++
 		case "info":
-			fmt.Printf("  %s %s: %s\n", ui.Cyan("i"), r.name, r.message)
+			fmt.Printf("  %s %s: %s\n", ui.Cyan("i"), r.Name, r.Message)
+		}
+	}
+	return issues
+}
+
+// fixChecks applies the fixes of failed results, after confirmation
+// unless yes is set, then runs the checks again and reports what changed.
+// It returns the results of the second run, or results if nothing was
+// fixed.
+func fixChecks(results []checkResult, filter checkFilter, yes bool) ([]checkResult, error) {
+	var fixable []checkResult
+	for _, r := range results {
+		if r.Status != "ok" && r.fix != nil {
+			fixable = append(fixable, r)
+		}
+	}
+
+	fmt.Println()
+	if len(fixable) == 0 {
+		ui.Muted("Nothing can be fixed automatically.")
+		fmt.Println()
+		return results, nil
+	}
+
+	ui.Info(fmt.Sprintf("%d fix(es) available:", len(fixable)))
+	for _, r := range fixable {
+		fmt.Printf("    %s %s\n", ui.Cyan(r.ID+":"), r.fix.description)
+	}
+	fmt.Println()
+
+	if !yes {
+		if !system.HasTTY() {
+			return nil, fmt.Errorf("--fix needs a terminal to confirm; pass --yes to apply fixes unattended")
+		}
+		ok, err := ui.Confirm("Apply these fixes?", true)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			ui.Muted("No changes made.")
+			fmt.Println()
+			return results, nil
 		}
 	}
 
-	suggestions, _ := brew.DoctorDiagnose()
-	if len(suggestions) > 0 {
+	var failed int
+	for _, r := range fixable {
 		fmt.Println()
-		ui.Info("Suggested fixes:")
-		for _, s := range suggestions {
-			fmt.Printf("    %s\n", s)
+		ui.Info(r.fix.description + "...")
+		if err := r.fix.run(yes); err != nil {
+			ui.Error(fmt.Sprintf("%s: %v", r.ID, err))
+			failed++
 		}
 	}
 
+	after := runChecks(filter)
+	status := make(map[string]string, len(after))
+	for _, r := range after {
+		status[r.ID] = r.Status
+	}
+
 	fmt.Println()
-	if issues == 0 {
-		ui.Success("All checks passed! Your environment is healthy.")
+	ui.Header("Before / after")
+	fmt.Println()
+	for _, r := range fixable {
+		now, ok := status[r.ID]
+		if !ok {
+			// Missing-tool results disappear once the tool is installed.
+			now = "ok"
+		}
+		mark := ui.Green("✓")
+		if now != "ok" {
+			mark = ui.Red("✗")
+		}
+		fmt.Printf("  %s %s: %s → %s\n", mark, r.ID, r.Status, now)
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		ui.Warn(fmt.Sprintf("%d fix(es) failed", failed))
 	} else {
-		ui.Muted(fmt.Sprintf("Found %d issue(s). Run 'openboot' to fix missing tools.", issues))
+		ui.Success("Fixes applied.")
 	}
 	fmt.Println()
+	return after, nil
+}
+
+func doctorExit(cmd *cobra.Command, results []checkResult, failOn string) error {
+	if !checksFailed(results, failOn) {
+		return nil
+	}
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return &ExitError{Code: exitDoctorFailed}
+}
+
+// loadRequirements returns the requirements from file or the remote
+// config of user, or nil when neither is given.
+func loadRequirements(file, user string) (*config.Requirements, error) {
+	switch {
+	case file != "" && user != "":
+		return nil, fmt.Errorf("use either --requirements or --user, not both")
+	case file != "":
+		return config.LoadRequirements(file)
+	case user != "":
+		var token string
+		if stored, err := auth.LoadToken(); err == nil && stored != nil {
+			token = stored.Token
+		}
+		rc, err := config.FetchRemoteConfig(user, token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch remote config: %w", err)
+		}
+		if rc.Requirements.Empty() {
+			return nil, fmt.Errorf("config @%s has no requirements", user)
+		}
+		return rc.Requirements, nil
+	}
+	return nil, nil
+}
+
+// brewInstallFix installs a formula with Homebrew.
+func brewInstallFix(formula string) func(bool) error {
+	return func(bool) error {
+		return brew.Install([]string{formula}, false)
+	}
+}
+
+// brewCleanupFix frees disk space taken by old versions and the download
+// cache.
+func brewCleanupFix(bool) error {
+	return brew.RunRemediation(brew.Remediation{Args: []string{"cleanup", "--prune=all"}})
+}
+
+func installHomebrewFix(unattended bool) error {
+	if unattended {
+		// The Homebrew installer skips its prompts with NONINTERACTIVE set.
+		os.Setenv("NONINTERACTIVE", "1")
+	}
+	return system.InstallHomebrew()
+}
+
+// gitIdentityFix sets the global git identity from OPENBOOT_GIT_NAME and
+// OPENBOOT_GIT_EMAIL, prompting for whatever is missing.
+func gitIdentityFix(unattended bool) error {
+	name, email := system.GetExistingGitConfig()
+	if v := os.Getenv("OPENBOOT_GIT_NAME"); v != "" {
+		name = v
+	}
+	if v := os.Getenv("OPENBOOT_GIT_EMAIL"); v != "" {
+		email = v
+	}
+	if name == "" || email == "" {
+		if unattended {
+			return fmt.Errorf("set OPENBOOT_GIT_NAME and OPENBOOT_GIT_EMAIL to fix the git identity unattended")
+		}
+		var err error
+		if name, email, err = ui.InputGitConfig(); err != nil {
+			return err
+		}
+		if name == "" || email == "" {
+			return fmt.Errorf("name and email are required")
+		}
+	}
+	return system.ConfigureGit(name, email)
+}
 
-	return nil
+// brewDoctorFix applies the 'brew doctor' remediations that can run
+// without sudo or user interaction.
+func brewDoctorFix(remediations []brew.Remediation) func(bool) error {
+	return func(bool) error {
+		var errs []string
+		seen := make(map[string]bool)
+		for _, r := range remediations {
+			key := strings.Join(r.Args, " ")
+			if len(r.Args) == 0 || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if err := brew.RunRemediation(r); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("%s", strings.Join(errs, "; "))
+		}
+		return nil
+	}
 }
 
 func checkHomebrew() []checkResult {
@@ -90,42 +514,48 @@ func checkHomebrew() []checkResult {
 
 	_, err := exec.LookPath("brew")
 	if err != nil {
-		return []checkResult{{
-			name:    "Homebrew",
-			status:  "error",
-			message: "not installed",
-		}}
+		return []checkResult{checkResult{
+			ID:      "homebrew.installed",
+			Name:    "Homebrew",
+			Status:  "error",
+			Message: "not installed",
+		}.withFix("Install Homebrew", installHomebrewFix)}
 	}
 
 	results = append(results, checkResult{
-		name:   "Homebrew installed",
-		status: "ok",
+		ID:     "homebrew.installed",
+		Name:   "Homebrew installed",
+		Status: "ok",
 	})
 
-	cmd := exec.Command("brew", "doctor")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		results = append(results, checkResult{
-			name:    "Homebrew health",
-			status:  "warn",
-			message: "run 'brew doctor' for details",
-		})
-	} else if strings.Contains(string(output), "ready to brew") {
-		results = append(results, checkResult{
-			name:   "Homebrew health",
-			status: "ok",
-		})
+	remediations, err := brew.DoctorRemediations()
+	health := checkResult{
+		ID:     "homebrew.health",
+		Name:   "Homebrew health",
+		Status: "ok",
+	}
+	if err != nil || len(remediations) > 0 {
+		health.Status = "warn"
+		health.Message = "run 'brew doctor' for details"
+	}
+	for _, r := range remediations {
+		if len(r.Args) > 0 {
+			health = health.withFix("Apply the automatic 'brew doctor' fixes", brewDoctorFix(remediations))
+			break
+		}
 	}
+	results = append(results, health)
 
-	cmd = exec.Command("brew", "outdated", "--json")
-	output, _ = cmd.Output()
+	cmd := exec.Command("brew", "outdated", "--json")
+	output, _ := cmd.Output()
 	if len(output) > 10 {
 		count := strings.Count(string(output), "\"name\"")
 		if count > 0 {
 			results = append(results, checkResult{
-				name:    "Outdated packages",
-				status:  "info",
-				message: fmt.Sprintf("%d packages can be upgraded (run 'openboot update')", count),
+				ID:      "homebrew.outdated",
+				Name:    "Outdated packages",
+				Status:  "info",
+				Message: fmt.Sprintf("%d packages can be upgraded (run 'openboot update')", count),
 			})
 		}
 	}
@@ -138,16 +568,18 @@ func checkGit() []checkResult {
 
 	_, err := exec.LookPath("git")
 	if err != nil {
-		return []checkResult{{
-			name:    "Git",
-			status:  "error",
-			message: "not installed",
-		}}
+		return []checkResult{checkResult{
+			ID:      "git.installed",
+			Name:    "Git",
+			Status:  "error",
+			Message: "not installed",
+		}.withFix("Install Git with Homebrew", brewInstallFix("git"))}
 	}
 
 	results = append(results, checkResult{
-		name:   "Git installed",
-		status: "ok",
+		ID:     "git.installed",
+		Name:   "Git installed",
+		Status: "ok",
 	})
 
 	name, _ := exec.Command("git", "config", "--global", "user.name").Output()
@@ -155,14 +587,16 @@ func checkGit() []checkResult {
 
 	if len(strings.TrimSpace(string(name))) == 0 || len(strings.TrimSpace(string(email))) == 0 {
 		results = append(results, checkResult{
-			name:    "Git identity",
-			status:  "warn",
-			message: "user.name or user.email not configured",
-		})
+			ID:      "git.identity",
+			Name:    "Git identity",
+			Status:  "warn",
+			Message: "user.name or user.email not configured",
+		}.withFix("Set git user.name and user.email", gitIdentityFix))
 	} else {
 		results = append(results, checkResult{
-			name:   "Git identity",
-			status: "ok",
+			ID:     "git.identity",
+			Name:   "Git identity",
+			Status: "ok",
 		})
 	}
 
@@ -175,37 +609,42 @@ func checkShell() []checkResult {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return []checkResult{{
-			name:    "Shell",
-			status:  "error",
-			message: "cannot determine home directory",
+			ID:      "shell.home",
+			Name:    "Shell",
+			Status:  "error",
+			Message: "cannot determine home directory",
 		}}
 	}
 	omzPath := filepath.Join(home, ".oh-my-zsh")
 
 	if _, err := os.Stat(omzPath); os.IsNotExist(err) {
 		results = append(results, checkResult{
-			name:    "Oh-My-Zsh",
-			status:  "info",
-			message: "not installed (optional)",
+			ID:      "shell.ohmyzsh",
+			Name:    "Oh-My-Zsh",
+			Status:  "info",
+			Message: "not installed (optional)",
 		})
 	} else {
 		results = append(results, checkResult{
-			name:   "Oh-My-Zsh installed",
-			status: "ok",
+			ID:     "shell.ohmyzsh",
+			Name:   "Oh-My-Zsh installed",
+			Status: "ok",
 		})
 	}
 
 	zshrcPath := filepath.Join(home, ".zshrc")
 	if _, err := os.Stat(zshrcPath); os.IsNotExist(err) {
 		results = append(results, checkResult{
-			name:    ".zshrc",
-			status:  "info",
-			message: "not found",
+			ID:      "shell.zshrc",
+			Name:    ".zshrc",
+			Status:  "info",
+			Message: "not found",
 		})
 	} else {
 		results = append(results, checkResult{
-			name:   ".zshrc exists",
-			status: "ok",
+			ID:     "shell.zshrc",
+			Name:   ".zshrc exists",
+			Status: "ok",
 		})
 	}
 
@@ -220,111 +659,132 @@ func checkTools() []checkResult {
 	for _, tool := range essentialTools {
 		if _, err := exec.LookPath(tool); err != nil {
 			results = append(results, checkResult{
-				name:    tool,
-				status:  "info",
-				message: "not installed",
-			})
+				ID:      "tools." + tool,
+				Name:    tool,
+				Status:  "info",
+				Message: "not installed",
+			}.withFix("Install "+tool+" with Homebrew", brewInstallFix(tool)))
 		}
 	}
 
 	return results
 }
 
-func checkNetwork() []checkResult {
-	if err := brew.CheckNetwork(); err != nil {
-		return []checkResult{{
-			name:    "Network",
-			status:  "error",
-			message: "cannot reach GitHub (required for Homebrew)",
-		}}
+func checkRequirements() []checkResult {
+	req := doctorRequirements
+	if req == nil {
+		return nil
 	}
-	return []checkResult{{
-		name:   "Network connectivity",
-		status: "ok",
-	}}
-}
+	var results []checkResult
 
-func checkDiskSpace() []checkResult {
-	availableGB, err := brew.CheckDiskSpace()
-	if err != nil {
-		return nil
+	for _, c := range req.Commands {
+		r := checkResult{ID: "requirements.command." + c.Name, Name: c.Name, Status: "error"}
+		if _, err := exec.LookPath(c.Name); err != nil {
+			r.Message = "not installed"
+			if c.Version != "" {
+				r.Message += ", requirements need " + c.Version
+			}
+			results = append(results, r)
+			continue
+		}
+		if c.Version == "" {
+			r.Status = "ok"
+			results = append(results, r)
+			continue
+		}
+
+		version, err := snapshot.ToolVersion(c.Name)
+		if err != nil {
+			r.Message = fmt.Sprintf("can't determine version: %v", err)
+			results = append(results, r)
+			continue
+		}
+		ok, err := snapshot.MatchVersion(version, c.Version)
+		switch {
+		case err != nil:
+			r.Message = err.Error()
+		case !ok:
+			r.Message = fmt.Sprintf("%s installed, requirements need %s", version, c.Version)
+		default:
+			r.Status = "ok"
+			r.Name = fmt.Sprintf("%s %s (%s)", c.Name, version, c.Version)
+		}
+		results = append(results, r)
 	}
 
-	if availableGB < 1.0 {
-		return []checkResult{{
-			name:    "Disk space",
-			status:  "error",
-			message: fmt.Sprintf("critically low: %.1f GB available", availableGB),
-		}}
+	for _, name := range req.Env {
+		r := checkResult{ID: "requirements.env." + name, Name: "$" + name, Status: "ok"}
+		if os.Getenv(name) == "" {
+			r.Status = "error"
+			r.Message = "not set"
+		}
+		results = append(results, r)
 	}
-	if availableGB < 5.0 {
+
+	home, _ := os.UserHomeDir()
+	for _, file := range req.Files {
+		r := checkResult{ID: "requirements.file." + file, Name: file, Status: "ok"}
+		path := file
+		if strings.HasPrefix(path, "~/") && home != "" {
+			path = filepath.Join(home, path[2:])
+		}
+		if _, err := os.Stat(path); err != nil {
+			r.Status = "error"
+			r.Message = "not found"
+		}
+		results = append(results, r)
+	}
+
+	return results
+}
+
+func checkNetwork() []checkResult {
+	if err := brew.CheckNetwork(); err != nil {
 		return []checkResult{{
-			name:    "Disk space",
-			status:  "warn",
-			message: fmt.Sprintf("low: %.1f GB available", availableGB),
+			ID:      "network",
+			Name:    "Network",
+			Status:  "error",
+			Message: "cannot reach GitHub (required for Homebrew)",
 		}}
 	}
 	return []checkResult{{
-		name:   fmt.Sprintf("Disk space (%.0f GB free)", availableGB),
-		status: "ok",
+		ID:     "network",
+		Name:   "Network connectivity",
+		Status: "ok",
 	}}
 }
 
-func checkInstallationConflicts() []checkResult {
-	var results []checkResult
-	var installations []string
-
-	home, err := os.UserHomeDir()
+func checkDiskSpace() []checkResult {
+	availableGB, err := brew.CheckDiskSpace()
 	if err != nil {
 		return nil
 	}
 
-	curlInstallPath := filepath.Join(home, ".openboot", "bin", "openboot")
-	if _, err := os.Stat(curlInstallPath); err == nil {
-		installations = append(installations, curlInstallPath+" (curl install)")
-	}
-
-	if brewPath, err := exec.LookPath("brew"); err == nil {
-		cmd := exec.Command(brewPath, "list", "openboot")
-		if err := cmd.Run(); err == nil {
-			if openbootPath, err := exec.LookPath("openboot"); err == nil {
-				if realPath, err := filepath.EvalSymlinks(openbootPath); err == nil {
-					if strings.Contains(realPath, "Cellar/openboot") || strings.Contains(realPath, "opt/homebrew") {
-						installations = append(installations, openbootPath+" (Homebrew)")
-					}
-				}
-			}
+	var result checkResult
+	switch {
+	case availableGB < 1.0:
+		result = checkResult{
+			ID:      "disk",
+			Name:    "Disk space",
+			Status:  "error",
+			Message: fmt.Sprintf("critically low: %.1f GB available", availableGB),
 		}
-	}
-
-	if len(installations) == 0 {
+	case availableGB < 5.0:
+		result = checkResult{
+			ID:      "disk",
+			Name:    "Disk space",
+			Status:  "warn",
+			Message: fmt.Sprintf("low: %.1f GB available", availableGB),
+		}
+	default:
 		return []checkResult{{
-			name:    "Installation",
-			status:  "error",
-			message: "OpenBoot not found in standard locations",
+			ID:     "disk",
+			Name:   fmt.Sprintf("Disk space (%.0f GB free)", availableGB),
+			Status: "ok",
 		}}
 	}
-
-	if len(installations) > 1 {
-		results = append(results, checkResult{
-			name:    "Multiple installations",
-			status:  "warn",
-			message: fmt.Sprintf("found at: %s", strings.Join(installations, ", ")),
-		})
-		results = append(results, checkResult{
-			name:    "Recommendation",
-			status:  "info",
-			message: "keep only one installation method to avoid conflicts",
-		})
-		return results
+	if _, err := exec.LookPath("brew"); err == nil {
+		result = result.withFix("Free space with 'brew cleanup --prune=all'", brewCleanupFix)
 	}
-
-	if whichPath, err := exec.LookPath("openboot"); err == nil {
-		results = append(results, checkResult{
-			name:   fmt.Sprintf("Single installation: %s", whichPath),
-			status: "ok",
-		})
-	}
-
-	return results
+	return []checkResult{result}
 }
@@ -0,0 +1,327 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Where Homebrew installs. /usr/local is the Intel prefix, which runs
+// under Rosetta on Apple silicon.
+var (
+	nativeBrewPrefix = "/opt/homebrew"
+	linuxBrewPrefix  = "/home/linuxbrew/.linuxbrew"
+	intelBrewPrefix  = "/usr/local"
+)
+
+const pathMarker = "__OPENBOOT_PATH__"
+
+// loginShellPATH returns PATH as set up by the user's login shell, which
+// is what their terminal uses, rather than the PATH openboot inherited.
+var loginShellPATH = func() (string, error) {
+	sh := os.Getenv("SHELL")
+	if sh == "" {
+		sh = "/bin/zsh"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Interactive so .zshrc (where nvm and friends live) is read too. The
+	// markers separate PATH from anything the rc files print.
+	cmd := exec.CommandContext(ctx, sh, "-l", "-i", "-c", `printf '%s%s%s\n' "`+pathMarker+`" "$PATH" "`+pathMarker+`"`)
+	cmd.Env = append(os.Environ(), "DISABLE_AUTO_UPDATE=true")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", sh, err)
+	}
+	_, rest, ok := bytes.Cut(output, []byte(pathMarker))
+	if !ok {
+		return "", fmt.Errorf("%s printed no PATH", sh)
+	}
+	value, _, ok := bytes.Cut(rest, []byte(pathMarker))
+	if !ok {
+		return "", fmt.Errorf("%s printed no PATH", sh)
+	}
+	return string(value), nil
+}
+
+// effectivePath is the login shell PATH split into directories.
+type effectivePath struct {
+	dirs []string
+	// err is why the login shell couldn't be used; dirs is then the
+	// inherited PATH.
+	err error
+}
+
+// shellPath caches the login shell PATH for one run of the checks.
+var shellPath *effectivePath
+
+func getShellPath() *effectivePath {
+	if shellPath != nil {
+		return shellPath
+	}
+	value, err := loginShellPATH()
+	if err != nil {
+		value = os.Getenv("PATH")
+	}
+	shellPath = &effectivePath{err: err}
+	for _, dir := range filepath.SplitList(value) {
+		if dir != "" {
+			shellPath.dirs = append(shellPath.dirs, dir)
+		}
+	}
+	return shellPath
+}
+
+// whichAll returns every executable called name in dirs, in PATH order.
+func whichAll(name string, dirs []string) []string {
+	var found []string
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, name)
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			found = append(found, candidate)
+		}
+	}
+	return found
+}
+
+// brewInstalls returns the prefixes with a Homebrew installation, native
+// first.
+func brewInstalls() []string {
+	var prefixes []string
+	for _, p := range []string{nativeBrewPrefix, linuxBrewPrefix, intelBrewPrefix} {
+		if _, err := os.Stat(filepath.Join(p, "bin", "brew")); err == nil {
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes
+}
+
+// inPrefix reports whether path, with symlinks resolved, is inside prefix.
+func inPrefix(path, prefix string) bool {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if resolvedPrefix, err := filepath.EvalSymlinks(prefix); err == nil {
+		prefix = resolvedPrefix
+	}
+	return strings.HasPrefix(path, prefix+string(filepath.Separator))
+}
+
+func checkPath() []checkResult {
+	p := getShellPath()
+	var results []checkResult
+
+	if p.err != nil {
+		results = append(results, checkResult{
+			ID:      "path.shell",
+			Name:    "Login shell PATH",
+			Status:  "info",
+			Message: fmt.Sprintf("couldn't read it (%v), checked the current PATH instead", p.err),
+		})
+	}
+
+	seen := make(map[string]bool)
+	var duplicates, missing []string
+	for _, dir := range p.dirs {
+		clean := filepath.Clean(dir)
+		if seen[clean] {
+			duplicates = append(duplicates, dir)
+			continue
+		}
+		seen[clean] = true
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			missing = append(missing, dir)
+		}
+	}
+	results = append(results, pathListResult("path.duplicates", "PATH entries unique", "listed more than once", duplicates))
+	results = append(results, pathListResult("path.missing", "PATH entries exist", "missing", missing))
+
+	installs := brewInstalls()
+	if containsPrefix(installs, nativeBrewPrefix) && containsPrefix(installs, intelBrewPrefix) {
+		msg := fmt.Sprintf("Intel (Rosetta) Homebrew in %s alongside native Homebrew in %s", intelBrewPrefix, nativeBrewPrefix)
+		native := dirIndex(p.dirs, filepath.Join(nativeBrewPrefix, "bin"))
+		intel := dirIndex(p.dirs, filepath.Join(intelBrewPrefix, "bin"))
+		if intel >= 0 && (native < 0 || intel < native) {
+			msg += "; the Intel one comes first on PATH"
+		}
+		results = append(results, checkResult{
+			ID:      "path.rosetta",
+			Name:    "Homebrew installations",
+			Status:  "warn",
+			Message: msg,
+		})
+	}
+
+	if len(installs) > 0 {
+		results = append(results, shadowedBinaries(installs[0], p.dirs)...)
+	}
+
+	return results
+}
+
+// shadowedBinaries reports Homebrew binaries from prefix that lose on PATH
+// to another binary of the same name, such as nvm's node.
+func shadowedBinaries(prefix string, dirs []string) []checkResult {
+	brewBin := filepath.Join(prefix, "bin")
+	entries, err := os.ReadDir(brewBin)
+	if err != nil {
+		return nil
+	}
+
+	var results []checkResult
+	for _, e := range entries {
+		name := e.Name()
+		if name == "brew" {
+			continue
+		}
+		found := whichAll(name, dirs)
+		if len(found) == 0 || inPrefix(found[0], prefix) {
+			continue
+		}
+		results = append(results, checkResult{
+			ID:      "path.shadowed." + name,
+			Name:    name,
+			Status:  "warn",
+			Message: fmt.Sprintf("%s is used instead of %s", found[0], filepath.Join(brewBin, name)),
+		})
+	}
+	return results
+}
+
+func pathListResult(id, name, problem string, dirs []string) checkResult {
+	if len(dirs) == 0 {
+		return checkResult{ID: id, Name: name, Status: "ok"}
+	}
+	sort.Strings(dirs)
+	return checkResult{
+		ID:      id,
+		Name:    name,
+		Status:  "warn",
+		Message: fmt.Sprintf("%s: %s", problem, strings.Join(dirs, ", ")),
+	}
+}
+
+func containsPrefix(prefixes []string, prefix string) bool {
+	for _, p := range prefixes {
+		if p == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+func dirIndex(dirs []string, dir string) int {
+	for i, d := range dirs {
+		if filepath.Clean(d) == dir {
+			return i
+		}
+	}
+	return -1
+}
+
+// openbootInstalls returns every openboot binary on the login shell PATH
+// plus the curl install, which may not be on PATH, labelled by how it was
+// installed.
+func openbootInstalls(curlInstallPath string) (installs []string, labels map[string]string) {
+	labels = make(map[string]string)
+	seen := make(map[string]bool)
+	add := func(path string) {
+		resolved := path
+		if r, err := filepath.EvalSymlinks(path); err == nil {
+			resolved = r
+		}
+		if seen[resolved] {
+			return
+		}
+		seen[resolved] = true
+		installs = append(installs, path)
+		switch {
+		case path == curlInstallPath:
+			labels[path] = "curl install"
+		case strings.Contains(resolved, "/Cellar/openboot/"):
+			labels[path] = "Homebrew"
+		}
+	}
+
+	for _, path := range whichAll("openboot", getShellPath().dirs) {
+		add(path)
+	}
+	if _, err := os.Stat(curlInstallPath); err == nil {
+		add(curlInstallPath)
+	}
+	return installs, labels
+}
+
+func checkInstallationConflicts() []checkResult {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	curlInstallPath := filepath.Join(home, ".openboot", "bin", "openboot")
+	installs, labels := openbootInstalls(curlInstallPath)
+
+	if len(installs) == 0 {
+		return []checkResult{{
+			ID:      "install.location",
+			Name:    "Installation",
+			Status:  "error",
+			Message: "OpenBoot not found in standard locations",
+		}}
+	}
+
+	if len(installs) > 1 {
+		described := make([]string, len(installs))
+		hasCurlInstall := false
+		for i, path := range installs {
+			described[i] = path
+			if label := labels[path]; label != "" {
+				described[i] += " (" + label + ")"
+			}
+			hasCurlInstall = hasCurlInstall || path == curlInstallPath
+		}
+		multiple := checkResult{
+			ID:      "install.multiple",
+			Name:    "Multiple installations",
+			Status:  "warn",
+			Message: fmt.Sprintf("found at: %s; %s is used", strings.Join(described, ", "), installs[0]),
+		}
+		if hasCurlInstall {
+			// Homebrew keeps its copy up to date, so it's the one to keep.
+			multiple = multiple.withFix("Remove the curl install at "+curlInstallPath, func(bool) error {
+				return os.Remove(curlInstallPath)
+			})
+		}
+		return []checkResult{multiple, {
+			ID:      "install.recommendation",
+			Name:    "Recommendation",
+			Status:  "info",
+			Message: "keep only one installation method to avoid conflicts",
+		}}
+	}
+
+	if whichAll("openboot", getShellPath().dirs) == nil {
+		return []checkResult{{
+			ID:      "install.location",
+			Name:    "Installation",
+			Status:  "warn",
+			Message: fmt.Sprintf("%s is not on your shell's PATH", filepath.Dir(installs[0])),
+		}}
+	}
+	return []checkResult{{
+		ID:     "install.location",
+		Name:   fmt.Sprintf("Single installation: %s", installs[0]),
+		Status: "ok",
+	}}
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeExecutable(t *testing.T, path string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"), 0755))
+}
+
+// stubShellPath makes the login shell report dirs as its PATH.
+func stubShellPath(t *testing.T, dirs ...string) {
+	t.Helper()
+	orig := loginShellPATH
+	loginShellPATH = func() (string, error) {
+		return strings.Join(dirs, string(os.PathListSeparator)), nil
+	}
+	shellPath = nil
+	t.Cleanup(func() {
+		loginShellPATH = orig
+		shellPath = nil
+	})
+}
+
+// stubBrewPrefixes points the Homebrew prefixes at temp dirs.
+func stubBrewPrefixes(t *testing.T) (native, intel string) {
+	t.Helper()
+	native, intel = t.TempDir(), t.TempDir()
+	origNative, origLinux, origIntel := nativeBrewPrefix, linuxBrewPrefix, intelBrewPrefix
+	nativeBrewPrefix, linuxBrewPrefix, intelBrewPrefix = native, filepath.Join(t.TempDir(), "none"), intel
+	t.Cleanup(func() {
+		nativeBrewPrefix, linuxBrewPrefix, intelBrewPrefix = origNative, origLinux, origIntel
+	})
+	return native, intel
+}
+
+func resultsByID(results []checkResult) map[string]checkResult {
+	byID := make(map[string]checkResult, len(results))
+	for _, r := range results {
+		byID[r.ID] = r
+	}
+	return byID
+}
+
+func TestCheckPath(t *testing.T) {
+	native, _ := stubBrewPrefixes(t)
+	writeExecutable(t, filepath.Join(native, "bin", "brew"))
+	writeExecutable(t, filepath.Join(native, "bin", "node"))
+	writeExecutable(t, filepath.Join(native, "bin", "jq"))
+	nvm := t.TempDir()
+	writeExecutable(t, filepath.Join(nvm, "node"))
+	missing := filepath.Join(t.TempDir(), "gone")
+	brewBin := filepath.Join(native, "bin")
+	stubShellPath(t, nvm, brewBin, missing, brewBin+"/")
+
+	byID := resultsByID(checkPath())
+
+	assert.Equal(t, "listed more than once: "+brewBin+"/", byID["path.duplicates"].Message)
+	assert.Equal(t, "missing: "+missing, byID["path.missing"].Message)
+	require.Contains(t, byID, "path.shadowed.node")
+	assert.Equal(t, filepath.Join(nvm, "node")+" is used instead of "+filepath.Join(brewBin, "node"), byID["path.shadowed.node"].Message)
+	assert.NotContains(t, byID, "path.shadowed.jq")
+	assert.NotContains(t, byID, "path.rosetta")
+	assert.NotContains(t, byID, "path.shell")
+}
+
+func TestCheckPath_Clean(t *testing.T) {
+	native, _ := stubBrewPrefixes(t)
+	writeExecutable(t, filepath.Join(native, "bin", "brew"))
+	writeExecutable(t, filepath.Join(native, "bin", "node"))
+	stubShellPath(t, filepath.Join(native, "bin"), "/usr/bin")
+
+	for _, r := range checkPath() {
+		assert.Equal(t, "ok", r.Status, r.ID)
+	}
+}
+
+func TestCheckPath_RosettaHomebrew(t *testing.T) {
+	native, intel := stubBrewPrefixes(t)
+	writeExecutable(t, filepath.Join(native, "bin", "brew"))
+	writeExecutable(t, filepath.Join(intel, "bin", "brew"))
+	writeExecutable(t, filepath.Join(native, "bin", "python3"))
+	writeExecutable(t, filepath.Join(intel, "bin", "python3"))
+	stubShellPath(t, filepath.Join(intel, "bin"), filepath.Join(native, "bin"))
+
+	byID := resultsByID(checkPath())
+
+	require.Contains(t, byID, "path.rosetta")
+	assert.Contains(t, byID["path.rosetta"].Message, "the Intel one comes first on PATH")
+	assert.Contains(t, byID, "path.shadowed.python3")
+}
+
+func TestCheckPath_FallsBackToCurrentPATH(t *testing.T) {
+	stubBrewPrefixes(t)
+	orig := loginShellPATH
+	loginShellPATH = func() (string, error) { return "", errors.New("no shell") }
+	shellPath = nil
+	t.Cleanup(func() {
+		loginShellPATH = orig
+		shellPath = nil
+	})
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+
+	byID := resultsByID(checkPath())
+	assert.Equal(t, "info", byID["path.shell"].Status)
+	assert.Equal(t, []string{dir}, shellPath.dirs)
+}
+
+func TestLoginShellPATH_IgnoresShellNoise(t *testing.T) {
+	dir := t.TempDir()
+	shell := filepath.Join(dir, "fakesh")
+	// Called as: fakesh -l -i -c <command>
+	require.NoError(t, os.WriteFile(shell, []byte("#!/bin/sh\necho 'Welcome!'\nPATH=/a:/b\nexport PATH\nexec /bin/sh -c \"$4\"\n"), 0755))
+	t.Setenv("SHELL", shell)
+
+	value, err := loginShellPATH()
+	require.NoError(t, err)
+	assert.Equal(t, "/a:/b", value)
+}
+
+func TestCheckInstallationConflicts(t *testing.T) {
+	stubBrewPrefixes(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	curl := filepath.Join(home, ".openboot", "bin", "openboot")
+	writeExecutable(t, curl)
+
+	cellar := t.TempDir()
+	writeExecutable(t, filepath.Join(cellar, "Cellar", "openboot", "1.0.0", "bin", "openboot"))
+	brewBin := filepath.Join(cellar, "bin")
+	require.NoError(t, os.MkdirAll(brewBin, 0755))
+	require.NoError(t, os.Symlink(filepath.Join(cellar, "Cellar", "openboot", "1.0.0", "bin", "openboot"), filepath.Join(brewBin, "openboot")))
+
+	stubShellPath(t, brewBin)
+	byID := resultsByID(checkInstallationConflicts())
+	multiple := byID["install.multiple"]
+	assert.Equal(t, "warn", multiple.Status)
+	assert.Contains(t, multiple.Message, filepath.Join(brewBin, "openboot")+" (Homebrew)")
+	assert.Contains(t, multiple.Message, curl+" (curl install)")
+	require.True(t, multiple.Fixable)
+	require.NoError(t, multiple.fix.run(true))
+	assert.NoFileExists(t, curl)
+
+	stubShellPath(t)
+	writeExecutable(t, curl)
+	byID = resultsByID(checkInstallationConflicts())
+	assert.Equal(t, "warn", byID["install.location"].Status)
+	assert.Contains(t, byID["install.location"].Message, "is not on your shell's PATH")
+}
@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openbootdotdev/openboot/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeDoctorChecks(t *testing.T) *[]string {
+	t.Helper()
+	var ran []string
+	orig := doctorChecks
+	doctorChecks = []doctorCheck{
+		{id: "network", run: func() []checkResult {
+			ran = append(ran, "network")
+			return []checkResult{{ID: "network", Name: "Network", Status: "ok"}}
+		}},
+		{id: "git", run: func() []checkResult {
+			ran = append(ran, "git")
+			return []checkResult{
+				{ID: "git.installed", Name: "Git installed", Status: "ok"},
+				{ID: "git.identity", Name: "Git identity", Status: "warn", Message: "not configured"},
+			}
+		}},
+		{id: "tools", run: func() []checkResult {
+			ran = append(ran, "tools")
+			return []checkResult{{ID: "tools.jq", Name: "jq", Status: "info", Message: "not installed"}}
+		}},
+	}
+	t.Cleanup(func() { doctorChecks = orig })
+	return &ran
+}
+
+func TestNewCheckFilter_RejectsUnknownIDs(t *testing.T) {
+	fakeDoctorChecks(t)
+
+	_, err := newCheckFilter([]string{"git.identity", "tools"}, []string{"network"})
+	require.NoError(t, err)
+
+	_, err = newCheckFilter([]string{"fonts"}, nil)
+	assert.ErrorContains(t, err, `unknown check "fonts"`)
+	_, err = newCheckFilter(nil, []string{"fonts.x"})
+	assert.ErrorContains(t, err, "network, git, tools")
+}
+
+func TestRunChecks_Filters(t *testing.T) {
+	ran := fakeDoctorChecks(t)
+
+	filter, err := newCheckFilter([]string{"git.identity", "tools"}, nil)
+	require.NoError(t, err)
+	results := runChecks(filter)
+	assert.Equal(t, []string{"git", "tools"}, *ran)
+	require.Len(t, results, 2)
+	assert.Equal(t, "git.identity", results[0].ID)
+	assert.Equal(t, "tools.jq", results[1].ID)
+
+	*ran = nil
+	filter, err = newCheckFilter(nil, []string{"network", "git.installed"})
+	require.NoError(t, err)
+	results = runChecks(filter)
+	assert.Equal(t, []string{"git", "tools"}, *ran)
+	require.Len(t, results, 2)
+	assert.Equal(t, "git.identity", results[0].ID)
+}
+
+func TestChecksFailed(t *testing.T) {
+	results := []checkResult{
+		{ID: "a", Status: "ok"},
+		{ID: "b", Status: "info"},
+		{ID: "c", Status: "warn"},
+	}
+
+	assert.False(t, checksFailed(results, "error"))
+	assert.True(t, checksFailed(results, "warn"))
+	assert.True(t, checksFailed(results, "info"))
+	assert.False(t, checksFailed(results, "never"))
+	assert.True(t, checksFailed(append(results, checkResult{Status: "error"}), "error"))
+}
+
+func TestRunDoctor_JSON(t *testing.T) {
+	fakeDoctorChecks(t)
+	require.NoError(t, doctorCmd.Flags().Set("json", "true"))
+	require.NoError(t, doctorCmd.Flags().Set("check", "git"))
+	require.NoError(t, doctorCmd.Flags().Set("fail-on", "warn"))
+	t.Cleanup(func() {
+		doctorCmd.Flags().Set("json", "false")
+		doctorCmd.Flags().Set("fail-on", "error")
+		doctorCmd.Flags().Lookup("check").Value.(interface{ Replace([]string) error }).Replace(nil)
+		doctorCmd.Flags().Lookup("check").Changed = false
+	})
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	runErr := runDoctor(doctorCmd)
+	os.Stdout = stdout
+	w.Close()
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	var exitErr *ExitError
+	require.True(t, errors.As(runErr, &exitErr))
+	assert.Equal(t, exitDoctorFailed, exitErr.Code)
+
+	var results []checkResult
+	require.NoError(t, json.Unmarshal(out, &results))
+	require.Len(t, results, 2)
+	assert.Equal(t, "git.installed", results[0].ID)
+	assert.Equal(t, "warn", results[1].Status)
+	assert.Equal(t, "not configured", results[1].Message)
+}
+
+func TestRunDoctor_RejectsInvalidFailOn(t *testing.T) {
+	fakeDoctorChecks(t)
+	require.NoError(t, doctorCmd.Flags().Set("fail-on", "ok"))
+	t.Cleanup(func() { doctorCmd.Flags().Set("fail-on", "error") })
+
+	assert.ErrorContains(t, runDoctor(doctorCmd), "invalid --fail-on")
+}
+
+func TestFixChecks_AppliesFixesAndRechecks(t *testing.T) {
+	identitySet := false
+	var fixedUnattended bool
+	orig := doctorChecks
+	doctorChecks = []doctorCheck{
+		{id: "git", run: func() []checkResult {
+			if identitySet {
+				return []checkResult{{ID: "git.identity", Name: "Git identity", Status: "ok"}}
+			}
+			return []checkResult{checkResult{ID: "git.identity", Name: "Git identity", Status: "warn"}.
+				withFix("Set git identity", func(unattended bool) error {
+					fixedUnattended = unattended
+					identitySet = true
+					return nil
+				})}
+		}},
+		{id: "tools", run: func() []checkResult {
+			return []checkResult{
+				checkResult{ID: "tools.jq", Name: "jq", Status: "info"}.
+					withFix("Install jq", func(bool) error { return errors.New("no network") }),
+				{ID: "tools.gh", Name: "gh", Status: "info"},
+			}
+		}},
+	}
+	t.Cleanup(func() { doctorChecks = orig })
+
+	before := runChecks(checkFilter{})
+	require.Len(t, before, 3)
+	assert.True(t, before[0].Fixable)
+	assert.False(t, before[2].Fixable)
+
+	after, err := fixChecks(before, checkFilter{}, true)
+	require.NoError(t, err)
+	assert.True(t, fixedUnattended)
+	require.Len(t, after, 3)
+	assert.Equal(t, "ok", after[0].Status)
+	assert.Equal(t, "info", after[1].Status)
+}
+
+func TestFixChecks_NothingFixable(t *testing.T) {
+	results := []checkResult{
+		{ID: "network", Status: "error"},
+		checkResult{ID: "git.identity", Status: "ok"}.withFix("unused", func(bool) error {
+			t.Fatal("fix ran for a passing check")
+			return nil
+		}),
+	}
+
+	after, err := fixChecks(results, checkFilter{}, true)
+	require.NoError(t, err)
+	assert.Equal(t, results, after)
+}
+
+func TestCheckResult_JSONHidesFix(t *testing.T) {
+	r := checkResult{ID: "tools.jq", Name: "jq", Status: "info"}.withFix("Install jq", func(bool) error { return nil })
+
+	data, err := json.Marshal(r)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"tools.jq","name":"jq","status":"info","fixable":true}`, string(data))
+}
+
+func TestCheckRequirements(t *testing.T) {
+	bin := t.TempDir()
+	home := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "node"), []byte("#!/bin/sh\necho v18.19.0\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "jq"), []byte("#!/bin/sh\necho jq-1.7.1\n"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".ssh"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".ssh", "id_ed25519"), nil, 0600))
+	t.Setenv("PATH", bin)
+	t.Setenv("HOME", home)
+	t.Setenv("TEAM_TOKEN", "")
+
+	doctorRequirements = &config.Requirements{
+		Commands: []config.CommandRequirement{
+			{Name: "node", Version: ">=20"},
+			{Name: "jq", Version: "^1.7"},
+			{Name: "gh"},
+		},
+		Env:   []string{"TEAM_TOKEN"},
+		Files: []string{"~/.ssh/id_ed25519", "~/.aws/config"},
+	}
+	t.Cleanup(func() { doctorRequirements = nil })
+
+	status := make(map[string]checkResult)
+	for _, r := range checkRequirements() {
+		status[r.ID] = r
+	}
+
+	assert.Equal(t, "error", status["requirements.command.node"].Status)
+	assert.Equal(t, "18.19.0 installed, requirements need >=20", status["requirements.command.node"].Message)
+	assert.Equal(t, "ok", status["requirements.command.jq"].Status)
+	assert.Equal(t, "jq 1.7.1 (^1.7)", status["requirements.command.jq"].Name)
+	assert.Equal(t, "not installed", status["requirements.command.gh"].Message)
+	assert.Equal(t, "not set", status["requirements.env.TEAM_TOKEN"].Message)
+	assert.Equal(t, "ok", status["requirements.file.~/.ssh/id_ed25519"].Status)
+	assert.Equal(t, "not found", status["requirements.file.~/.aws/config"].Message)
+}
+
+func TestCheckRequirements_NoneLoaded(t *testing.T) {
+	doctorRequirements = nil
+	assert.Empty(t, checkRequirements())
+}
+
+func TestLoadRequirements_RejectsBothSources(t *testing.T) {
+	_, err := loadRequirements("openboot.yaml", "alice")
+	assert.ErrorContains(t, err, "either --requirements or --user")
+
+	req, err := loadRequirements("", "")
+	require.NoError(t, err)
+	assert.Nil(t, req)
+}
@@ -0,0 +1,361 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/openbootdotdev/openboot/internal/dotfiles"
+	"github.com/openbootdotdev/openboot/internal/system"
+	"github.com/openbootdotdev/openboot/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var dotfilesCmd = &cobra.Command{
+	Use:   "dotfiles",
+	Short: "Manage your dotfiles repository",
+	Long: `Manage the dotfiles repository cloned to ~/.dotfiles, or to the path
+given with --dotfiles-path when it was first set up. When a subdirectory
+was given with --dotfiles-subdir, only that directory is linked.
+
+Files ending in .tmpl are rendered with Go templates instead of being
+symlinked. Templates can use {{ .Hostname }}, {{ .Arch }},
+{{ .HomebrewPrefix }}, {{ .Home }}, {{ .User }}, {{ .GitName }},
+{{ .GitEmail }}, {{ env "NAME" }} and {{ prompt "key" "Question" }}.
+Prompted values are stored in ~/.openboot/dotfiles-vars.json.
+
+Files ending in .age are encrypted secrets. They are decrypted to their
+target with 0600 permissions using the key in ~/.openboot/dotfiles.key,
+or a passphrase from OPENBOOT_DOTFILES_PASSPHRASE or a prompt.`,
+	Example: `  # Check link health and sync state
+  openboot dotfiles status
+
+  # Pull the latest dotfiles and link new files
+  openboot dotfiles pull
+
+  # Move ~/.tmux.conf into the repo and link it back
+  openboot dotfiles add ~/.tmux.conf
+
+  # Remove all links and restore backed-up files
+  openboot dotfiles unlink
+
+  # Show what re-rendering templates would change
+  openboot dotfiles diff
+
+  # Create a local key for encrypted secrets
+  openboot dotfiles keygen
+
+  # Encrypt ~/.npmrc into the repo and commit it
+  openboot dotfiles add --encrypt ~/.npmrc`,
+}
+
+var dotfilesDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show what re-rendering templates would change",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDotfilesDiff()
+	},
+}
+
+var dotfilesAddCmd = &cobra.Command{
+	Use:   "add <path>",
+	Short: "Add a file to the dotfiles repo",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		encrypt, _ := cmd.Flags().GetBool("encrypt")
+		pkg, _ := cmd.Flags().GetString("package")
+		if encrypt {
+			return runDotfilesAddEncrypted(args[0], pkg)
+		}
+		dst, err := dotfiles.Add(args[0], pkg)
+		if err != nil {
+			return err
+		}
+		ui.Success(fmt.Sprintf("Added %s -> %s", args[0], dst))
+		return nil
+	},
+}
+
+var dotfilesStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show link health, local changes and sync state",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fetch, _ := cmd.Flags().GetBool("fetch")
+		return runDotfilesStatus(fetch)
+	},
+}
+
+var dotfilesPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Pull the dotfiles repo and relink",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if err := dotfiles.Pull(dryRun); err != nil {
+			return err
+		}
+		if !dryRun {
+			ui.Success("Dotfiles up to date")
+		}
+		return nil
+	},
+}
+
+var dotfilesUnlinkCmd = &cobra.Command{
+	Use:   "unlink",
+	Short: "Remove dotfile links and restore backups",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if err := dotfiles.Unlink(dryRun); err != nil {
+			return err
+		}
+		if !dryRun {
+			ui.Success("Dotfiles unlinked")
+		}
+		return nil
+	},
+}
+
+var dotfilesKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Create a local key for encrypted dotfiles",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recipient, err := dotfiles.GenerateSecretKey()
+		if err != nil {
+			return err
+		}
+		path, _ := dotfiles.SecretKeyPath()
+		ui.Success(fmt.Sprintf("Key written to %s", path))
+		ui.Info(fmt.Sprintf("Public key: %s", recipient))
+		ui.Muted("Copy this file to your other machines to decrypt your secrets there.")
+		return nil
+	},
+}
+
+func init() {
+	dotfiles.InputPrompter = func(key, question string) (string, error) {
+		return ui.Input(question, key)
+	}
+	dotfiles.PasswordPrompter = func(key, question string) (string, error) {
+		return ui.Password(question)
+	}
+
+	dotfilesAddCmd.Flags().Bool("encrypt", false, "encrypt the file with age before committing it")
+	dotfilesAddCmd.Flags().String("package", "", "stow package to add the file to")
+
+	dotfilesStatusCmd.Flags().Bool("fetch", false, "fetch from the remote before comparing")
+	dotfilesPullCmd.Flags().Bool("dry-run", false, "preview changes without pulling or linking")
+	dotfilesUnlinkCmd.Flags().Bool("dry-run", false, "preview changes without removing anything")
+
+	dotfilesCmd.AddCommand(dotfilesStatusCmd)
+	dotfilesCmd.AddCommand(dotfilesPullCmd)
+	dotfilesCmd.AddCommand(dotfilesAddCmd)
+	dotfilesCmd.AddCommand(dotfilesUnlinkCmd)
+	dotfilesCmd.AddCommand(dotfilesDiffCmd)
+	dotfilesCmd.AddCommand(dotfilesKeygenCmd)
+}
+
+func runDotfilesStatus(fetch bool) error {
+	repo, err := dotfiles.RepoPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(repo); os.IsNotExist(err) {
+		return fmt.Errorf("dotfiles directory not found: %s", repo)
+	}
+	home, err := system.HomeDir()
+	if err != nil {
+		return err
+	}
+
+	status, err := dotfiles.GetStatus(repo, home, fetch)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	ui.Header("Dotfiles Status")
+	fmt.Println()
+
+	plan := status.Plan
+	fmt.Printf("  %s %d linked\n", ui.Green("✓"), len(plan.Linked))
+	for _, l := range plan.Pending {
+		fmt.Printf("  %s not linked: %s\n", ui.Yellow("!"), l.Target)
+	}
+	for _, c := range plan.Conflicts {
+		fmt.Printf("  %s %s: %s\n", ui.Red("✗"), c.Kind, c.Path)
+	}
+	for _, l := range status.Broken {
+		fmt.Printf("  %s broken link: %s -> %s\n", ui.Red("✗"), l.Target, l.Source)
+	}
+	for _, l := range status.Outdated {
+		fmt.Printf("  %s template out of date: %s\n", ui.Yellow("!"), l.Target)
+	}
+	if status.RenderError != nil {
+		fmt.Printf("  %s %v\n", ui.Red("✗"), status.RenderError)
+	}
+
+	if len(status.Modified) > 0 {
+		fmt.Printf("  %s %d local changes in %s:\n", ui.Yellow("!"), len(status.Modified), repo)
+		for _, line := range status.Modified {
+			fmt.Printf("      %s\n", line)
+		}
+	}
+
+	switch {
+	case status.Upstream == "":
+		fmt.Printf("  %s no upstream branch\n", ui.Cyan("i"))
+	case status.Ahead == 0 && status.Behind == 0:
+		fmt.Printf("  %s up to date with %s\n", ui.Green("✓"), status.Upstream)
+	default:
+		fmt.Printf("  %s %d ahead, %d behind %s\n", ui.Yellow("!"), status.Ahead, status.Behind, status.Upstream)
+	}
+
+	fmt.Println()
+	if status.Healthy() {
+		ui.Success("Dotfiles are healthy")
+	} else if len(plan.Pending) > 0 || len(plan.Conflicts) > 0 || len(status.Broken) > 0 || len(status.Outdated) > 0 {
+		ui.Muted("Run 'openboot dotfiles pull' to relink.")
+	}
+	fmt.Println()
+	return nil
+}
+
+func runDotfilesAddEncrypted(path, pkg string) error {
+	repo, err := dotfiles.RepoPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(repo); os.IsNotExist(err) {
+		return fmt.Errorf("dotfiles directory not found: %s", repo)
+	}
+	home, err := system.HomeDir()
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+
+	dst, err := dotfiles.RepoFileFor(repo, home, path, pkg)
+	if err != nil {
+		return err
+	}
+	dst += dotfiles.EncryptedSuffix
+
+	keys, err := dotfiles.LoadSecretKeys(dotfiles.PasswordPrompter)
+	if err != nil {
+		return err
+	}
+
+	if err := dotfiles.EncryptInto(path, dst, keys); err != nil {
+		return err
+	}
+	rel, _ := filepath.Rel(repo, dst)
+	if err := dotfiles.Commit(repo, "Add encrypted "+rel, rel); err != nil {
+		return err
+	}
+
+	ui.Success(fmt.Sprintf("Encrypted %s -> %s", path, dst))
+	return nil
+}
+
+const diffContextLines = 2
+
+func runDotfilesDiff() error {
+	repo, err := dotfiles.RepoPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(repo); os.IsNotExist(err) {
+		return fmt.Errorf("dotfiles directory not found: %s", repo)
+	}
+
+	home, err := system.HomeDir()
+	if err != nil {
+		return err
+	}
+
+	plan, err := dotfiles.PlanLinks(repo, home)
+	if err != nil {
+		return err
+	}
+	if len(plan.Templates) == 0 {
+		ui.Muted("No templates in " + repo)
+		return nil
+	}
+
+	data, err := dotfiles.LoadTemplateData(nil)
+	if err != nil {
+		return err
+	}
+	renders, err := plan.RenderTemplates(data)
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	for _, r := range renders {
+		if !r.Changed() {
+			continue
+		}
+		changed++
+		printTemplateDiff(r)
+	}
+
+	if changed == 0 {
+		ui.Success("All rendered dotfiles are up to date")
+	}
+	return nil
+}
+
+func printTemplateDiff(r dotfiles.TemplateRender) {
+	fmt.Println(ui.Red("--- " + r.Link.Target))
+	fmt.Println(ui.Green("+++ " + r.Link.Source))
+	if !r.Exists {
+		fmt.Println(ui.Cyan("(new file)"))
+	}
+
+	lines := dotfiles.LineDiff(string(r.Current), string(r.Rendered))
+	show := make([]bool, len(lines))
+	for i, l := range lines {
+		if l.Op == dotfiles.DiffEqual {
+			continue
+		}
+		for j := i - diffContextLines; j <= i+diffContextLines; j++ {
+			if j >= 0 && j < len(lines) {
+				show[j] = true
+			}
+		}
+	}
+
+	skipped := false
+	for i, l := range lines {
+		if !show[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			fmt.Println(ui.Cyan("@@"))
+			skipped = false
+		}
+		switch l.Op {
+		case dotfiles.DiffDelete:
+			fmt.Println(ui.Red("-" + l.Text))
+		case dotfiles.DiffInsert:
+			fmt.Println(ui.Green("+" + l.Text))
+		default:
+			fmt.Println(" " + l.Text)
+		}
+	}
+	fmt.Println()
+}
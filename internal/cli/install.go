@@ -70,6 +70,10 @@ func init() {
 	installCmd.Flags().StringVar(&cfg.Shell, "shell", "", "shell setup: install, skip")
 	installCmd.Flags().StringVar(&cfg.Macos, "macos", "", "macOS preferences: configure, skip")
 	installCmd.Flags().StringVar(&cfg.Dotfiles, "dotfiles", "", "dotfiles: clone, link, skip")
+	installCmd.Flags().StringVar(&cfg.DotfilesRepo, "dotfiles-repo", "", "dotfiles repository URL")
+	installCmd.Flags().StringVar(&cfg.DotfilesRef, "dotfiles-ref", "", "dotfiles branch, tag or commit to check out")
+	installCmd.Flags().StringVar(&cfg.DotfilesSubdir, "dotfiles-subdir", "", "directory inside the dotfiles repo to link from")
+	installCmd.Flags().StringVar(&cfg.DotfilesPath, "dotfiles-path", "", "where to clone the dotfiles repo (default ~/.dotfiles)")
 
 	installCmd.Flags().BoolVar(&cfg.Update, "update", false, "update Homebrew before installing")
 	installCmd.Flags().BoolVar(&cfg.Rollback, "rollback", false, "restore backed-up config files")
@@ -93,6 +93,10 @@ func init() {
 	rootCmd.Flags().StringVar(&cfg.Shell, "shell", "", "shell setup: install, skip")
 	rootCmd.Flags().StringVar(&cfg.Macos, "macos", "", "macOS preferences: configure, skip")
 	rootCmd.Flags().StringVar(&cfg.Dotfiles, "dotfiles", "", "dotfiles: clone, link, skip")
+	rootCmd.Flags().StringVar(&cfg.DotfilesRepo, "dotfiles-repo", "", "dotfiles repository URL")
+	rootCmd.Flags().StringVar(&cfg.DotfilesRef, "dotfiles-ref", "", "dotfiles branch, tag or commit to check out")
+	rootCmd.Flags().StringVar(&cfg.DotfilesSubdir, "dotfiles-subdir", "", "directory inside the dotfiles repo to link from")
+	rootCmd.Flags().StringVar(&cfg.DotfilesPath, "dotfiles-path", "", "where to clone the dotfiles repo (default ~/.dotfiles)")
 
 	rootCmd.Flags().BoolVar(&cfg.Update, "update", false, "update Homebrew before installing")
 	rootCmd.Flags().BoolVar(&cfg.Rollback, "rollback", false, "restore backed-up config files")
@@ -102,7 +106,9 @@ func init() {
 	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(doctorCmd)
 	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(cleanCmd)
+	rootCmd.AddCommand(dotfilesCmd)
 	rootCmd.AddCommand(loginCmd)
 	rootCmd.AddCommand(logoutCmd)
 
@@ -146,6 +152,16 @@ Learn more:
   GitHub:        https://github.com/openbootdotdev/openboot
 `
 
+// ExitError ends the process with Code. Commands return it after they have
+// already reported the outcome, so nothing else is printed.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
 func Execute() error {
 	return rootCmd.Execute()
 }
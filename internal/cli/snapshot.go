@@ -8,7 +8,6 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -17,6 +16,7 @@ import (
 	"github.com/openbootdotdev/openboot/internal/config"
 	"github.com/openbootdotdev/openboot/internal/installer"
 	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/openbootdotdev/openboot/internal/system"
 	"github.com/openbootdotdev/openboot/internal/ui"
 	"github.com/spf13/cobra"
 )
@@ -29,22 +29,72 @@ shell config, and dev tools into a portable JSON snapshot.
 
 Export:
   openboot snapshot                            Capture interactively (save or upload)
-  openboot snapshot --local                    Save to ~/.openboot/snapshot.json
+  openboot snapshot --local                    Save to ~/.openboot/snapshots/
   openboot snapshot --json > my-setup.json     Export as JSON
+  openboot snapshot --json --exclude 'acme/*'  Export without packages from matching taps
+  openboot snapshot --export sh > setup.sh     Export as a standalone bash script
+  openboot snapshot --export ansible --from my-setup.json > playbook.yml
+                                               Convert a snapshot file (also: nix-darwin)
+
+Uploads, --json and --export output are redacted using ~/.openboot/redact.json, e.g.
+  {"drop_hostname": true, "drop_git_name": true, "drop_dotfiles_repo": false,
+   "email": "remove", "exclude": ["acme-corp/*", "*-internal"]}
+and the redaction flags. Uploads show exactly what will be sent before sending.
 
 Import:
   openboot snapshot --import my-setup.json     Restore from a local file
-  openboot snapshot --import https://...       Restore from a URL`,
+  openboot snapshot --import https://...       Restore from a URL (must be signed by a trusted key)
+  openboot snapshot --import URL --sha256 HASH Restore from a URL pinned to a checksum
+  openboot snapshot --import file --yes        Restore unattended, without the editor or prompts
+  openboot snapshot --import file --skip macos Restore everything except macOS preferences
+
+--only and --skip take formulae, casks, npm, taps (or packages for all four),
+git, shell, dotfiles and macos. A restore where some steps failed exits with 3.
+
+History:
+  openboot snapshot list                       List saved snapshots
+  openboot snapshot show <id>                  Show a saved snapshot
+  openboot snapshot restore <id>               Restore a saved snapshot ("latest" for the newest)
+  openboot snapshot prune                      Delete snapshots outside the retention policy
+
+Signing:
+  openboot snapshot sign my-setup.json         Sign a snapshot, writing my-setup.json.sig
+  openboot snapshot verify https://...         Check a snapshot's signature
+  openboot snapshot trust <public-key> [name]  Accept snapshots signed by this key
+
+Maintenance:
+  openboot snapshot migrate my-setup.json      Upgrade a file to the current schema version
+
+Compare:
+  openboot snapshot diff a.json b.json         Show what differs between two snapshots
+  openboot snapshot diff local live            Compare the saved snapshot with this Mac
+  openboot snapshot merge a.json b.json c.json Merge snapshots into a team baseline`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runSnapshot(cmd)
 	},
 }
 
 func init() {
-	snapshotCmd.Flags().Bool("local", false, "Save snapshot locally only")
-	snapshotCmd.Flags().Bool("json", false, "Output as JSON to stdout")
-	snapshotCmd.Flags().Bool("dry-run", false, "preview without installing or modifying anything")
-	snapshotCmd.Flags().String("import", "", "Restore from a snapshot file or URL")
+	addSnapshotFlags(snapshotCmd)
+}
+
+// addSnapshotFlags registers the capture, export and import flags on cmd.
+func addSnapshotFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("local", false, "Save snapshot locally only")
+	cmd.Flags().Bool("json", false, "Output as JSON to stdout")
+	cmd.Flags().String("export", "", "write a standalone setup to stdout: "+strings.Join(snapshot.ExportFormats(), ", "))
+	cmd.Flags().String("from", "", "with --export, convert this snapshot file, URL or ID instead of capturing")
+	cmd.Flags().Bool("dry-run", false, "preview without installing or modifying anything")
+	cmd.Flags().String("import", "", "Restore from a snapshot file or URL")
+	cmd.Flags().String("sha256", "", "with --import, require the snapshot to have this SHA-256 checksum")
+	cmd.Flags().Bool("insecure", false, "with --import, allow unsigned snapshots from URLs")
+	cmd.Flags().Bool("redact-hostname", false, "leave the hostname out of uploads and --json output")
+	cmd.Flags().String("redact-email", "", "git email in uploads and --json output: keep, hash or remove")
+	cmd.Flags().Bool("redact-git-name", false, "leave the git user name out of uploads and --json output")
+	cmd.Flags().Bool("redact-dotfiles-repo", false, "leave the dotfiles repo out of uploads and --json output")
+	addRestoreFlags(cmd)
+	cmd.Flags().StringArray("exclude", nil, "leave packages and taps matching this glob out of uploads and --json output (repeatable)")
+	cmd.MarkFlagsMutuallyExclusive("json", "export")
 }
 
 // stderr-only styles so stdout stays clean for --json piping
@@ -53,21 +103,35 @@ var (
 	snapSuccessStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#22c55e"))
 	snapMutedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#666666"))
 	snapBoldStyle    = lipgloss.NewStyle().Bold(true)
+	snapWarnStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#eab308")).Bold(true)
 )
 
 func runSnapshot(cmd *cobra.Command) error {
 	importFile, _ := cmd.Flags().GetString("import")
 	if importFile != "" {
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-		return runSnapshotImport(importFile, dryRun)
+		opts, err := restoreFlags(cmd)
+		if err != nil {
+			return err
+		}
+		return runSnapshotImport(cmd, importFile, opts)
 	}
 
 	localFlag, _ := cmd.Flags().GetBool("local")
 	jsonFlag, _ := cmd.Flags().GetBool("json")
 	dryRunFlag, _ := cmd.Flags().GetBool("dry-run")
 
+	rules, err := redactionRules(cmd)
+	if err != nil {
+		return err
+	}
+
 	if jsonFlag {
-		return captureJSONSnapshot()
+		return captureJSONSnapshot(rules)
+	}
+
+	if format, _ := cmd.Flags().GetString("export"); format != "" {
+		from, _ := cmd.Flags().GetString("from")
+		return runSnapshotExport(format, from, rules)
 	}
 
 	snap, err := captureEnvironment()
@@ -126,19 +190,24 @@ func runSnapshot(cmd *cobra.Command) error {
 		return nil
 	}
 
-	return uploadSnapshot(edited)
+	return uploadSnapshot(edited, rules)
 }
 
-func captureJSONSnapshot() error {
+func captureJSONSnapshot(rules snapshot.RedactionRules) error {
 	fmt.Fprintln(os.Stderr, "Capturing environment snapshot...")
 	snap, err := snapshot.Capture()
 	if err != nil {
 		return err
 	}
+	stampVersion(snap)
 	fmt.Fprintln(os.Stderr, "Matching packages with catalog...")
 	catalogMatch := snapshot.MatchPackages(snap)
 	snap.CatalogMatch = *catalogMatch
 	snap.MatchedPreset = snapshot.DetectBestPreset(snap)
+	snap, changes := rules.Apply(snap)
+	if len(changes) > 0 {
+		fmt.Fprintf(os.Stderr, "Redacted %d field(s) per your redaction rules\n", len(changes))
+	}
 	data, err := json.MarshalIndent(snap, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal snapshot: %w", err)
@@ -163,7 +232,7 @@ func captureEnvironment() (*snapshot.Snapshot, error) {
 func captureWithUI() (*snapshot.Snapshot, error) {
 	fmt.Fprintln(os.Stderr)
 
-	progress := ui.NewScanProgress(8)
+	progress := ui.NewScanProgress(10)
 
 	snap, err := snapshot.CaptureWithProgress(func(step snapshot.ScanStep) {
 		progress.Update(step)
@@ -175,9 +244,17 @@ func captureWithUI() (*snapshot.Snapshot, error) {
 		return nil, fmt.Errorf("failed to capture snapshot: %w", err)
 	}
 
+	stampVersion(snap)
 	return snap, nil
 }
 
+// stampVersion records which openboot captured snap.
+func stampVersion(snap *snapshot.Snapshot) {
+	if snap.Machine != nil {
+		snap.Machine.OpenbootVersion = version
+	}
+}
+
 func reviewSnapshot(snap *snapshot.Snapshot) (*snapshot.Snapshot, bool, error) {
 	edited, confirmed, err := ui.RunSnapshotEditor(snap)
 	if err != nil {
@@ -193,7 +270,18 @@ func reviewSnapshot(snap *snapshot.Snapshot) (*snapshot.Snapshot, bool, error) {
 	return edited, true, nil
 }
 
-func uploadSnapshot(snap *snapshot.Snapshot) error {
+func uploadSnapshot(snap *snapshot.Snapshot, rules snapshot.RedactionRules) error {
+	snap, ok, err := reviewOutgoing(snap, rules)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, snapMutedStyle.Render("Upload cancelled."))
+		fmt.Fprintln(os.Stderr)
+		return nil
+	}
+
 	apiBase := auth.GetAPIBase()
 
 	if !auth.IsAuthenticated() {
@@ -417,6 +505,11 @@ func showSnapshotSummary(snap *snapshot.Snapshot) {
 			snapBoldStyle.Render("Git:"))
 	}
 
+	if snap.Dotfiles.RepoURL != "" {
+		fmt.Fprintf(os.Stderr, "  %s %s\n",
+			snapBoldStyle.Render("Dotfiles:"), describeDotfiles(snap.Dotfiles))
+	}
+
 	if len(snap.DevTools) > 0 {
 		var toolNames []string
 		for _, tool := range snap.DevTools {
@@ -484,6 +577,12 @@ func showSnapshotPreview(snap *snapshot.Snapshot) {
 	fmt.Fprintf(os.Stderr, "  %s %s <%s>\n",
 		snapBoldStyle.Render("Git:"), snap.Git.UserName, snap.Git.UserEmail)
 
+	if snap.Dotfiles.RepoURL != "" {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", snapBoldStyle.Render("Dotfiles:"), describeDotfiles(snap.Dotfiles))
+	} else {
+		fmt.Fprintf(os.Stderr, "  %s none\n", snapBoldStyle.Render("Dotfiles:"))
+	}
+
 	fmt.Fprintf(os.Stderr, "  %s %d\n", snapBoldStyle.Render("Dev Tools:"), len(snap.DevTools))
 	for _, tool := range snap.DevTools {
 		fmt.Fprintf(os.Stderr, "    %s %s\n", tool.Name, tool.Version)
@@ -501,68 +600,83 @@ func printSnapshotList(items []string, max int) {
 	}
 }
 
-func runSnapshotImport(importPath string, dryRun bool) error {
-	snap, err := loadSnapshot(importPath)
-	if err != nil {
-		return err
+func runSnapshotImport(cmd *cobra.Command, importPath string, opts restoreOptions) error {
+	if !opts.Yes && !system.HasTTY() {
+		return fmt.Errorf("no terminal to review the snapshot in; pass --yes to restore without prompts")
 	}
 
-	showRestoreInfo(snap, importPath)
-
-	edited, confirmed, err := ui.RunSnapshotEditor(snap)
+	snap, err := loadVerifiedSnapshot(importPath, opts.importOptions)
 	if err != nil {
 		return err
 	}
-	if !confirmed {
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, snapMutedStyle.Render("Restore cancelled."))
-		fmt.Fprintln(os.Stderr)
-		return nil
-	}
+	snap = opts.filterSnapshot(snap)
 
-	ok, err := confirmInstallation(edited, dryRun)
-	if err != nil {
-		return err
-	}
-	if !ok {
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, snapMutedStyle.Render("Installation cancelled."))
-		fmt.Fprintln(os.Stderr)
-		return nil
-	}
-
-	return installer.RunFromSnapshot(buildImportConfig(edited, dryRun))
-}
+	showRestoreInfo(snap, importPath)
+	showCompatibilityWarnings(snap)
+	showUnsafeFields(snap)
 
-func loadSnapshot(importPath string) (*snapshot.Snapshot, error) {
-	localPath := importPath
-	if strings.HasPrefix(importPath, "http://") || strings.HasPrefix(importPath, "https://") {
-		fmt.Fprintf(os.Stderr, "  Downloading snapshot from %s...\n", importPath)
-		client := &http.Client{Timeout: 30 * time.Second}
-		resp, err := client.Get(importPath)
+	edited := snap
+	if opts.Yes {
+		fmt.Fprintln(os.Stderr, snapMutedStyle.Render("  Restoring without review (--yes)"))
+	} else {
+		var confirmed bool
+		edited, confirmed, err = ui.RunSnapshotEditor(snap)
 		if err != nil {
-			return nil, fmt.Errorf("failed to download snapshot: %w", err)
+			return err
 		}
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("failed to download snapshot: HTTP %d", resp.StatusCode)
+		if !confirmed {
+			fmt.Fprintln(os.Stderr)
+			fmt.Fprintln(os.Stderr, snapMutedStyle.Render("Restore cancelled."))
+			fmt.Fprintln(os.Stderr)
+			return nil
 		}
-		tmpFile := filepath.Join(os.TempDir(), "openboot-snapshot-import.json")
-		data, err := io.ReadAll(resp.Body)
+
+		ok, err := confirmInstallation(edited, opts.DryRun)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read snapshot response: %w", err)
+			return err
 		}
-		if err := os.WriteFile(tmpFile, data, 0644); err != nil {
-			return nil, fmt.Errorf("failed to save snapshot: %w", err)
+		if !ok {
+			fmt.Fprintln(os.Stderr)
+			fmt.Fprintln(os.Stderr, snapMutedStyle.Render("Installation cancelled."))
+			fmt.Fprintln(os.Stderr)
+			return nil
 		}
-		defer os.Remove(tmpFile)
-		localPath = tmpFile
 	}
 
-	snap, err := snapshot.LoadFile(localPath)
+	cfg := buildImportConfig(edited, opts.DryRun)
+	opts.applyToConfig(cfg)
+	return restoreExitError(cmd, installer.RunFromSnapshot(cfg))
+}
+
+// loadSnapshot reads a snapshot for viewing or comparing. Use
+// loadVerifiedSnapshot when the snapshot is going to be installed.
+func loadSnapshot(importPath string) (*snapshot.Snapshot, error) {
+	data, err := readSnapshotSource(importPath)
 	if err != nil {
 		return nil, err
 	}
+	return decodeSnapshot(importPath, data)
+}
+
+// loadVerifiedSnapshot is loadSnapshot plus the integrity checks required
+// before installing: a pinned checksum, or a trusted signature for remote
+// sources.
+func loadVerifiedSnapshot(importPath string, opts importOptions) (*snapshot.Snapshot, error) {
+	data, err := readSnapshotSource(importPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySnapshotSource(importPath, data, opts); err != nil {
+		return nil, err
+	}
+	return decodeSnapshot(importPath, data)
+}
+
+func decodeSnapshot(source string, data []byte) (*snapshot.Snapshot, error) {
+	snap, err := snapshot.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", source, err)
+	}
 
 	catalogMatch := snapshot.MatchPackages(snap)
 	snap.CatalogMatch = *catalogMatch
@@ -570,10 +684,25 @@ func loadSnapshot(importPath string) (*snapshot.Snapshot, error) {
 	return snap, nil
 }
 
+// resolveSnapshotPath maps a history ID (or "latest") to its file. Anything
+// that exists on disk or isn't a known ID is returned unchanged.
+func resolveSnapshotPath(path string) string {
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	if entry, err := snapshot.ResolveRef(path); err == nil {
+		return entry.Path
+	}
+	return path
+}
+
 func showRestoreInfo(snap *snapshot.Snapshot, source string) {
 	fmt.Fprintln(os.Stderr)
 	fmt.Fprintln(os.Stderr, snapTitleStyle.Render("=== Restoring from Snapshot ==="))
 	fmt.Fprintf(os.Stderr, "  %s %s\n", snapBoldStyle.Render("Source:"), source)
+	if desc := snap.Machine.Describe(); desc != "" {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", snapBoldStyle.Render("Captured on:"), desc)
+	}
 	fmt.Fprintf(os.Stderr, "  %s %d formulae, %d casks, %d npm, %d taps\n",
 		snapBoldStyle.Render("Packages:"),
 		len(snap.Packages.Formulae), len(snap.Packages.Casks),
@@ -594,9 +723,24 @@ func showRestoreInfo(snap *snapshot.Snapshot, source string) {
 		fmt.Fprintf(os.Stderr, "  %s Oh-My-Zsh (theme: %s, plugins: %s)\n",
 			snapBoldStyle.Render("Shell:"), theme, plugins)
 	}
+	if snap.Dotfiles.RepoURL != "" {
+		fmt.Fprintf(os.Stderr, "  %s %s\n",
+			snapBoldStyle.Render("Dotfiles:"), describeDotfiles(snap.Dotfiles))
+	}
 	fmt.Fprintln(os.Stderr)
 }
 
+func describeDotfiles(d snapshot.DotfilesSnapshot) string {
+	desc := d.RepoURL
+	if d.Ref != "" {
+		desc += "@" + d.Ref
+	}
+	if d.Subdir != "" {
+		desc += " (" + d.Subdir + ")"
+	}
+	return desc
+}
+
 func confirmInstallation(edited *snapshot.Snapshot, dryRun bool) (bool, error) {
 	totalFormulae := len(edited.Packages.Formulae)
 	totalCasks := len(edited.Packages.Casks)
@@ -674,5 +818,24 @@ func buildImportConfig(edited *snapshot.Snapshot, dryRun bool) *config.Config {
 		Plugins: edited.Shell.Plugins,
 	}
 
+	if edited.Dotfiles.RepoURL != "" {
+		cfg.SnapshotDotfiles = &config.SnapshotDotfilesConfig{
+			RepoURL: edited.Dotfiles.RepoURL,
+			Ref:     edited.Dotfiles.Ref,
+			Subdir:  edited.Dotfiles.Subdir,
+			Path:    edited.Dotfiles.Path,
+		}
+	}
+
+	for _, p := range edited.MacOSPrefs {
+		cfg.SnapshotMacOSPrefs = append(cfg.SnapshotMacOSPrefs, config.SnapshotMacOSPref{
+			Domain: p.Domain,
+			Key:    p.Key,
+			Type:   snapshot.PrefType(p),
+			Value:  p.Value,
+			Desc:   p.Desc,
+		})
+	}
+
 	return cfg
 }
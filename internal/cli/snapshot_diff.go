@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/openbootdotdev/openboot/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff <a> <b>",
+	Short: "Compare two snapshots",
+	Long: `Compare two snapshots and show what b has that a doesn't, and the other way round.
+
+Each side can be a snapshot file, a URL, a saved snapshot ID, "local" for the
+latest saved snapshot, or "live" to capture the current system.`,
+	Example: `  # Compare a teammate's snapshot with this machine
+  openboot snapshot diff teammate.json live
+
+  # Compare the saved snapshot with a published one
+  openboot snapshot diff local https://openboot.dev/alice/default/snapshot
+
+  # Machine-readable output
+  openboot snapshot diff a.json b.json --json`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonFlag, _ := cmd.Flags().GetBool("json")
+		return runSnapshotDiff(args[0], args[1], jsonFlag)
+	},
+}
+
+func init() {
+	snapshotDiffCmd.Flags().Bool("json", false, "Output the diff as JSON")
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+}
+
+func runSnapshotDiff(from, to string, jsonOutput bool) error {
+	a, err := resolveSnapshot(from)
+	if err != nil {
+		return err
+	}
+	b, err := resolveSnapshot(to)
+	if err != nil {
+		return err
+	}
+
+	diff := snapshot.Diff(a, b)
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal diff: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printSnapshotDiff(diff, from, to)
+	return nil
+}
+
+// resolveSnapshot loads a snapshot from a file, a URL, a history ID, the
+// latest saved snapshot ("local") or the current system ("live").
+func resolveSnapshot(source string) (*snapshot.Snapshot, error) {
+	switch source {
+	case "live":
+		return captureWithUI()
+	case "local":
+		return snapshot.LoadLocal()
+	default:
+		return loadSnapshot(source)
+	}
+}
+
+func printSnapshotDiff(d *snapshot.SnapshotDiff, from, to string) {
+	fmt.Println(ui.Red("--- " + from))
+	fmt.Println(ui.Green("+++ " + to))
+	fmt.Println()
+
+	if !d.Machine.Empty() {
+		fmt.Println(ui.Cyan("Machine"))
+		printValueChange("macOS", d.Machine.OSVersion)
+		printValueChange("macOS build", d.Machine.OSBuild)
+		printValueChange("arch", d.Machine.Arch)
+		printValueChange("Homebrew prefix", d.Machine.HomebrewPrefix)
+		printValueChange("Homebrew", d.Machine.HomebrewVersion)
+		printValueChange("openboot", d.Machine.OpenbootVersion)
+		printValueChange("Command Line Tools", d.Machine.CLTVersion)
+		printValueChange("Rosetta", d.Machine.Rosetta)
+		fmt.Println()
+	}
+
+	if d.Empty() {
+		ui.Success("Snapshots are identical")
+		return
+	}
+
+	printListDiff("Formulae", d.Formulae)
+	printListDiff("Casks", d.Casks)
+	printListDiff("Taps", d.Taps)
+	printListDiff("npm", d.Npm)
+
+	if len(d.MacOSPrefs) > 0 {
+		fmt.Println(ui.Cyan("macOS Preferences"))
+		for _, p := range d.MacOSPrefs {
+			name := p.Domain + " " + p.Key
+			switch {
+			case p.From == "":
+				fmt.Println(ui.Green(fmt.Sprintf("  + %s = %s", name, p.To)))
+			case p.To == "":
+				fmt.Println(ui.Red(fmt.Sprintf("  - %s = %s", name, p.From)))
+			default:
+				fmt.Println(ui.Yellow(fmt.Sprintf("  ~ %s: %s -> %s", name, p.From, p.To)))
+			}
+		}
+		fmt.Println()
+	}
+
+	if d.Shell.Default != nil || d.Shell.OhMyZsh != nil || d.Shell.Theme != nil || !d.Shell.Plugins.Empty() {
+		fmt.Println(ui.Cyan("Shell"))
+		printValueChange("default shell", d.Shell.Default)
+		printValueChange("Oh-My-Zsh", d.Shell.OhMyZsh)
+		printValueChange("theme", d.Shell.Theme)
+		printListItems("plugin", d.Shell.Plugins)
+		fmt.Println()
+	}
+
+	if d.Git.UserName != nil || d.Git.UserEmail != nil {
+		fmt.Println(ui.Cyan("Git"))
+		printValueChange("user.name", d.Git.UserName)
+		printValueChange("user.email", d.Git.UserEmail)
+		fmt.Println()
+	}
+
+	if d.Dotfiles.RepoURL != nil || d.Dotfiles.Ref != nil {
+		fmt.Println(ui.Cyan("Dotfiles"))
+		printValueChange("repo", d.Dotfiles.RepoURL)
+		printValueChange("ref", d.Dotfiles.Ref)
+		fmt.Println()
+	}
+
+	if len(d.DevTools) > 0 {
+		fmt.Println(ui.Cyan("Dev Tools"))
+		for _, t := range d.DevTools {
+			switch {
+			case t.From == "":
+				fmt.Println(ui.Green(fmt.Sprintf("  + %s %s", t.Name, t.To)))
+			case t.To == "":
+				fmt.Println(ui.Red(fmt.Sprintf("  - %s %s", t.Name, t.From)))
+			default:
+				fmt.Println(ui.Yellow(fmt.Sprintf("  ~ %s: %s -> %s", t.Name, t.From, t.To)))
+			}
+		}
+		fmt.Println()
+	}
+}
+
+func printListDiff(title string, d snapshot.ListDiff) {
+	if d.Empty() {
+		return
+	}
+	fmt.Printf("%s %s\n", ui.Cyan(title), summarizeListDiff(d))
+	printListItems("", d)
+	fmt.Println()
+}
+
+func printListItems(label string, d snapshot.ListDiff) {
+	if label != "" {
+		label += " "
+	}
+	for _, item := range d.Added {
+		fmt.Println(ui.Green("  + " + label + item))
+	}
+	for _, item := range d.Removed {
+		fmt.Println(ui.Red("  - " + label + item))
+	}
+}
+
+func summarizeListDiff(d snapshot.ListDiff) string {
+	var parts []string
+	if len(d.Added) > 0 {
+		parts = append(parts, fmt.Sprintf("+%d", len(d.Added)))
+	}
+	if len(d.Removed) > 0 {
+		parts = append(parts, fmt.Sprintf("-%d", len(d.Removed)))
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+func printValueChange(label string, c *snapshot.ValueChange) {
+	if c == nil {
+		return
+	}
+	from, to := c.From, c.To
+	if from == "" {
+		from = "(none)"
+	}
+	if to == "" {
+		to = "(none)"
+	}
+	fmt.Println(ui.Yellow(fmt.Sprintf("  ~ %s: %s -> %s", label, from, to)))
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+)
+
+// runSnapshotExport writes a snapshot in another tool's format to stdout.
+// Like --json output it leaves the machine, so redaction rules apply.
+func runSnapshotExport(format, from string, rules snapshot.RedactionRules) error {
+	exporter, err := snapshot.LookupExporter(format)
+	if err != nil {
+		return err
+	}
+
+	var snap *snapshot.Snapshot
+	if from != "" {
+		snap, err = loadSnapshot(from)
+	} else {
+		fmt.Fprintln(os.Stderr, "Capturing environment snapshot...")
+		snap, err = snapshot.Capture()
+		if err == nil {
+			stampVersion(snap)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	snap, changes := rules.Apply(snap)
+	if len(changes) > 0 {
+		fmt.Fprintf(os.Stderr, "Redacted %d field(s) per your redaction rules\n", len(changes))
+	}
+	if err := exporter.Write(os.Stdout, snap); err != nil {
+		return fmt.Errorf("failed to write %s export: %w", format, err)
+	}
+	fmt.Fprintf(os.Stderr, "✓ Exported as %s\n", format)
+	return nil
+}
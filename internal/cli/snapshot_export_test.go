@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotFlags_JSONAndExportAreExclusive(t *testing.T) {
+	newCmd := func() *cobra.Command {
+		cmd := &cobra.Command{Use: "snapshot"}
+		addSnapshotFlags(cmd)
+		return cmd
+	}
+
+	cmd := newCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--json"}))
+	assert.NoError(t, cmd.ValidateFlagGroups())
+
+	cmd = newCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--export", "sh"}))
+	assert.NoError(t, cmd.ValidateFlagGroups())
+
+	cmd = newCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--json", "--export", "sh"}))
+	assert.ErrorContains(t, cmd.ValidateFlagGroups(), "none of the others can be")
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved snapshots",
+	Long: `List the snapshots saved with 'openboot snapshot --local', newest first.
+
+Any ID (or an unambiguous prefix of one) can be passed to 'snapshot show',
+'snapshot restore', 'snapshot diff' and 'clean --from'.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSnapshotList()
+	},
+}
+
+var snapshotShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a saved snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonFlag, _ := cmd.Flags().GetBool("json")
+		return runSnapshotShow(args[0], jsonFlag)
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <id>",
+	Short: "Restore a saved snapshot",
+	Long: `Restore a saved snapshot by ID. Use "latest" for the most recent one.
+
+This is the same as 'openboot snapshot --import' with the snapshot's file.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := restoreFlags(cmd)
+		if err != nil {
+			return err
+		}
+		return runSnapshotRestore(cmd, args[0], opts)
+	},
+}
+
+var snapshotPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old saved snapshots",
+	Long: `Delete saved snapshots that fall outside the retention policy.
+
+A snapshot is kept if it is one of the last --keep-last snapshots, the newest
+of one of the last --keep-daily days, or the newest of one of the last
+--keep-weekly weeks. The most recent snapshot is never deleted.
+
+The default policy is applied automatically each time a snapshot is saved.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keepLast, _ := cmd.Flags().GetInt("keep-last")
+		keepDaily, _ := cmd.Flags().GetInt("keep-daily")
+		keepWeekly, _ := cmd.Flags().GetInt("keep-weekly")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		policy := snapshot.RetentionPolicy{KeepLast: keepLast, KeepDaily: keepDaily, KeepWeekly: keepWeekly}
+		return runSnapshotPrune(policy, dryRun)
+	},
+}
+
+func init() {
+	snapshotShowCmd.Flags().Bool("json", false, "Print the raw snapshot JSON")
+	snapshotRestoreCmd.Flags().Bool("dry-run", false, "preview without installing or modifying anything")
+	addRestoreFlags(snapshotRestoreCmd)
+	snapshotPruneCmd.Flags().Int("keep-last", snapshot.DefaultRetention.KeepLast, "number of most recent snapshots to keep")
+	snapshotPruneCmd.Flags().Int("keep-daily", snapshot.DefaultRetention.KeepDaily, "number of days to keep one snapshot for")
+	snapshotPruneCmd.Flags().Int("keep-weekly", snapshot.DefaultRetention.KeepWeekly, "number of weeks to keep one snapshot for")
+	snapshotPruneCmd.Flags().Bool("dry-run", false, "show what would be deleted without deleting anything")
+
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotShowCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	snapshotCmd.AddCommand(snapshotPruneCmd)
+}
+
+func runSnapshotList() error {
+	entries, err := snapshot.ListHistory()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, snapMutedStyle.Render("No snapshots saved yet — run 'openboot snapshot --local' first."))
+		return nil
+	}
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, snapTitleStyle.Render("=== Saved Snapshots ==="))
+	fmt.Fprintln(os.Stderr)
+
+	for i, e := range entries {
+		line := fmt.Sprintf("  %-20s %s", e.ID, e.SavedAt.Format("2006-01-02 15:04"))
+		if snap, err := snapshot.LoadFile(e.Path); err == nil {
+			line += fmt.Sprintf("  %d formulae, %d casks, %d npm",
+				len(snap.Packages.Formulae), len(snap.Packages.Casks), len(snap.Packages.Npm))
+			if snap.Hostname != "" {
+				line += "  " + snapMutedStyle.Render(snap.Hostname)
+			}
+		} else {
+			line += "  " + snapMutedStyle.Render("(unreadable)")
+		}
+		if i == 0 {
+			line += "  " + snapSuccessStyle.Render("(latest)")
+		}
+		fmt.Fprintln(os.Stderr, line)
+	}
+
+	fmt.Fprintln(os.Stderr)
+	return nil
+}
+
+func runSnapshotShow(ref string, jsonOutput bool) error {
+	entry, err := snapshot.ResolveRef(ref)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := os.ReadFile(entry.Path)
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot file: %w", err)
+		}
+		fmt.Println(strings.TrimRight(string(data), "\n"))
+		return nil
+	}
+
+	snap, err := snapshot.LoadFile(entry.Path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintf(os.Stderr, "  %s %s\n", snapBoldStyle.Render("ID:"), entry.ID)
+	fmt.Fprintf(os.Stderr, "  %s %s\n", snapBoldStyle.Render("Saved:"), entry.SavedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(os.Stderr, "  %s %s\n", snapBoldStyle.Render("Location:"), entry.Path)
+	showSnapshotPreview(snap)
+	fmt.Fprintln(os.Stderr)
+	return nil
+}
+
+func runSnapshotRestore(cmd *cobra.Command, ref string, opts restoreOptions) error {
+	entry, err := snapshot.ResolveRef(ref)
+	if err != nil {
+		return err
+	}
+	return runSnapshotImport(cmd, entry.Path, opts)
+}
+
+func runSnapshotPrune(policy snapshot.RetentionPolicy, dryRun bool) error {
+	removed, err := snapshot.Prune(policy, dryRun)
+	if err != nil {
+		return err
+	}
+
+	if len(removed) == 0 {
+		fmt.Fprintln(os.Stderr, snapMutedStyle.Render("Nothing to prune."))
+		return nil
+	}
+
+	verb := "Deleted"
+	if dryRun {
+		verb = "Would delete"
+	}
+	fmt.Fprintf(os.Stderr, "%s %d snapshot(s):\n", verb, len(removed))
+	for _, e := range removed {
+		fmt.Fprintf(os.Stderr, "  %s\n", e.ID)
+	}
+	return nil
+}
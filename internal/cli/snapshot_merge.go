@@ -0,0 +1,294 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/openbootdotdev/openboot/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var snapshotMergeCmd = &cobra.Command{
+	Use:   "merge <a> <b> [more...]",
+	Short: "Merge several snapshots into one",
+	Long: `Merge snapshots, e.g. from everyone on a team, into one baseline.
+
+Strategies decide which packages, taps, preferences and dev tools are kept:
+  union          Anything found in any snapshot
+  intersection   Only what every snapshot has
+  majority       What more than half of the snapshots have (default)
+
+A preference's value, a dev tool's version, the default shell, the theme and
+the dotfiles repo take the most common value. Ties are reported as conflicts;
+with --edit they are resolved interactively, otherwise the value from the
+earliest snapshot wins. Git identity and hostname are never merged.
+
+Each input can be a file, a URL, a saved snapshot ID, "local" or "live".`,
+	Example: `  # Team baseline from five snapshots
+  openboot snapshot merge alice.json bob.json carol.json dan.json eve.json -o team.json
+
+  # Review the result and resolve conflicts in the snapshot editor
+  openboot snapshot merge *.json --strategy union --edit -o team.json`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		strategyFlag, _ := cmd.Flags().GetString("strategy")
+		output, _ := cmd.Flags().GetString("output")
+		edit, _ := cmd.Flags().GetBool("edit")
+		strategy, err := snapshot.ParseMergeStrategy(strategyFlag)
+		if err != nil {
+			return err
+		}
+		return runSnapshotMerge(args, strategy, output, edit)
+	},
+}
+
+func init() {
+	snapshotMergeCmd.Flags().String("strategy", string(snapshot.MergeMajority), "union, intersection or majority")
+	snapshotMergeCmd.Flags().StringP("output", "o", "", "write the merged snapshot to this file instead of stdout")
+	snapshotMergeCmd.Flags().Bool("edit", false, "resolve conflicts and review the result in the snapshot editor")
+	snapshotCmd.AddCommand(snapshotMergeCmd)
+}
+
+func runSnapshotMerge(sources []string, strategy snapshot.MergeStrategy, output string, edit bool) error {
+	snaps := make([]*snapshot.Snapshot, len(sources))
+	for i, source := range sources {
+		snap, err := resolveSnapshot(source)
+		if err != nil {
+			return err
+		}
+		snaps[i] = snap
+	}
+
+	res, err := snapshot.Merge(snaps, strategy)
+	if err != nil {
+		return err
+	}
+
+	printMergeReport(res, strategy)
+
+	merged := res.Snapshot
+	if edit {
+		var ok bool
+		merged, ok, err = editMerge(res, snaps)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(os.Stderr, snapMutedStyle.Render("Merge cancelled."))
+			return nil
+		}
+	} else if len(res.Conflicts) > 0 {
+		fmt.Fprintln(os.Stderr, snapMutedStyle.Render("  Conflicts used the value from the earliest snapshot; pass --edit to choose."))
+		fmt.Fprintln(os.Stderr)
+	}
+
+	catalogMatch := snapshot.MatchPackages(merged)
+	merged.CatalogMatch = *catalogMatch
+	merged.MatchedPreset = snapshot.DetectBestPreset(merged)
+
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	if output == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, snapSuccessStyle.Render("✓ Merged snapshot written to "+output))
+	return nil
+}
+
+func printMergeReport(res *snapshot.MergeResult, strategy snapshot.MergeStrategy) {
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, snapTitleStyle.Render(fmt.Sprintf("=== Merged %d snapshots (%s) ===", res.Total, strategy)))
+	fmt.Fprintln(os.Stderr)
+
+	kept := map[string][]string{
+		"formulae":      res.Snapshot.Packages.Formulae,
+		"casks":         res.Snapshot.Packages.Casks,
+		"taps":          res.Snapshot.Packages.Taps,
+		"npm":           res.Snapshot.Packages.Npm,
+		"shell.plugins": res.Snapshot.Shell.Plugins,
+	}
+	for _, t := range res.Snapshot.DevTools {
+		kept["dev_tools"] = append(kept["dev_tools"], t.Name)
+	}
+
+	for _, field := range []string{"formulae", "casks", "taps", "npm", "shell.plugins", "dev_tools"} {
+		counts := res.Counts[field]
+		if len(counts) == 0 {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "  %s %d of %d kept\n", snapBoldStyle.Render(field+":"), len(kept[field]), len(counts))
+		keep := make(map[string]bool, len(kept[field]))
+		for _, name := range kept[field] {
+			keep[name] = true
+		}
+		for _, c := range counts {
+			line := fmt.Sprintf("    %d/%d  %s", c.Count, res.Total, c.Name)
+			if !keep[c.Name] {
+				line = snapMutedStyle.Render(line + " (dropped)")
+			}
+			fmt.Fprintln(os.Stderr, line)
+		}
+	}
+
+	if len(res.Conflicts) > 0 {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, snapWarnStyle.Render(fmt.Sprintf("  ⚠ %d conflict(s):", len(res.Conflicts))))
+		for _, c := range res.Conflicts {
+			fmt.Fprintf(os.Stderr, "    %s: %s\n", describeConflict(c), formatConflictValues(c.Values, res.Total))
+		}
+	}
+	fmt.Fprintln(os.Stderr)
+}
+
+func describeConflict(c snapshot.MergeConflict) string {
+	switch c.Field {
+	case "macos_prefs":
+		return fmt.Sprintf("%s.%s", c.Domain, c.Key)
+	case "dev_tools":
+		return c.Key + " version"
+	default:
+		return c.Field
+	}
+}
+
+func formatConflictValues(values []snapshot.ItemCount, total int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%s (%d/%d)", v.Name, v.Count, total)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// editMerge resolves scalar conflicts with prompts, then opens the snapshot
+// editor on every candidate formula, cask and preference value, with the
+// merge result pre-selected. Picking between conflicting preference values
+// happens there.
+func editMerge(res *snapshot.MergeResult, snaps []*snapshot.Snapshot) (*snapshot.Snapshot, bool, error) {
+	for _, c := range res.Conflicts {
+		if c.Field == "macos_prefs" {
+			continue
+		}
+		choice, err := ui.SelectOption("Conflict: "+describeConflict(c), conflictOptions(c, res.Total))
+		if err != nil {
+			return nil, false, err
+		}
+		if err := res.Resolve(c, c.Values[indexOfOption(c, res.Total, choice)].Name); err != nil {
+			return nil, false, err
+		}
+	}
+
+	hints := ui.EditorHints{
+		Title:      fmt.Sprintf("🔀 Merge Editor — %d snapshots", res.Total),
+		Notes:      map[string]string{},
+		Deselected: map[string]bool{},
+	}
+	for _, field := range []string{"formulae", "casks"} {
+		for _, c := range res.Counts[field] {
+			hints.Notes[c.Name] = fmt.Sprintf("(%d/%d)", c.Count, res.Total)
+		}
+	}
+	keep := map[string]bool{}
+	for _, name := range res.Snapshot.Packages.Formulae {
+		keep[name] = true
+	}
+	for _, name := range res.Snapshot.Packages.Casks {
+		keep[name] = true
+	}
+	for _, p := range res.Snapshot.MacOSPrefs {
+		keep[ui.EditorPrefKey(p)] = true
+	}
+	prefCounts := map[string]int{}
+	for _, s := range snaps {
+		for _, p := range s.MacOSPrefs {
+			prefCounts[ui.EditorPrefKey(p)]++
+		}
+	}
+	cand := res.Candidates
+	for _, name := range append(append([]string{}, cand.Packages.Formulae...), cand.Packages.Casks...) {
+		hints.Deselected[name] = !keep[name]
+	}
+	for _, p := range cand.MacOSPrefs {
+		k := ui.EditorPrefKey(p)
+		hints.Notes[k] = fmt.Sprintf("(%d/%d)", prefCounts[k], res.Total)
+		hints.Deselected[k] = !keep[k]
+	}
+
+	edited, ok, err := ui.RunSnapshotEditorWithHints(cand, hints)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	merged := *res.Snapshot
+	merged.Packages.Formulae = edited.Packages.Formulae
+	merged.Packages.Casks = edited.Packages.Casks
+	merged.MacOSPrefs, err = dedupePrefs(edited.MacOSPrefs)
+	if err != nil {
+		return nil, false, err
+	}
+	return &merged, true, nil
+}
+
+// dedupePrefs asks which value to keep when more than one value of the same
+// preference was left selected in the editor.
+func dedupePrefs(prefs []snapshot.MacOSPref) ([]snapshot.MacOSPref, error) {
+	type prefKey struct{ domain, key string }
+	var order []prefKey
+	byKey := map[prefKey][]snapshot.MacOSPref{}
+	for _, p := range prefs {
+		k := prefKey{p.Domain, p.Key}
+		if _, ok := byKey[k]; !ok {
+			order = append(order, k)
+		}
+		byKey[k] = append(byKey[k], p)
+	}
+
+	out := make([]snapshot.MacOSPref, 0, len(order))
+	for _, k := range order {
+		options := byKey[k]
+		if len(options) == 1 {
+			out = append(out, options[0])
+			continue
+		}
+		labels := make([]string, len(options))
+		for i, p := range options {
+			labels[i] = p.Value
+		}
+		choice, err := ui.SelectOption(fmt.Sprintf("Which value for %s.%s?", k.domain, k.key), labels)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range options {
+			if p.Value == choice {
+				out = append(out, p)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func conflictOptions(c snapshot.MergeConflict, total int) []string {
+	options := make([]string, len(c.Values))
+	for i, v := range c.Values {
+		options[i] = fmt.Sprintf("%s (%d/%d)", v.Name, v.Count, total)
+	}
+	return options
+}
+
+func indexOfOption(c snapshot.MergeConflict, total int, choice string) int {
+	for i, o := range conflictOptions(c, total) {
+		if o == choice {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var snapshotMigrateCmd = &cobra.Command{
+	Use:   "migrate <file>",
+	Short: "Upgrade a snapshot file to the current schema version",
+	Long: `Upgrade a snapshot file written by an older openboot to the current schema
+version, rewriting it in place.
+
+Older snapshots are upgraded automatically when they are loaded; this makes
+the change permanent so the file can be shared with other tools.`,
+	Example: `  openboot snapshot migrate my-setup.json
+  openboot snapshot migrate my-setup.json --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		return runSnapshotMigrate(args[0], dryRun)
+	},
+}
+
+func init() {
+	snapshotMigrateCmd.Flags().Bool("dry-run", false, "report the upgrade without rewriting the file")
+	snapshotCmd.AddCommand(snapshotMigrateCmd)
+}
+
+func runSnapshotMigrate(path string, dryRun bool) error {
+	path = resolveSnapshotPath(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+
+	_, from, err := snapshot.Migrate(data)
+	if err != nil {
+		return fmt.Errorf("failed to migrate %s: %w", path, err)
+	}
+	if from == snapshot.CurrentVersion {
+		fmt.Fprintln(os.Stderr, snapMutedStyle.Render(fmt.Sprintf("%s is already at version %d.", path, from)))
+		return nil
+	}
+
+	snap, err := snapshot.Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed to migrate %s: %w", path, err)
+	}
+
+	if dryRun {
+		fmt.Fprintf(os.Stderr, "Would upgrade %s from version %d to %d\n", path, from, snapshot.CurrentVersion)
+		return nil
+	}
+
+	out, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat snapshot file: %w", err)
+	}
+	if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+
+	fmt.Fprintln(os.Stderr, snapSuccessStyle.Render(fmt.Sprintf("✓ Upgraded %s from version %d to %d", path, from, snapshot.CurrentVersion)))
+	return nil
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/openbootdotdev/openboot/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// redactionRules loads ~/.openboot/redact.json and layers the command-line
+// redaction flags on top.
+func redactionRules(cmd *cobra.Command) (snapshot.RedactionRules, error) {
+	rules, err := snapshot.LoadRedactionRules()
+	if err != nil {
+		return rules, err
+	}
+
+	if dropHostname, _ := cmd.Flags().GetBool("redact-hostname"); dropHostname {
+		rules.DropHostname = true
+	}
+	if dropName, _ := cmd.Flags().GetBool("redact-git-name"); dropName {
+		rules.DropGitName = true
+	}
+	if dropRepo, _ := cmd.Flags().GetBool("redact-dotfiles-repo"); dropRepo {
+		rules.DropDotfilesRepo = true
+	}
+	if email, _ := cmd.Flags().GetString("redact-email"); email != "" {
+		rules.Email = email
+	}
+	exclude, _ := cmd.Flags().GetStringArray("exclude")
+	rules.Exclude = append(rules.Exclude, exclude...)
+
+	return rules, rules.Validate()
+}
+
+// reviewOutgoing shows exactly what will be uploaded after redaction and
+// lets the user tighten the rules before anything is sent. It returns the
+// redacted snapshot, or false if the user cancelled.
+func reviewOutgoing(snap *snapshot.Snapshot, rules snapshot.RedactionRules) (*snapshot.Snapshot, bool, error) {
+	for {
+		out, changes := rules.Apply(snap)
+		showOutgoingReview(out, changes)
+
+		const (
+			optUpload       = "Upload as shown"
+			optDropHostname = "Drop hostname"
+			optDropGitName  = "Remove git name"
+			optHashEmail    = "Replace email with a hash"
+			optRemoveEmail  = "Remove email"
+			optDropDotfiles = "Remove dotfiles repo"
+			optExclude      = "Exclude packages or taps matching a pattern"
+			optCancel       = "Cancel upload"
+		)
+		options := []string{optUpload}
+		if out.Hostname != "" {
+			options = append(options, optDropHostname)
+		}
+		if out.Git.UserName != "" {
+			options = append(options, optDropGitName)
+		}
+		if snap.Git.UserEmail != "" && rules.Email != snapshot.EmailHash && rules.Email != snapshot.EmailRemove {
+			options = append(options, optHashEmail)
+		}
+		if out.Git.UserEmail != "" {
+			options = append(options, optRemoveEmail)
+		}
+		if out.Dotfiles.RepoURL != "" {
+			options = append(options, optDropDotfiles)
+		}
+		options = append(options, optExclude, optCancel)
+
+		choice, err := ui.SelectOption("What should leave this machine?", options)
+		if err != nil {
+			return nil, false, err
+		}
+
+		switch choice {
+		case optUpload:
+			return out, true, nil
+		case optDropHostname:
+			rules.DropHostname = true
+		case optDropGitName:
+			rules.DropGitName = true
+		case optDropDotfiles:
+			rules.DropDotfilesRepo = true
+		case optHashEmail:
+			rules.Email = snapshot.EmailHash
+		case optRemoveEmail:
+			rules.Email = snapshot.EmailRemove
+		case optExclude:
+			pattern, err := ui.Input("Pattern (e.g. acme-corp/* or *-internal)", "")
+			if err != nil {
+				return nil, false, err
+			}
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				continue
+			}
+			candidate := rules
+			candidate.Exclude = append(append([]string{}, rules.Exclude...), pattern)
+			if err := candidate.Validate(); err != nil {
+				fmt.Fprintln(os.Stderr, snapWarnStyle.Render("  "+err.Error()))
+				continue
+			}
+			rules = candidate
+		default:
+			return nil, false, nil
+		}
+	}
+}
+
+func showOutgoingReview(snap *snapshot.Snapshot, changes []snapshot.Redaction) {
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, snapTitleStyle.Render("=== This will be uploaded ==="))
+	fmt.Fprintln(os.Stderr)
+
+	fmt.Fprintf(os.Stderr, "  %s %s\n", snapBoldStyle.Render("Hostname:"), orNone(snap.Hostname))
+	fmt.Fprintf(os.Stderr, "  %s %s <%s>\n", snapBoldStyle.Render("Git:"), orNone(snap.Git.UserName), orNone(snap.Git.UserEmail))
+	printOutgoingList("Taps", snap.Packages.Taps)
+	printOutgoingList("Formulae", snap.Packages.Formulae)
+	printOutgoingList("Casks", snap.Packages.Casks)
+	printOutgoingList("npm", snap.Packages.Npm)
+	fmt.Fprintf(os.Stderr, "  %s %d\n", snapBoldStyle.Render("macOS Preferences:"), len(snap.MacOSPrefs))
+	fmt.Fprintf(os.Stderr, "  %s %s\n", snapBoldStyle.Render("Shell:"), orNone(snap.Shell.Default))
+	if snap.Dotfiles.RepoURL != "" {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", snapBoldStyle.Render("Dotfiles:"), describeDotfiles(snap.Dotfiles))
+	}
+	fmt.Fprintf(os.Stderr, "  %s %d\n", snapBoldStyle.Render("Dev Tools:"), len(snap.DevTools))
+
+	if len(changes) > 0 {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, snapBoldStyle.Render("  Redacted:"))
+		for _, c := range changes {
+			fmt.Fprintln(os.Stderr, snapMutedStyle.Render(fmt.Sprintf("    %s %s (%s)", c.Field, c.Value, c.Action)))
+		}
+	}
+	fmt.Fprintln(os.Stderr)
+}
+
+func printOutgoingList(label string, items []string) {
+	fmt.Fprintf(os.Stderr, "  %s %d\n", snapBoldStyle.Render(label+":"), len(items))
+	if len(items) > 0 {
+		fmt.Fprintf(os.Stderr, "    %s\n", strings.Join(items, ", "))
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
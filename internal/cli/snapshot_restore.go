@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/openbootdotdev/openboot/internal/config"
+	"github.com/openbootdotdev/openboot/internal/installer"
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// exitRestoreIncomplete is the exit status of a restore that finished but
+// had some steps fail.
+const exitRestoreIncomplete = 3
+
+// restoreCategories are the parts of a snapshot --only and --skip accept.
+// "packages" is shorthand for formulae, casks, npm and taps.
+var restoreCategories = []string{"formulae", "casks", "npm", "taps", "git", "shell", "dotfiles", "macos"}
+
+// restoreOptions control how a snapshot is installed.
+type restoreOptions struct {
+	importOptions
+	DryRun bool
+	// Yes skips the editor and confirmation and answers every later
+	// prompt with its default, as 'openboot --silent' does.
+	Yes bool
+	// Include holds the categories to restore; nil means all of them.
+	Include map[string]bool
+}
+
+func addRestoreFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("yes", "y", false, "restore without the editor or any prompts")
+	cmd.Flags().Bool("silent", false, "same as --yes")
+	cmd.Flags().StringSlice("only", nil, "restore only these categories: "+strings.Join(restoreCategories, ", ")+" or packages")
+	cmd.Flags().StringSlice("skip", nil, "leave these categories out of the restore")
+}
+
+// restoreFlags reads the flags added by addRestoreFlags, plus --dry-run,
+// --sha256 and --insecure where the command has them.
+func restoreFlags(cmd *cobra.Command) (restoreOptions, error) {
+	var opts restoreOptions
+	opts.DryRun, _ = cmd.Flags().GetBool("dry-run")
+	opts.SHA256, _ = cmd.Flags().GetString("sha256")
+	opts.Insecure, _ = cmd.Flags().GetBool("insecure")
+	yes, _ := cmd.Flags().GetBool("yes")
+	silent, _ := cmd.Flags().GetBool("silent")
+	opts.Yes = yes || silent
+
+	only, _ := cmd.Flags().GetStringSlice("only")
+	skip, _ := cmd.Flags().GetStringSlice("skip")
+	include, err := parseRestoreCategories(only, skip)
+	if err != nil {
+		return opts, err
+	}
+	opts.Include = include
+	return opts, nil
+}
+
+// parseRestoreCategories turns --only and --skip into the set of
+// categories to restore, or nil when neither was given.
+func parseRestoreCategories(only, skip []string) (map[string]bool, error) {
+	if len(only) == 0 && len(skip) == 0 {
+		return nil, nil
+	}
+
+	include := map[string]bool{}
+	if len(only) == 0 {
+		for _, c := range restoreCategories {
+			include[c] = true
+		}
+	}
+	for _, name := range only {
+		names, err := expandRestoreCategory(name)
+		if err != nil {
+			return nil, fmt.Errorf("--only: %w", err)
+		}
+		for _, n := range names {
+			include[n] = true
+		}
+	}
+	for _, name := range skip {
+		names, err := expandRestoreCategory(name)
+		if err != nil {
+			return nil, fmt.Errorf("--skip: %w", err)
+		}
+		for _, n := range names {
+			delete(include, n)
+		}
+	}
+	return include, nil
+}
+
+func expandRestoreCategory(name string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "packages" {
+		return []string{"formulae", "casks", "npm", "taps"}, nil
+	}
+	for _, c := range restoreCategories {
+		if c == name {
+			return []string{name}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown category %q (want %s or packages)", name, strings.Join(restoreCategories, ", "))
+}
+
+func (o restoreOptions) includes(category string) bool {
+	return o.Include == nil || o.Include[category]
+}
+
+// filterSnapshot returns a copy of snap without the excluded categories,
+// so the review and editor only show what will be restored.
+func (o restoreOptions) filterSnapshot(snap *snapshot.Snapshot) *snapshot.Snapshot {
+	out := *snap
+	if !o.includes("formulae") {
+		out.Packages.Formulae = nil
+	}
+	if !o.includes("casks") {
+		out.Packages.Casks = nil
+	}
+	if !o.includes("npm") {
+		out.Packages.Npm = nil
+	}
+	if !o.includes("taps") {
+		out.Packages.Taps = nil
+	}
+	if !o.includes("git") {
+		out.Git = snapshot.GitSnapshot{}
+	}
+	if !o.includes("shell") {
+		out.Shell = snapshot.ShellSnapshot{}
+	}
+	if !o.includes("dotfiles") {
+		out.Dotfiles = snapshot.DotfilesSnapshot{}
+	}
+	if !o.includes("macos") {
+		out.MacOSPrefs = nil
+	}
+	return &out
+}
+
+// applyToConfig turns off the installer steps for excluded categories and
+// makes the remaining steps non-interactive with --yes. Unattended, the
+// shell and macOS steps only apply what the snapshot contains rather than
+// falling back to openboot's defaults.
+func (o restoreOptions) applyToConfig(cfg *config.Config) {
+	cfg.Silent = o.Yes
+	if o.Yes {
+		if cfg.SnapshotShell == nil || !cfg.SnapshotShell.OhMyZsh {
+			cfg.Shell = "skip"
+		}
+		if len(cfg.SnapshotMacOSPrefs) == 0 {
+			cfg.Macos = "skip"
+		}
+	}
+	if !o.includes("git") {
+		cfg.SnapshotGit = nil
+	}
+	if !o.includes("shell") {
+		cfg.Shell = "skip"
+		cfg.SnapshotShell = nil
+	}
+	if !o.includes("dotfiles") {
+		cfg.SnapshotDotfiles = nil
+	}
+	if !o.includes("macos") {
+		cfg.Macos = "skip"
+	}
+}
+
+// restoreExitError maps an incomplete restore to exitRestoreIncomplete
+// after listing what failed.
+func restoreExitError(cmd *cobra.Command, err error) error {
+	var incomplete *installer.IncompleteError
+	if !errors.As(err, &incomplete) {
+		return err
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, snapWarnStyle.Render("⚠ Restore finished with failures: "+strings.Join(incomplete.Failed, ", ")))
+	fmt.Fprintln(os.Stderr)
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return &ExitError{Code: exitRestoreIncomplete}
+}
+
+// showCompatibilityWarnings compares the machine a snapshot was captured
+// on with this one and warns about likely problems.
+func showCompatibilityWarnings(snap *snapshot.Snapshot) {
+	if snap.Machine == nil {
+		return
+	}
+	here, err := snapshot.CaptureMachine()
+	if err != nil {
+		return
+	}
+	warnings := snapshot.CheckCompatibility(snap, here)
+	if len(warnings) == 0 {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, snapWarnStyle.Render("  ⚠ This snapshot may not fit this Mac:"))
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "    • %s\n", w)
+	}
+	fmt.Fprintln(os.Stderr)
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/openbootdotdev/openboot/internal/config"
+	"github.com/openbootdotdev/openboot/internal/installer"
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRestoreCategories(t *testing.T) {
+	all, err := parseRestoreCategories(nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, all)
+
+	only, err := parseRestoreCategories([]string{"packages", "Git"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"formulae": true, "casks": true, "npm": true, "taps": true, "git": true}, only)
+
+	skip, err := parseRestoreCategories(nil, []string{"macos"})
+	require.NoError(t, err)
+	assert.Len(t, skip, len(restoreCategories)-1)
+	assert.False(t, skip["macos"])
+	assert.True(t, skip["shell"])
+
+	both, err := parseRestoreCategories([]string{"packages"}, []string{"npm"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"formulae": true, "casks": true, "taps": true}, both)
+
+	_, err = parseRestoreCategories([]string{"fonts"}, nil)
+	assert.ErrorContains(t, err, `--only: unknown category "fonts"`)
+}
+
+func TestRestoreFlags(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("dry-run", false, "")
+	addRestoreFlags(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{"--silent", "--skip", "macos,dotfiles", "--dry-run"}))
+
+	opts, err := restoreFlags(cmd)
+	require.NoError(t, err)
+	assert.True(t, opts.Yes)
+	assert.True(t, opts.DryRun)
+	assert.False(t, opts.includes("macos"))
+	assert.False(t, opts.includes("dotfiles"))
+	assert.True(t, opts.includes("formulae"))
+}
+
+func TestRestoreOptions_FilterSnapshot(t *testing.T) {
+	snap := &snapshot.Snapshot{
+		Packages: snapshot.PackageSnapshot{
+			Formulae: []string{"git"},
+			Casks:    []string{"firefox"},
+			Npm:      []string{"typescript"},
+			Taps:     []string{"acme/tools"},
+		},
+		Git:        snapshot.GitSnapshot{UserName: "Test", UserEmail: "test@example.com"},
+		Shell:      snapshot.ShellSnapshot{OhMyZsh: true, Theme: "robbyrussell"},
+		Dotfiles:   snapshot.DotfilesSnapshot{RepoURL: "https://github.com/test/dotfiles"},
+		MacOSPrefs: []snapshot.MacOSPref{{Domain: "com.apple.dock", Key: "autohide", Value: "1"}},
+	}
+
+	opts := restoreOptions{Include: map[string]bool{"formulae": true, "git": true}}
+	out := opts.filterSnapshot(snap)
+	assert.Equal(t, []string{"git"}, out.Packages.Formulae)
+	assert.Nil(t, out.Packages.Casks)
+	assert.Nil(t, out.Packages.Npm)
+	assert.Nil(t, out.Packages.Taps)
+	assert.Equal(t, "Test", out.Git.UserName)
+	assert.False(t, out.Shell.OhMyZsh)
+	assert.Empty(t, out.Dotfiles.RepoURL)
+	assert.Nil(t, out.MacOSPrefs)
+
+	// The original is untouched.
+	assert.Equal(t, []string{"firefox"}, snap.Packages.Casks)
+
+	assert.Equal(t, snap, restoreOptions{}.filterSnapshot(snap))
+}
+
+func TestRestoreOptions_ApplyToConfig(t *testing.T) {
+	snap := &snapshot.Snapshot{
+		Git:      snapshot.GitSnapshot{UserName: "Test"},
+		Shell:    snapshot.ShellSnapshot{OhMyZsh: true},
+		Dotfiles: snapshot.DotfilesSnapshot{RepoURL: "https://github.com/test/dotfiles"},
+	}
+
+	cfg := buildImportConfig(snap, true)
+	restoreOptions{Yes: true}.applyToConfig(cfg)
+	assert.True(t, cfg.Silent)
+	assert.NotNil(t, cfg.SnapshotGit)
+	assert.NotNil(t, cfg.SnapshotShell)
+	assert.Empty(t, cfg.Shell)
+	assert.NotNil(t, cfg.SnapshotDotfiles)
+	assert.Equal(t, "skip", cfg.Macos, "--yes never applies default preferences the snapshot doesn't contain")
+
+	withPrefs := *snap
+	withPrefs.Shell = snapshot.ShellSnapshot{}
+	withPrefs.MacOSPrefs = []snapshot.MacOSPref{{Domain: "com.apple.dock", Key: "autohide", Value: "1"}}
+	cfg = buildImportConfig(&withPrefs, true)
+	restoreOptions{Yes: true}.applyToConfig(cfg)
+	assert.Equal(t, "skip", cfg.Shell, "--yes never installs Oh-My-Zsh the snapshot doesn't use")
+	assert.Empty(t, cfg.Macos)
+	assert.Equal(t, []config.SnapshotMacOSPref{{Domain: "com.apple.dock", Key: "autohide", Type: "bool", Value: "1"}}, cfg.SnapshotMacOSPrefs)
+
+	cfg = buildImportConfig(snap, true)
+	restoreOptions{Include: map[string]bool{"formulae": true}}.applyToConfig(cfg)
+	assert.False(t, cfg.Silent)
+	assert.Nil(t, cfg.SnapshotGit)
+	assert.Nil(t, cfg.SnapshotShell)
+	assert.Equal(t, "skip", cfg.Shell)
+	assert.Nil(t, cfg.SnapshotDotfiles)
+	assert.Equal(t, "skip", cfg.Macos)
+}
+
+func TestRestoreExitError(t *testing.T) {
+	cmd := &cobra.Command{}
+	assert.NoError(t, restoreExitError(cmd, nil))
+
+	other := errors.New("boom")
+	assert.Equal(t, other, restoreExitError(cmd, other))
+
+	err := restoreExitError(cmd, &installer.IncompleteError{Failed: []string{"packages", "npm"}})
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitRestoreIncomplete, exitErr.Code)
+	assert.True(t, cmd.SilenceErrors)
+}
@@ -0,0 +1,305 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// maxSnapshotSize caps how much is read from a snapshot source. Real
+// snapshots are a few KB; anything this large is not a snapshot.
+const maxSnapshotSize = 10 << 20
+
+var (
+	// errNoSignature is returned when a snapshot has no .sig next to it.
+	errNoSignature = errors.New("snapshot is not signed")
+	errNotFound    = errors.New("not found")
+)
+
+// importOptions are the integrity requirements for a snapshot that is
+// about to be installed.
+type importOptions struct {
+	SHA256   string
+	Insecure bool
+}
+
+var snapshotSignCmd = &cobra.Command{
+	Use:   "sign <file>",
+	Short: "Sign a snapshot file",
+	Long: `Sign a snapshot with this machine's ed25519 key, writing a detached
+signature to <file>.sig. Publish the .sig next to the snapshot.
+
+A key is generated in ~/.openboot/signing_key the first time. Share the
+printed public key so others can trust it with 'openboot snapshot trust'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSnapshotSign(args[0])
+	},
+}
+
+var snapshotVerifyCmd = &cobra.Command{
+	Use:   "verify <file|url>",
+	Short: "Verify a snapshot's signature or checksum",
+	Long: `Check that a snapshot was signed by a key in ~/.openboot/trusted_keys, or
+that it matches a pinned SHA-256 checksum.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sha, _ := cmd.Flags().GetString("sha256")
+		return runSnapshotVerify(args[0], sha)
+	},
+}
+
+var snapshotTrustCmd = &cobra.Command{
+	Use:   "trust <public-key> [name]",
+	Short: "Trust snapshots signed by a public key",
+	Long: `Add a public key to ~/.openboot/trusted_keys. Snapshots imported from URLs
+must be signed by one of these keys unless pinned with --sha256.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := ""
+		if len(args) == 2 {
+			name = args[1]
+		}
+		return runSnapshotTrust(args[0], name)
+	},
+}
+
+func init() {
+	snapshotVerifyCmd.Flags().String("sha256", "", "check against this SHA-256 checksum instead of a signature")
+
+	snapshotCmd.AddCommand(snapshotSignCmd)
+	snapshotCmd.AddCommand(snapshotVerifyCmd)
+	snapshotCmd.AddCommand(snapshotTrustCmd)
+}
+
+func runSnapshotSign(path string) error {
+	path = resolveSnapshotPath(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+	if _, err := snapshot.Parse(data); err != nil {
+		return fmt.Errorf("refusing to sign %s: %w", path, err)
+	}
+
+	key, created, err := snapshot.LoadOrCreateSigningKey()
+	if err != nil {
+		return err
+	}
+
+	sig, err := json.MarshalIndent(snapshot.Sign(data, key), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal signature: %w", err)
+	}
+	sigPath := path + snapshot.SignatureSuffix
+	if err := os.WriteFile(sigPath, append(sig, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+
+	if created {
+		fmt.Fprintf(os.Stderr, "Generated a new signing key in %s\n", snapshot.SigningKeyPath())
+	}
+	fmt.Fprintln(os.Stderr, snapSuccessStyle.Render("✓ Signed "+path))
+	fmt.Fprintf(os.Stderr, "  %s %s\n", snapBoldStyle.Render("Signature:"), sigPath)
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, snapBoldStyle.Render("  Others can trust this key with:"))
+	fmt.Fprintf(os.Stderr, "    %s\n", snapMutedStyle.Render("openboot snapshot trust "+snapshot.EncodePublicKey(key.Public().(ed25519.PublicKey))))
+	return nil
+}
+
+func runSnapshotVerify(source, sha string) error {
+	data, err := readSnapshotSource(source)
+	if err != nil {
+		return err
+	}
+
+	if sha != "" {
+		if err := snapshot.VerifySHA256(data, sha); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, snapSuccessStyle.Render("✓ Checksum matches"))
+		return nil
+	}
+
+	signer, err := verifySignature(source, data)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, snapSuccessStyle.Render("✓ Signed by "+describeKey(signer)))
+	return nil
+}
+
+func runSnapshotTrust(encoded, name string) error {
+	key, err := snapshot.ParsePublicKey(encoded)
+	if err != nil {
+		return err
+	}
+	if err := snapshot.AddTrustedKey(key, name); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, snapSuccessStyle.Render("✓ Trusted key "+snapshot.KeyID(key)))
+	return nil
+}
+
+// verifySnapshotSource enforces importOptions. A pinned checksum is always
+// checked. Otherwise remote snapshots need a trusted signature, and local
+// files are checked only if they have a .sig next to them.
+func verifySnapshotSource(source string, data []byte, opts importOptions) error {
+	if opts.SHA256 != "" {
+		if err := snapshot.VerifySHA256(data, opts.SHA256); err != nil {
+			return fmt.Errorf("refusing to install %s: %w", source, err)
+		}
+		fmt.Fprintln(os.Stderr, snapSuccessStyle.Render("  ✓ Checksum verified"))
+		return nil
+	}
+
+	signer, err := verifySignature(source, data)
+	switch {
+	case err == nil:
+		fmt.Fprintln(os.Stderr, snapSuccessStyle.Render("  ✓ Signed by "+describeKey(signer)))
+		return nil
+	case !isRemoteSource(source) && errors.Is(err, errNoSignature):
+		return nil
+	case opts.Insecure:
+		fmt.Fprintln(os.Stderr, snapWarnStyle.Render(fmt.Sprintf("  ⚠ Installing an unverified snapshot (--insecure): %v", err)))
+		return nil
+	default:
+		return fmt.Errorf("refusing to install %s: %w\n"+
+			"  Pin it with --sha256, trust the publisher's key with 'openboot snapshot trust',\n"+
+			"  or pass --insecure if you trust this source", source, err)
+	}
+}
+
+// verifySignature checks the detached signature at source+".sig" against
+// the trusted keys.
+func verifySignature(source string, data []byte) (*snapshot.TrustedKey, error) {
+	sigData, err := readSignature(source)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := snapshot.ParseSignature(sigData)
+	if err != nil {
+		return nil, err
+	}
+	trusted, err := snapshot.LoadTrustedKeys()
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Verify(data, sig, trusted)
+}
+
+func readSignature(source string) ([]byte, error) {
+	if isRemoteSource(source) {
+		data, err := download(source+snapshot.SignatureSuffix, maxSnapshotSize)
+		if errors.Is(err, errNotFound) {
+			return nil, errNoSignature
+		}
+		return data, err
+	}
+	data, err := os.ReadFile(resolveSnapshotPath(source) + snapshot.SignatureSuffix)
+	if os.IsNotExist(err) {
+		return nil, errNoSignature
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature: %w", err)
+	}
+	return data, nil
+}
+
+// readSnapshotSource reads a snapshot file, history ID or URL into memory.
+func readSnapshotSource(source string) ([]byte, error) {
+	if isRemoteSource(source) {
+		fmt.Fprintf(os.Stderr, "  Downloading snapshot from %s...\n", source)
+		data, err := download(source, maxSnapshotSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download snapshot: %w", err)
+		}
+		return data, nil
+	}
+
+	path := resolveSnapshotPath(source)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("snapshot file not found: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+	return data, nil
+}
+
+func isRemoteSource(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// download fetches url, failing if the body is larger than limit bytes.
+func download(url string, limit int64) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("response is larger than %d bytes", limit)
+	}
+	return data, nil
+}
+
+func describeKey(k *snapshot.TrustedKey) string {
+	id := snapshot.KeyID(k.Key)
+	if k.Comment == "" {
+		return id
+	}
+	return fmt.Sprintf("%s (%s)", k.Comment, id)
+}
+
+// showUnsafeFields lists the parts of a snapshot that run third-party code
+// or change system settings, so they're reviewed before installing.
+func showUnsafeFields(snap *snapshot.Snapshot) {
+	var lines []string
+	if len(snap.Packages.Taps) > 0 {
+		lines = append(lines, fmt.Sprintf("Taps (third-party formula sources): %s", strings.Join(snap.Packages.Taps, ", ")))
+	}
+	if snap.Dotfiles.RepoURL != "" {
+		lines = append(lines, fmt.Sprintf("Dotfiles (cloned and linked into your home directory): %s", describeDotfiles(snap.Dotfiles)))
+	}
+	if len(snap.Packages.Npm) > 0 {
+		lines = append(lines, fmt.Sprintf("npm globals (may run install scripts): %d packages", len(snap.Packages.Npm)))
+	}
+	if len(snap.MacOSPrefs) > 0 {
+		lines = append(lines, fmt.Sprintf("macOS preferences: %d changes", len(snap.MacOSPrefs)))
+	}
+	if snap.Git.UserName != "" || snap.Git.UserEmail != "" {
+		lines = append(lines, fmt.Sprintf("Git identity: %s <%s>", snap.Git.UserName, snap.Git.UserEmail))
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, snapWarnStyle.Render("  ⚠ Review before installing:"))
+	for _, l := range lines {
+		fmt.Fprintf(os.Stderr, "    • %s\n", l)
+	}
+	fmt.Fprintln(os.Stderr)
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveSnapshot(t *testing.T, data []byte, sig []byte) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/snap.json":
+			w.Write(data)
+		case "/snap.json.sig":
+			if sig == nil {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(sig)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/snap.json"
+}
+
+func TestVerifySnapshotSource_RemoteRequiresTrust(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	data := []byte(`{"version": 2}`)
+	url := serveSnapshot(t, data, nil)
+
+	err := verifySnapshotSource(url, data, importOptions{})
+	assert.ErrorContains(t, err, "refusing to install")
+
+	assert.NoError(t, verifySnapshotSource(url, data, importOptions{Insecure: true}))
+}
+
+func TestVerifySnapshotSource_RemoteTrustedSignature(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	data := []byte(`{"version": 2}`)
+
+	key, _, err := snapshot.LoadOrCreateSigningKey()
+	require.NoError(t, err)
+	sig, err := json.Marshal(snapshot.Sign(data, key))
+	require.NoError(t, err)
+	url := serveSnapshot(t, data, sig)
+
+	assert.Error(t, verifySnapshotSource(url, data, importOptions{}), "key is not trusted yet")
+
+	require.NoError(t, snapshot.AddTrustedKey(key.Public().(ed25519.PublicKey), "me"))
+	assert.NoError(t, verifySnapshotSource(url, data, importOptions{}))
+	assert.Error(t, verifySnapshotSource(url, []byte(`{"version": 2, "hostname": "x"}`), importOptions{Insecure: false}))
+}
+
+func TestVerifySnapshotSource_SHA256Pin(t *testing.T) {
+	data := []byte(`{"version": 2}`)
+	sum := sha256.Sum256(data)
+
+	assert.NoError(t, verifySnapshotSource("https://example.invalid/s.json", data, importOptions{SHA256: hex.EncodeToString(sum[:])}))
+	assert.ErrorContains(t, verifySnapshotSource("local.json", data, importOptions{SHA256: "00"}), "sha256 mismatch")
+}
+
+func TestVerifySnapshotSource_LocalUnsignedAllowed(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "snap.json")
+	data := []byte(`{"version": 2}`)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+
+	assert.NoError(t, verifySnapshotSource(path, data, importOptions{}))
+
+	require.NoError(t, os.WriteFile(path+snapshot.SignatureSuffix, []byte(`{"key_id": "deadbeef", "signature": "c2ln"}`), 0644))
+	assert.Error(t, verifySnapshotSource(path, data, importOptions{}), "a bad signature is never ignored")
+}
+
+func TestDownload_RejectsOversizedResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 2048))
+	}))
+	defer server.Close()
+
+	_, err := download(server.URL, 1024)
+	assert.ErrorContains(t, err, "larger than")
+
+	data, err := download(server.URL, 4096)
+	require.NoError(t, err)
+	assert.Len(t, data, 2048)
+}
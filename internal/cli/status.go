@@ -0,0 +1,396 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openbootdotdev/openboot/internal/auth"
+	"github.com/openbootdotdev/openboot/internal/brew"
+	"github.com/openbootdotdev/openboot/internal/cleaner"
+	"github.com/openbootdotdev/openboot/internal/config"
+	"github.com/openbootdotdev/openboot/internal/installer"
+	"github.com/openbootdotdev/openboot/internal/macos"
+	"github.com/openbootdotdev/openboot/internal/shell"
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/openbootdotdev/openboot/internal/system"
+	"github.com/openbootdotdev/openboot/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// exitDrift is the exit code used when the machine has drifted from the
+// desired state.
+const exitDrift = 2
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show how this Mac has drifted from your config or snapshot",
+	Long: `Compare this Mac against a desired state and report missing, extra and
+changed items: packages, taps, macOS preferences, shell, git and dotfiles.
+
+Sources (checked in order):
+  1. --from <file|url>     Compare against a snapshot file or URL
+  2. --user <username>     Compare against your openboot.dev config
+  3. Local snapshot         Compare against the latest in ~/.openboot/snapshots
+
+Exits with status 2 when drift is found.
+
+Examples:
+  openboot status                             Check against local snapshot
+  openboot status --user myname               Check against cloud config
+  openboot status --json                      Machine-readable report
+  openboot status --fix                       Install missing and remove extra items`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runStatus(cmd)
+	},
+}
+
+func init() {
+	statusCmd.Flags().String("from", "", "snapshot file or URL to compare against")
+	statusCmd.Flags().String("user", "", "openboot.dev username/slug to compare against")
+	statusCmd.Flags().Bool("json", false, "output the drift report as JSON")
+	statusCmd.Flags().Bool("fix", false, "install missing items and remove extra ones")
+	statusCmd.Flags().Bool("dry-run", false, "with --fix, preview changes without applying them")
+	statusCmd.Flags().String("sha256", "", "with --fix, require the --from snapshot to have this SHA-256 checksum")
+	statusCmd.Flags().Bool("insecure", false, "with --fix, allow an unsigned --from snapshot from a URL")
+}
+
+func runStatus(cmd *cobra.Command) error {
+	fromFile, _ := cmd.Flags().GetString("from")
+	user, _ := cmd.Flags().GetString("user")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	fix, _ := cmd.Flags().GetBool("fix")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	var verify *importOptions
+	if fix {
+		sha, _ := cmd.Flags().GetString("sha256")
+		insecure, _ := cmd.Flags().GetBool("insecure")
+		verify = &importOptions{SHA256: sha, Insecure: insecure}
+	}
+
+	desired, source, err := loadDesiredState(fromFile, user, verify)
+	if err != nil {
+		return err
+	}
+
+	live, err := captureWithUI()
+	if err != nil {
+		return err
+	}
+
+	drift := snapshot.DetectDrift(desired, live)
+	if len(drift.Formulae.Missing) > 0 && brew.IsInstalled() {
+		if installed, _, err := brew.GetInstalledPackages(); err == nil {
+			drift.IgnoreInstalledFormulae(installed)
+		}
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(drift, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal drift report: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printDrift(drift, source)
+	}
+
+	if drift.Count() == 0 {
+		return nil
+	}
+
+	if fix {
+		fixed, err := fixDrift(drift, desired, dryRun)
+		if err != nil {
+			return err
+		}
+		if fixed && !dryRun {
+			return nil
+		}
+	} else if !jsonOutput {
+		ui.Muted("Run 'openboot status --fix' to converge.")
+		fmt.Println()
+	}
+
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return &ExitError{Code: exitDrift}
+}
+
+// loadDesiredState resolves the desired state the same way clean does. A
+// remote config only describes packages, taps and the dotfiles repo. A
+// --from snapshot is checked against verify when it is non-nil, i.e. when
+// it is going to be installed from.
+func loadDesiredState(fromFile, user string, verify *importOptions) (*snapshot.Snapshot, string, error) {
+	switch {
+	case fromFile != "":
+		var snap *snapshot.Snapshot
+		var err error
+		if verify != nil {
+			snap, err = loadVerifiedSnapshot(fromFile, *verify)
+		} else {
+			snap, err = loadSnapshot(fromFile)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return snap, fromFile, nil
+	case user != "":
+		var token string
+		if stored, err := auth.LoadToken(); err == nil && stored != nil {
+			token = stored.Token
+		}
+		rc, err := config.FetchRemoteConfig(user, token)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to fetch remote config: %w", err)
+		}
+		return remoteConfigSnapshot(rc), "@" + user, nil
+	default:
+		snap, err := snapshot.LoadLocal()
+		if err != nil {
+			return nil, "", fmt.Errorf("no local snapshot found — run 'openboot snapshot --local' first, or use --from or --user flags: %w", err)
+		}
+		return snap, snapshot.LocalPath(), nil
+	}
+}
+
+func remoteConfigSnapshot(rc *config.RemoteConfig) *snapshot.Snapshot {
+	return &snapshot.Snapshot{
+		Packages: snapshot.PackageSnapshot{
+			Formulae: rc.Packages,
+			Casks:    rc.Casks,
+			Taps:     rc.Taps,
+			Npm:      rc.Npm,
+		},
+		Dotfiles: snapshot.DotfilesSnapshot{
+			RepoURL: rc.DotfilesRepo,
+			Ref:     rc.DotfilesRef,
+		},
+	}
+}
+
+func printDrift(d *snapshot.Drift, source string) {
+	fmt.Println()
+	ui.Header("OpenBoot Status")
+	fmt.Println()
+	ui.Info(fmt.Sprintf("Comparing against %s", source))
+	fmt.Println()
+
+	if d.Count() == 0 {
+		ui.Success("No drift — this Mac matches the desired state.")
+		fmt.Println()
+		return
+	}
+
+	printPackageDrift("Formulae", d.Formulae)
+	printPackageDrift("Casks", d.Casks)
+	printPackageDrift("Taps", d.Taps)
+	printPackageDrift("npm", d.Npm)
+
+	if len(d.MacOSPrefs) > 0 {
+		fmt.Println(ui.Cyan("macOS Preferences"))
+		for _, p := range d.MacOSPrefs {
+			if p.To == "" {
+				fmt.Printf("  %s %s %s not set (want %s)\n", ui.Red("missing"), p.Domain, p.Key, p.From)
+			} else {
+				fmt.Printf("  %s %s %s = %s (want %s)\n", ui.Yellow("changed"), p.Domain, p.Key, p.To, p.From)
+			}
+		}
+		fmt.Println()
+	}
+
+	if d.Shell.Default != nil || d.Shell.OhMyZsh != nil || d.Shell.Theme != nil || !d.Shell.Plugins.Empty() {
+		fmt.Println(ui.Cyan("Shell"))
+		printDriftValue("default shell", d.Shell.Default)
+		printDriftValue("Oh-My-Zsh", d.Shell.OhMyZsh)
+		printDriftValue("theme", d.Shell.Theme)
+		for _, p := range d.Shell.Plugins.Removed {
+			fmt.Printf("  %s plugin %s\n", ui.Red("missing"), p)
+		}
+		for _, p := range d.Shell.Plugins.Added {
+			fmt.Printf("  %s plugin %s\n", ui.Yellow("extra"), p)
+		}
+		fmt.Println()
+	}
+
+	if d.Git.UserName != nil || d.Git.UserEmail != nil {
+		fmt.Println(ui.Cyan("Git"))
+		printDriftValue("user.name", d.Git.UserName)
+		printDriftValue("user.email", d.Git.UserEmail)
+		fmt.Println()
+	}
+
+	if d.Dotfiles.RepoURL != nil || d.Dotfiles.Ref != nil {
+		fmt.Println(ui.Cyan("Dotfiles"))
+		printDriftValue("repo", d.Dotfiles.RepoURL)
+		printDriftValue("ref", d.Dotfiles.Ref)
+		fmt.Println()
+	}
+
+	if len(d.DevTools) > 0 {
+		fmt.Println(ui.Cyan("Dev Tools"))
+		for _, t := range d.DevTools {
+			if t.To == "" {
+				fmt.Printf("  %s %s (want %s)\n", ui.Red("missing"), t.Name, t.From)
+			} else {
+				fmt.Printf("  %s %s %s (want %s)\n", ui.Yellow("changed"), t.Name, t.To, t.From)
+			}
+		}
+		fmt.Println()
+	}
+
+	ui.Warn(fmt.Sprintf("%d items have drifted", d.Count()))
+	fmt.Println()
+}
+
+func printPackageDrift(title string, d snapshot.PackageDrift) {
+	if d.Count() == 0 {
+		return
+	}
+	fmt.Println(ui.Cyan(title))
+	if len(d.Missing) > 0 {
+		fmt.Printf("  %s %s\n", ui.Red("missing"), strings.Join(d.Missing, ", "))
+	}
+	if len(d.Extra) > 0 {
+		fmt.Printf("  %s %s\n", ui.Yellow("extra"), strings.Join(d.Extra, ", "))
+	}
+	fmt.Println()
+}
+
+func printDriftValue(label string, c *snapshot.ValueChange) {
+	if c == nil {
+		return
+	}
+	live := c.To
+	if live == "" {
+		live = "(not set)"
+	}
+	fmt.Printf("  %s %s: %s (want %s)\n", ui.Yellow("changed"), label, live, c.From)
+}
+
+// fixDrift installs missing packages, removes extra ones and reapplies
+// preferences, shell and git settings from the desired snapshot. Extra taps,
+// dev tool versions and the dotfiles repo are reported but left alone.
+func fixDrift(d *snapshot.Drift, desired *snapshot.Snapshot, dryRun bool) (bool, error) {
+	if !dryRun {
+		proceed, err := ui.Confirm(fmt.Sprintf("Fix %d drifted items?", d.Count()), false)
+		if err != nil {
+			return false, err
+		}
+		if !proceed {
+			ui.Muted("Fix cancelled.")
+			fmt.Println()
+			return false, nil
+		}
+	}
+
+	var failed int
+
+	missing := &snapshot.Snapshot{Packages: snapshot.PackageSnapshot{
+		Formulae: d.Formulae.Missing,
+		Casks:    d.Casks.Missing,
+		Taps:     d.Taps.Missing,
+		Npm:      d.Npm.Missing,
+	}}
+	if len(d.Formulae.Missing)+len(d.Casks.Missing)+len(d.Npm.Missing)+len(d.Taps.Missing) > 0 {
+		if err := installer.InstallPackages(buildImportConfig(missing, dryRun)); err != nil {
+			ui.Error(fmt.Sprintf("Some packages failed to install: %v", err))
+			failed++
+		}
+	}
+
+	extra := &cleaner.CleanResult{
+		ExtraFormulae: d.Formulae.Extra,
+		ExtraCasks:    d.Casks.Extra,
+		ExtraNpm:      d.Npm.Extra,
+	}
+	if extra.TotalExtra() > 0 {
+		// Without a readable protect list nothing is safe to remove.
+		protected, err := cleaner.LoadProtected()
+		if err != nil {
+			ui.Error(fmt.Sprintf("Skipping removals: %v", err))
+			failed++
+			extra = &cleaner.CleanResult{}
+		}
+		extra.Protect(protected)
+	}
+	if extra.TotalExtra() > 0 {
+		if err := cleaner.Execute(extra, dryRun); err != nil {
+			ui.Error(fmt.Sprintf("Some packages failed to remove: %v", err))
+			failed++
+		}
+	}
+
+	if len(d.MacOSPrefs) > 0 {
+		fmt.Println()
+		ui.Header("Restoring macOS preferences")
+		fmt.Println()
+		if err := macos.Configure(driftedPreferences(d.MacOSPrefs), dryRun); err != nil {
+			ui.Error(fmt.Sprintf("Failed to restore preferences: %v", err))
+			failed++
+		}
+	}
+
+	if d.Shell.OhMyZsh != nil || d.Shell.Theme != nil || !d.Shell.Plugins.Empty() {
+		if err := shell.RestoreFromSnapshot(desired.Shell.OhMyZsh, desired.Shell.Theme, desired.Shell.Plugins, dryRun); err != nil {
+			ui.Error(fmt.Sprintf("Failed to restore shell config: %v", err))
+			failed++
+		}
+	}
+
+	if d.Git.UserName != nil || d.Git.UserEmail != nil {
+		name, email := system.GetExistingGitConfig()
+		if d.Git.UserName != nil {
+			name = desired.Git.UserName
+		}
+		if d.Git.UserEmail != nil {
+			email = desired.Git.UserEmail
+		}
+		if dryRun {
+			fmt.Printf("[DRY-RUN] Would set git identity to %s <%s>\n", name, email)
+		} else if err := system.ConfigureGit(name, email); err != nil {
+			ui.Error(fmt.Sprintf("Failed to restore git config: %v", err))
+			failed++
+		}
+	}
+
+	if len(d.Taps.Extra) > 0 || len(d.DevTools) > 0 || d.Dotfiles.RepoURL != nil || d.Dotfiles.Ref != nil {
+		fmt.Println()
+		ui.Muted("Extra taps, dev tool versions and the dotfiles repo must be fixed by hand.")
+	}
+
+	fmt.Println()
+	if dryRun {
+		ui.Muted("Dry run complete — no changes were made.")
+	} else if failed > 0 {
+		ui.Warn(fmt.Sprintf("%d fix steps had failures", failed))
+	} else {
+		ui.Success("Drift fixed!")
+	}
+	fmt.Println()
+	return failed == 0, nil
+}
+
+// driftedPreferences turns drifted prefs back into writable preferences,
+// taking the value type from the known defaults.
+func driftedPreferences(changes []snapshot.PrefChange) []macos.Preference {
+	types := make(map[string]macos.Preference, len(macos.DefaultPreferences))
+	for _, p := range macos.DefaultPreferences {
+		types[p.Domain+" "+p.Key] = p
+	}
+
+	prefs := make([]macos.Preference, 0, len(changes))
+	for _, c := range changes {
+		known := types[c.Domain+" "+c.Key]
+		prefs = append(prefs, macos.Preference{
+			Domain: c.Domain,
+			Key:    c.Key,
+			Type:   known.Type,
+			Value:  c.From,
+			Desc:   known.Desc,
+		})
+	}
+	return prefs
+}
@@ -9,19 +9,29 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var selfUpdate bool
+var selfUpdate, selfRollback bool
 
 var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update Homebrew packages or OpenBoot itself",
 	Long: `Update Homebrew package definitions and upgrade all installed packages.
 
-Use --self to update the OpenBoot binary to the latest release.
+Use --self to update the OpenBoot binary to the latest release. The
+download is checked against the release's checksums.txt (and its
+signature, when the release has one) before it replaces the binary, and
+the previous binary is kept next to it as openboot.prev.
 
 Examples:
-  openboot update          # Update Homebrew packages
-  openboot update --self   # Update OpenBoot itself`,
+  openboot update                    # Update Homebrew packages
+  openboot update --self             # Update OpenBoot itself
+  openboot update --self --rollback  # Go back to the version before the last update`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if selfRollback {
+			if !selfUpdate {
+				return fmt.Errorf("--rollback only works with --self")
+			}
+			return runSelfRollback()
+		}
 		if selfUpdate {
 			return runSelfUpdate()
 		}
@@ -32,6 +42,7 @@ Examples:
 func init() {
 	updateCmd.Flags().BoolVar(&cfg.DryRun, "dry-run", false, "preview without installing or modifying anything")
 	updateCmd.Flags().BoolVar(&selfUpdate, "self", false, "Update OpenBoot binary to latest release")
+	updateCmd.Flags().BoolVar(&selfRollback, "rollback", false, "with --self, restore the binary replaced by the last update")
 }
 
 func runSelfUpdate() error {
@@ -54,6 +65,29 @@ func runSelfUpdate() error {
 
 	fmt.Println()
 	ui.Success("OpenBoot updated to latest version!")
+	ui.Muted("Run 'openboot update --self --rollback' to go back to the previous version.")
+	fmt.Println()
+	return nil
+}
+
+func runSelfRollback() error {
+	if updater.IsHomebrewInstall() {
+		fmt.Println()
+		ui.Info("OpenBoot was installed via Homebrew.")
+		ui.Info("Use 'brew' to change versions.")
+		fmt.Println()
+		return nil
+	}
+
+	fmt.Println()
+	ui.Header("OpenBoot Rollback")
+	fmt.Println()
+
+	if err := updater.Rollback(); err != nil {
+		return err
+	}
+
+	ui.Success("Restored the previous OpenBoot version. Run 'openboot update --self --rollback' again to undo.")
 	fmt.Println()
 	return nil
 }
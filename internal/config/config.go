@@ -43,9 +43,15 @@ type Config struct {
 	RemoteConfig *RemoteConfig
 	PackagesOnly bool
 
-	SnapshotShell    *SnapshotShellConfig
-	SnapshotGit      *SnapshotGitConfig
-	SnapshotDotfiles string
+	DotfilesRepo   string
+	DotfilesRef    string
+	DotfilesSubdir string
+	DotfilesPath   string
+
+	SnapshotShell      *SnapshotShellConfig
+	SnapshotGit        *SnapshotGitConfig
+	SnapshotDotfiles   *SnapshotDotfilesConfig
+	SnapshotMacOSPrefs []SnapshotMacOSPref
 }
 
 type SnapshotShellConfig struct {
@@ -59,16 +65,37 @@ type SnapshotGitConfig struct {
 	UserEmail string
 }
 
+// SnapshotMacOSPref is a macOS preference captured in a snapshot. Type is
+// the defaults(1) value type: bool, int, float or string.
+type SnapshotMacOSPref struct {
+	Domain string
+	Key    string
+	Type   string
+	Value  string
+	Desc   string
+}
+
+type SnapshotDotfilesConfig struct {
+	RepoURL string
+	Ref     string
+	Subdir  string
+	Path    string
+}
+
 type RemoteConfig struct {
-	Username     string   `json:"username"`
-	Slug         string   `json:"slug"`
-	Name         string   `json:"name"`
-	Preset       string   `json:"preset"`
-	Packages     []string `json:"packages"`
-	Casks        []string `json:"casks"`
-	Taps         []string `json:"taps"`
-	Npm          []string `json:"npm"`
-	DotfilesRepo string   `json:"dotfiles_repo"`
+	Username       string   `json:"username"`
+	Slug           string   `json:"slug"`
+	Name           string   `json:"name"`
+	Preset         string   `json:"preset"`
+	Packages       []string `json:"packages"`
+	Casks          []string `json:"casks"`
+	Taps           []string `json:"taps"`
+	Npm            []string `json:"npm"`
+	DotfilesRepo   string   `json:"dotfiles_repo"`
+	DotfilesRef    string   `json:"dotfiles_ref"`
+	DotfilesSubdir string   `json:"dotfiles_subdir"`
+	// Requirements is optional; 'openboot doctor --user' checks it.
+	Requirements *Requirements `json:"requirements,omitempty"`
 }
 
 type Preset struct {
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Requirements is what a team expects every machine to have, checked by
+// 'openboot doctor'.
+type Requirements struct {
+	Commands []CommandRequirement `json:"commands,omitempty" yaml:"commands"`
+	// Env lists environment variables that must be set and non-empty.
+	Env []string `json:"env,omitempty" yaml:"env"`
+	// Files lists paths that must exist. A leading ~/ is the home directory.
+	Files []string `json:"files,omitempty" yaml:"files"`
+}
+
+// CommandRequirement is a command that must be on PATH, optionally at a
+// version matching a range such as ">=20", "^1.22" or "3.12.x".
+type CommandRequirement struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version,omitempty" yaml:"version"`
+}
+
+// Empty reports whether r requires nothing.
+func (r *Requirements) Empty() bool {
+	return r == nil || len(r.Commands) == 0 && len(r.Env) == 0 && len(r.Files) == 0
+}
+
+// LoadRequirements reads the requirements section of a config file. The
+// file may be YAML or JSON:
+//
+//	requirements:
+//	  commands:
+//	    - name: node
+//	      version: ">=20"
+//	  env: [GITHUB_TOKEN]
+//	  files: [~/.ssh/id_ed25519]
+func LoadRequirements(path string) (*Requirements, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read requirements: %w", err)
+	}
+	var file struct {
+		Requirements *Requirements `yaml:"requirements"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse requirements %s: %w", path, err)
+	}
+	if file.Requirements.Empty() {
+		return nil, fmt.Errorf("%s has no requirements section", path)
+	}
+	for _, c := range file.Requirements.Commands {
+		if c.Name == "" {
+			return nil, fmt.Errorf("%s: every required command needs a name", path)
+		}
+	}
+	return file.Requirements, nil
+}
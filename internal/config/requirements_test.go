@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRequirements(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadRequirements_YAML(t *testing.T) {
+	path := writeRequirements(t, "openboot.yaml", `
+requirements:
+  commands:
+    - name: node
+      version: ">=20"
+    - name: jq
+  env: [GITHUB_TOKEN]
+  files: ["~/.ssh/id_ed25519"]
+`)
+
+	req, err := LoadRequirements(path)
+	require.NoError(t, err)
+	assert.Equal(t, []CommandRequirement{{Name: "node", Version: ">=20"}, {Name: "jq"}}, req.Commands)
+	assert.Equal(t, []string{"GITHUB_TOKEN"}, req.Env)
+	assert.Equal(t, []string{"~/.ssh/id_ed25519"}, req.Files)
+}
+
+func TestLoadRequirements_JSON(t *testing.T) {
+	path := writeRequirements(t, "config.json", `{"packages":["git"],"requirements":{"commands":[{"name":"go","version":"^1.22"}]}}`)
+
+	req, err := LoadRequirements(path)
+	require.NoError(t, err)
+	assert.Equal(t, []CommandRequirement{{Name: "go", Version: "^1.22"}}, req.Commands)
+}
+
+func TestLoadRequirements_Errors(t *testing.T) {
+	_, err := LoadRequirements(writeRequirements(t, "a.yaml", "packages: [git]\n"))
+	assert.ErrorContains(t, err, "no requirements section")
+
+	_, err = LoadRequirements(writeRequirements(t, "b.yaml", "requirements:\n  commands:\n    - version: '1'\n"))
+	assert.ErrorContains(t, err, "needs a name")
+
+	_, err = LoadRequirements(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
@@ -6,162 +6,220 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/openbootdotdev/openboot/internal/system"
 )
 
 const defaultDotfilesDir = ".dotfiles"
 
+// Clone clones repoURL, using the recorded ref, subdirectory and path when
+// they were recorded for the same repo.
 func Clone(repoURL string, dryRun bool) error {
 	if repoURL == "" {
 		return nil
 	}
-
-	home, err := system.HomeDir()
+	saved, err := LoadSource()
 	if err != nil {
 		return err
 	}
-	dotfilesPath := filepath.Join(home, defaultDotfilesDir)
-
-	if _, err := os.Stat(dotfilesPath); err == nil {
-		fmt.Printf("Dotfiles already exist at %s, skipping clone\n", dotfilesPath)
-		return nil
+	source := Source{URL: repoURL}
+	if saved.URL == repoURL {
+		source = saved
 	}
+	return CloneSource(source, dryRun)
+}
 
-	if dryRun {
-		fmt.Printf("[DRY-RUN] Would clone %s to %s\n", repoURL, dotfilesPath)
-		return nil
+// RepoPath returns the directory of the cloned repo whose contents are
+// linked into $HOME, taking the recorded path and subdirectory into account.
+func RepoPath() (string, error) {
+	s, err := LoadSource()
+	if err != nil {
+		return "", err
 	}
-
-	cmd := exec.Command("git", "clone", repoURL, dotfilesPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return s.LinkDir()
 }
 
-func Link(dryRun bool) error {
+// Link symlinks the cloned dotfiles into $HOME, renders *.tmpl files and
+// decrypts *.age files.
+// Conflicting paths are reported first and then moved into a timestamped
+// backup directory under ~/.openboot/dotfiles-backup. When unattended is
+// set nothing is prompted for: a missing template variable or secrets
+// passphrase is an error instead.
+func Link(dryRun, unattended bool) error {
 	home, err := system.HomeDir()
 	if err != nil {
 		return err
 	}
-	dotfilesPath := filepath.Join(home, defaultDotfilesDir)
+	dotfilesPath, err := RepoPath()
+	if err != nil {
+		return err
+	}
 
 	if _, err := os.Stat(dotfilesPath); os.IsNotExist(err) {
 		return fmt.Errorf("dotfiles directory not found: %s", dotfilesPath)
 	}
 
-	if hasStowPackages(dotfilesPath) {
-		return linkWithStow(dotfilesPath, dryRun)
+	plan, err := PlanLinks(dotfilesPath, home)
+	if err != nil {
+		return fmt.Errorf("failed to plan dotfile links: %w", err)
 	}
 
-	return linkDirect(dotfilesPath, dryRun)
-}
-
-func hasStowPackages(dotfilesPath string) bool {
-	entries, err := os.ReadDir(dotfilesPath)
+	var renders []TemplateRender
+	prompt := defaultPrompter(unattended)
+	if dryRun {
+		prompt = placeholderPrompter
+	}
+	data, err := LoadTemplateData(prompt)
 	if err != nil {
-		return false
+		return err
+	}
+	if len(plan.Templates) > 0 {
+		renders, err = plan.RenderTemplates(data)
+		if err != nil {
+			return err
+		}
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
-			pkgPath := filepath.Join(dotfilesPath, entry.Name())
-			subEntries, _ := os.ReadDir(pkgPath)
-			for _, sub := range subEntries {
-				if strings.HasPrefix(sub.Name(), ".") {
-					return true
-				}
+	if len(plan.Secrets) > 0 && !dryRun {
+		keys, err := LoadSecretKeys(defaultPasswordPrompter(unattended))
+		if err != nil {
+			return err
+		}
+		secrets, err := plan.DecryptSecrets(keys)
+		if err != nil {
+			return err
+		}
+		renders = append(renders, secrets...)
+	}
+
+	plan.PrintConflicts()
+
+	if dryRun {
+		for _, link := range plan.Pending {
+			fmt.Printf("[DRY-RUN] Would symlink %s -> %s\n", link.Target, link.Source)
+		}
+		for _, c := range plan.Conflicts {
+			fmt.Printf("[DRY-RUN] Would back up %s and symlink %s -> %s\n", c.Path, c.Link.Target, c.Link.Source)
+		}
+		for _, r := range renders {
+			if r.Changed() {
+				fmt.Printf("[DRY-RUN] Would render %s -> %s\n", r.Link.Source, r.Link.Target)
 			}
 		}
+		for _, link := range plan.Secrets {
+			fmt.Printf("[DRY-RUN] Would decrypt %s -> %s\n", link.Source, link.Target)
+		}
+		return nil
 	}
-	return false
-}
 
-func linkWithStow(dotfilesPath string, dryRun bool) error {
-	entries, err := os.ReadDir(dotfilesPath)
-	if err != nil {
+	if err := data.Save(); err != nil {
 		return err
 	}
 
-	home, err := system.HomeDir()
+	manifest, err := LoadManifest()
 	if err != nil {
 		return err
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
-			continue
-		}
-
-		pkg := entry.Name()
-		if dryRun {
-			fmt.Printf("[DRY-RUN] Would stow package: %s\n", pkg)
-			continue
-		}
+	backup := backupDir(home, time.Now())
+	linkErr := plan.Apply(backup, manifest)
+	renderErr := plan.writeRenders(renders, backup, manifest)
+	if err := manifest.Save(); err != nil {
+		return err
+	}
+	if renderErr != nil {
+		return renderErr
+	}
+	return linkErr
+}
 
-		if pkg == "zsh" {
-			zshrc := filepath.Join(home, ".zshrc")
-			zshrcBackup := filepath.Join(home, ".zshrc.pre-oh-my-zsh")
-			os.Remove(zshrc)
-			os.Remove(zshrcBackup)
-		}
+// InputPrompter and PasswordPrompter ask the user for missing template
+// variables and secret passphrases. They are set by the CLI, which owns the
+// terminal UI; Link only uses them when a terminal is attached and it is not
+// running unattended.
+var (
+	InputPrompter    Prompter
+	PasswordPrompter Prompter
+)
 
-		cmd := exec.Command("stow", "-v", "-t", home, pkg)
-		cmd.Dir = dotfilesPath
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("Warning: failed to stow %s: %v\n", pkg, err)
-		}
+func defaultPrompter(unattended bool) Prompter {
+	if unattended || !system.HasTTY() {
+		return nil
 	}
+	return InputPrompter
+}
 
-	return nil
+// placeholderPrompter stands in for unanswered prompts during a dry run so
+// that previews never stop to ask questions.
+func placeholderPrompter(key, _ string) (string, error) {
+	return "<" + key + ">", nil
 }
 
-func linkDirect(dotfilesPath string, dryRun bool) error {
-	home, err := system.HomeDir()
-	if err != nil {
-		return err
+func defaultPasswordPrompter(unattended bool) Prompter {
+	if unattended || !system.HasTTY() {
+		return nil
 	}
+	return PasswordPrompter
+}
+
+func backupDir(home string, now time.Time) string {
+	return filepath.Join(home, ".openboot", "dotfiles-backup", now.Format("20060102-150405"))
+}
 
+func hasStowPackages(dotfilesPath string) bool {
 	entries, err := os.ReadDir(dotfilesPath)
 	if err != nil {
-		return err
+		return false
 	}
 
 	for _, entry := range entries {
-		name := entry.Name()
-		if name == ".git" || name == "README.md" || name == "LICENSE" {
-			continue
-		}
-
-		src := filepath.Join(dotfilesPath, name)
-		dst := filepath.Join(home, name)
-
-		if dryRun {
-			fmt.Printf("[DRY-RUN] Would symlink %s -> %s\n", dst, src)
-			continue
-		}
-
-		if _, err := os.Lstat(dst); err == nil {
-			backupPath := dst + ".openboot.bak"
-			if err := os.Rename(dst, backupPath); err != nil {
-				fmt.Printf("Warning: failed to backup %s: %v\n", dst, err)
-				continue
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			pkgPath := filepath.Join(dotfilesPath, entry.Name())
+			subEntries, _ := os.ReadDir(pkgPath)
+			for _, sub := range subEntries {
+				if strings.HasPrefix(sub.Name(), ".") {
+					return true
+				}
 			}
-			fmt.Printf("Backed up: %s -> %s\n", dst, backupPath)
-		}
-
-		if err := os.Symlink(src, dst); err != nil {
-			fmt.Printf("Warning: failed to symlink %s: %v\n", name, err)
-		} else {
-			fmt.Printf("Linked: %s -> %s\n", dst, src)
 		}
 	}
-
-	return nil
+	return false
 }
 
 func GetDotfilesURL() string {
 	return os.Getenv("OPENBOOT_DOTFILES")
 }
+
+// RepoFileFor returns where the file at path (inside home) belongs in the
+// repo. Stow-style repos need a package name; other repos mirror $HOME.
+func RepoFileFor(repo, home, path, pkg string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(home, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%s is not inside your home directory", path)
+	}
+
+	if pkg != "" {
+		return filepath.Join(repo, pkg, rel), nil
+	}
+	if hasStowPackages(repo) {
+		return "", fmt.Errorf("%s uses stow-style packages; choose one with --package", repo)
+	}
+	return filepath.Join(repo, rel), nil
+}
+
+// Commit stages paths in the dotfiles repo and commits them.
+func Commit(repo, message string, paths ...string) error {
+	args := append([]string{"-C", repo, "add", "--"}, paths...)
+	if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("git add failed: %s", strings.TrimSpace(string(output)))
+	}
+	if output, err := exec.Command("git", "-C", repo, "commit", "-m", message).CombinedOutput(); err != nil {
+		return fmt.Errorf("git commit failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
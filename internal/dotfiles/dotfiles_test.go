@@ -49,13 +49,17 @@ func TestClone_AlreadyExists(t *testing.T) {
 
 	err = Clone("https://github.com/user/dotfiles", false)
 	assert.NoError(t, err)
+
+	saved, err := LoadSource()
+	require.NoError(t, err)
+	assert.Empty(t, saved.URL, "a directory without an origin is not recorded as the clone")
 }
 
 func TestLink_DotfilesDirNotExist(t *testing.T) {
 	tmpHome := t.TempDir()
 	t.Setenv("HOME", tmpHome)
 
-	err := Link(false)
+	err := Link(false, false)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not found")
 }
@@ -72,7 +76,7 @@ func TestLink_DryRun(t *testing.T) {
 	err = os.WriteFile(testFile, []byte("test"), 0644)
 	require.NoError(t, err)
 
-	err = Link(true)
+	err = Link(true, false)
 	assert.NoError(t, err)
 
 	linkedFile := filepath.Join(tmpHome, ".vimrc")
@@ -121,98 +125,3 @@ func TestHasStowPackages_HiddenDirIgnored(t *testing.T) {
 	result := hasStowPackages(tmpDir)
 	assert.False(t, result)
 }
-
-func TestLinkDirect_DryRun(t *testing.T) {
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-
-	dotfilesPath := filepath.Join(tmpHome, defaultDotfilesDir)
-	err := os.MkdirAll(dotfilesPath, 0755)
-	require.NoError(t, err)
-
-	testFile := filepath.Join(dotfilesPath, ".bashrc")
-	err = os.WriteFile(testFile, []byte("test"), 0644)
-	require.NoError(t, err)
-
-	err = linkDirect(dotfilesPath, true)
-	assert.NoError(t, err)
-
-	linkedFile := filepath.Join(tmpHome, ".bashrc")
-	_, err = os.Lstat(linkedFile)
-	assert.True(t, os.IsNotExist(err))
-}
-
-func TestLinkDirect_SkipsGitDir(t *testing.T) {
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-
-	dotfilesPath := filepath.Join(tmpHome, defaultDotfilesDir)
-	gitDir := filepath.Join(dotfilesPath, ".git")
-	err := os.MkdirAll(gitDir, 0755)
-	require.NoError(t, err)
-
-	err = linkDirect(dotfilesPath, true)
-	assert.NoError(t, err)
-}
-
-func TestLinkDirect_SkipsREADME(t *testing.T) {
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-
-	dotfilesPath := filepath.Join(tmpHome, defaultDotfilesDir)
-	err := os.MkdirAll(dotfilesPath, 0755)
-	require.NoError(t, err)
-
-	readme := filepath.Join(dotfilesPath, "README.md")
-	err = os.WriteFile(readme, []byte("test"), 0644)
-	require.NoError(t, err)
-
-	err = linkDirect(dotfilesPath, true)
-	assert.NoError(t, err)
-}
-
-func TestLinkWithStow_DryRun(t *testing.T) {
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-
-	dotfilesPath := filepath.Join(tmpHome, defaultDotfilesDir)
-	vimPkg := filepath.Join(dotfilesPath, "vim")
-	err := os.MkdirAll(vimPkg, 0755)
-	require.NoError(t, err)
-
-	vimrc := filepath.Join(vimPkg, ".vimrc")
-	err = os.WriteFile(vimrc, []byte("test"), 0644)
-	require.NoError(t, err)
-
-	err = linkWithStow(dotfilesPath, true)
-	assert.NoError(t, err)
-}
-
-func TestLinkWithStow_SkipsHiddenDirs(t *testing.T) {
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-
-	dotfilesPath := filepath.Join(tmpHome, defaultDotfilesDir)
-	hiddenDir := filepath.Join(dotfilesPath, ".hidden")
-	err := os.MkdirAll(hiddenDir, 0755)
-	require.NoError(t, err)
-
-	err = linkWithStow(dotfilesPath, true)
-	assert.NoError(t, err)
-}
-
-func TestLinkWithStow_SkipsFiles(t *testing.T) {
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-
-	dotfilesPath := filepath.Join(tmpHome, defaultDotfilesDir)
-	err := os.MkdirAll(dotfilesPath, 0755)
-	require.NoError(t, err)
-
-	file := filepath.Join(dotfilesPath, "file.txt")
-	err = os.WriteFile(file, []byte("test"), 0644)
-	require.NoError(t, err)
-
-	err = linkWithStow(dotfilesPath, true)
-	assert.NoError(t, err)
-}
@@ -0,0 +1,361 @@
+package dotfiles
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IgnoreFileName is the file at the root of a dotfiles repo listing glob
+// patterns that must never be linked into the home directory.
+const IgnoreFileName = ".openbootignore"
+
+// defaultIgnores are repo housekeeping files that are never dotfiles.
+var defaultIgnores = []string{
+	".git",
+	".github",
+	".gitignore",
+	".gitmodules",
+	".DS_Store",
+	IgnoreFileName,
+	"README*",
+	"LICENSE*",
+	"Makefile",
+}
+
+type ConflictKind string
+
+const (
+	ConflictExistingFile   ConflictKind = "existing file"
+	ConflictExistingDir    ConflictKind = "existing directory"
+	ConflictForeignSymlink ConflictKind = "foreign symlink"
+)
+
+// FileLink maps a file inside the dotfiles repo to its location in $HOME.
+type FileLink struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// Conflict is a path in $HOME that has to be moved aside before Link can
+// create its symlink. Path is usually Link.Target, but can be a parent
+// directory of it that exists as a regular file.
+type Conflict struct {
+	Link   FileLink
+	Path   string
+	Kind   ConflictKind
+	Detail string
+}
+
+// LinkPlan is the full set of changes Link would make, computed without
+// touching the filesystem.
+type LinkPlan struct {
+	RepoDir   string
+	Home      string
+	Pending   []FileLink
+	Linked    []FileLink
+	Conflicts []Conflict
+	Templates []FileLink
+	Secrets   []FileLink
+}
+
+// PlanLinks walks repoDir and works out which symlinks are needed in home.
+// Stow-style repos (top-level package directories containing dotfiles) are
+// linked per package; otherwise the repo root is treated as a single package.
+// Files are linked individually so existing directories like ~/.config are
+// merged into rather than replaced. Template and encrypted files are
+// collected separately in Templates and Secrets, with the suffix stripped
+// from their target.
+func PlanLinks(repoDir, home string) (*LinkPlan, error) {
+	ignores, err := loadIgnorePatterns(repoDir)
+	if err != nil {
+		return nil, err
+	}
+
+	roots, err := packageRoots(repoDir, ignores)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &LinkPlan{RepoDir: repoDir, Home: home}
+	for _, root := range roots {
+		if err := plan.addPackage(root, ignores); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+func packageRoots(repoDir string, ignores []string) ([]string, error) {
+	if !hasStowPackages(repoDir) {
+		return []string{repoDir}, nil
+	}
+
+	entries, err := os.ReadDir(repoDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || strings.HasPrefix(name, ".") || isIgnored(name, ignores) {
+			continue
+		}
+		roots = append(roots, filepath.Join(repoDir, name))
+	}
+	return roots, nil
+}
+
+func (p *LinkPlan) addPackage(root string, ignores []string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		repoRel, err := filepath.Rel(p.RepoDir, path)
+		if err != nil {
+			return err
+		}
+		if isIgnored(repoRel, ignores) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if isTemplate(rel) {
+			target := filepath.Join(p.Home, strings.TrimSuffix(rel, TemplateSuffix))
+			p.Templates = append(p.Templates, FileLink{Source: path, Target: target})
+			return nil
+		}
+		if isEncrypted(rel) {
+			target := filepath.Join(p.Home, strings.TrimSuffix(rel, EncryptedSuffix))
+			p.Secrets = append(p.Secrets, FileLink{Source: path, Target: target})
+			return nil
+		}
+		p.addLink(FileLink{Source: path, Target: filepath.Join(p.Home, rel)})
+		return nil
+	})
+}
+
+func (p *LinkPlan) addLink(link FileLink) {
+	if blocker := blockingParent(p.Home, link.Target); blocker != "" {
+		p.Conflicts = append(p.Conflicts, Conflict{
+			Link:   link,
+			Path:   blocker,
+			Kind:   ConflictExistingFile,
+			Detail: "parent path is not a directory",
+		})
+		return
+	}
+
+	info, err := os.Lstat(link.Target)
+	if os.IsNotExist(err) {
+		p.Pending = append(p.Pending, link)
+		return
+	}
+	if err != nil {
+		p.Conflicts = append(p.Conflicts, Conflict{Link: link, Path: link.Target, Kind: ConflictExistingFile, Detail: err.Error()})
+		return
+	}
+
+	if sameFile(link.Source, link.Target) {
+		p.Linked = append(p.Linked, link)
+		return
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		dest, _ := os.Readlink(link.Target)
+		p.Conflicts = append(p.Conflicts, Conflict{Link: link, Path: link.Target, Kind: ConflictForeignSymlink, Detail: dest})
+	case info.IsDir():
+		p.Conflicts = append(p.Conflicts, Conflict{Link: link, Path: link.Target, Kind: ConflictExistingDir})
+	default:
+		p.Conflicts = append(p.Conflicts, Conflict{Link: link, Path: link.Target, Kind: ConflictExistingFile})
+	}
+}
+
+// blockingParent returns the first ancestor of target below home that exists
+// but is not a directory, or "" if the parent chain is usable.
+func blockingParent(home, target string) string {
+	rel, err := filepath.Rel(home, filepath.Dir(target))
+	if err != nil || rel == "." {
+		return ""
+	}
+
+	current := home
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Stat(current)
+		if os.IsNotExist(err) {
+			return ""
+		}
+		if err == nil && !info.IsDir() {
+			return current
+		}
+	}
+	return ""
+}
+
+// sameFile reports whether target already resolves to source, either as a
+// direct symlink or through a symlinked parent directory.
+func sameFile(source, target string) bool {
+	resolvedTarget, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return false
+	}
+	resolvedSource, err := filepath.EvalSymlinks(source)
+	if err != nil {
+		return false
+	}
+	return resolvedTarget == resolvedSource
+}
+
+// Apply moves every conflicting path into backupDir, keeping its path
+// relative to home, and then creates all pending symlinks. Every change is
+// recorded in m.
+func (p *LinkPlan) Apply(backupDir string, m *Manifest) error {
+	backedUp := make(map[string]bool)
+	for _, c := range p.Conflicts {
+		if backedUp[c.Path] {
+			continue
+		}
+		dst, err := backupPath(p.Home, c.Path, backupDir)
+		if err != nil {
+			return err
+		}
+		backedUp[c.Path] = true
+		m.addBackup(c.Path, dst)
+		fmt.Printf("Backed up: %s -> %s\n", c.Path, dst)
+	}
+	for _, link := range p.Linked {
+		m.addLink(link)
+	}
+
+	links := append([]FileLink{}, p.Pending...)
+	for _, c := range p.Conflicts {
+		links = append(links, c.Link)
+	}
+
+	var failed []string
+	for _, link := range links {
+		if err := m.mkdirAll(p.Home, filepath.Dir(link.Target)); err != nil {
+			fmt.Printf("Warning: failed to create %s: %v\n", filepath.Dir(link.Target), err)
+			failed = append(failed, link.Target)
+			continue
+		}
+		if err := os.Symlink(link.Source, link.Target); err != nil {
+			fmt.Printf("Warning: failed to symlink %s: %v\n", link.Target, err)
+			failed = append(failed, link.Target)
+			continue
+		}
+		m.addLink(link)
+		fmt.Printf("Linked: %s -> %s\n", link.Target, link.Source)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d dotfiles failed to link", len(failed))
+	}
+	return nil
+}
+
+func backupPath(home, path, backupDir string) (string, error) {
+	rel, err := filepath.Rel(home, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	dst := filepath.Join(backupDir, rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := os.Rename(path, dst); err != nil {
+		return "", fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	return dst, nil
+}
+
+// PrintConflicts writes a human-readable conflict report to stdout.
+func (p *LinkPlan) PrintConflicts() {
+	if len(p.Conflicts) == 0 {
+		return
+	}
+
+	conflicts := append([]Conflict{}, p.Conflicts...)
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Path < conflicts[j].Path })
+
+	fmt.Printf("Found %d conflicting paths in %s:\n", len(conflicts), p.Home)
+	for _, c := range conflicts {
+		if c.Detail != "" {
+			fmt.Printf("  %s: %s (%s)\n", c.Kind, c.Path, c.Detail)
+		} else {
+			fmt.Printf("  %s: %s\n", c.Kind, c.Path)
+		}
+	}
+}
+
+func loadIgnorePatterns(repoDir string) ([]string, error) {
+	patterns := append([]string{}, defaultIgnores...)
+
+	f, err := os.Open(filepath.Join(repoDir, IgnoreFileName))
+	if os.IsNotExist(err) {
+		return patterns, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFileName, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, strings.TrimSuffix(line, "/"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFileName, err)
+	}
+	return patterns, nil
+}
+
+// isIgnored matches rel (relative to the repo root) against the patterns.
+// Patterns containing a slash are anchored to the repo root; all others
+// match any single path component, like .gitignore.
+func isIgnored(rel string, patterns []string) bool {
+	rel = filepath.ToSlash(rel)
+	parts := strings.Split(rel, "/")
+
+	for _, pattern := range patterns {
+		if strings.Contains(pattern, "/") {
+			pattern = strings.TrimPrefix(pattern, "/")
+			if ok, _ := filepath.Match(pattern, rel); ok {
+				return true
+			}
+			if strings.HasPrefix(rel, pattern+"/") {
+				return true
+			}
+			continue
+		}
+		for _, part := range parts {
+			if ok, _ := filepath.Match(pattern, part); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
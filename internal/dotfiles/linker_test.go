@@ -0,0 +1,196 @@
+package dotfiles
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func targets(links []FileLink) []string {
+	var out []string
+	for _, l := range links {
+		out = append(out, l.Target)
+	}
+	return out
+}
+
+func TestPlanLinks_DirectLayout(t *testing.T) {
+	home := t.TempDir()
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(repo, ".vimrc"), "set nu")
+	writeFile(t, filepath.Join(repo, ".config", "nvim", "init.lua"), "--")
+	writeFile(t, filepath.Join(repo, "README.md"), "readme")
+	writeFile(t, filepath.Join(repo, "Makefile"), "all:")
+	writeFile(t, filepath.Join(repo, ".github", "workflows", "ci.yml"), "on: push")
+	writeFile(t, filepath.Join(repo, ".git", "HEAD"), "ref")
+
+	plan, err := PlanLinks(repo, home)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		filepath.Join(home, ".vimrc"),
+		filepath.Join(home, ".config", "nvim", "init.lua"),
+	}, targets(plan.Pending))
+	assert.Empty(t, plan.Conflicts)
+}
+
+func TestPlanLinks_StowLayout(t *testing.T) {
+	home := t.TempDir()
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(repo, "vim", ".vimrc"), "set nu")
+	writeFile(t, filepath.Join(repo, "git", ".gitconfig"), "[user]")
+	writeFile(t, filepath.Join(repo, "README.md"), "readme")
+
+	plan, err := PlanLinks(repo, home)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		filepath.Join(home, ".vimrc"),
+		filepath.Join(home, ".gitconfig"),
+	}, targets(plan.Pending))
+}
+
+func TestPlanLinks_IgnoreFile(t *testing.T) {
+	home := t.TempDir()
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(repo, ".vimrc"), "set nu")
+	writeFile(t, filepath.Join(repo, ".zshrc"), "export")
+	writeFile(t, filepath.Join(repo, "scripts", "install.sh"), "#!/bin/sh")
+	writeFile(t, filepath.Join(repo, ".config", "secret", "token"), "x")
+	writeFile(t, filepath.Join(repo, IgnoreFileName), "# comment\n\nscripts/\n.config/secret\n*.sh\n.zshrc\n")
+
+	plan, err := PlanLinks(repo, home)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{filepath.Join(home, ".vimrc")}, targets(plan.Pending))
+}
+
+func TestPlanLinks_Conflicts(t *testing.T) {
+	home := t.TempDir()
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(repo, ".vimrc"), "new")
+	writeFile(t, filepath.Join(repo, ".zshrc"), "new")
+	writeFile(t, filepath.Join(repo, ".tmux.conf"), "new")
+	writeFile(t, filepath.Join(repo, ".gitconfig"), "new")
+	writeFile(t, filepath.Join(repo, ".local", "bin", "tool"), "new")
+
+	writeFile(t, filepath.Join(home, ".vimrc"), "old")
+	other := filepath.Join(t.TempDir(), "zshrc")
+	writeFile(t, other, "other")
+	require.NoError(t, os.Symlink(other, filepath.Join(home, ".zshrc")))
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".tmux.conf"), 0755))
+	require.NoError(t, os.Symlink(filepath.Join(repo, ".gitconfig"), filepath.Join(home, ".gitconfig")))
+	writeFile(t, filepath.Join(home, ".local"), "not a dir")
+
+	plan, err := PlanLinks(repo, home)
+	require.NoError(t, err)
+
+	assert.Empty(t, plan.Pending)
+	assert.Equal(t, []string{filepath.Join(home, ".gitconfig")}, targets(plan.Linked))
+
+	kinds := map[string]ConflictKind{}
+	for _, c := range plan.Conflicts {
+		kinds[c.Path] = c.Kind
+	}
+	assert.Equal(t, map[string]ConflictKind{
+		filepath.Join(home, ".vimrc"):     ConflictExistingFile,
+		filepath.Join(home, ".zshrc"):     ConflictForeignSymlink,
+		filepath.Join(home, ".tmux.conf"): ConflictExistingDir,
+		filepath.Join(home, ".local"):     ConflictExistingFile,
+	}, kinds)
+}
+
+func TestPlanLinks_SymlinkedParentAlreadyLinked(t *testing.T) {
+	home := t.TempDir()
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(repo, ".config", "nvim", "init.lua"), "--")
+	require.NoError(t, os.Symlink(filepath.Join(repo, ".config"), filepath.Join(home, ".config")))
+
+	plan, err := PlanLinks(repo, home)
+	require.NoError(t, err)
+
+	assert.Empty(t, plan.Pending)
+	assert.Empty(t, plan.Conflicts)
+	assert.Len(t, plan.Linked, 1)
+}
+
+func TestLinkPlan_ApplyBacksUpConflicts(t *testing.T) {
+	home := t.TempDir()
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(repo, ".vimrc"), "new")
+	writeFile(t, filepath.Join(repo, ".config", "git", "ignore"), "*.log")
+	writeFile(t, filepath.Join(home, ".vimrc"), "old")
+
+	plan, err := PlanLinks(repo, home)
+	require.NoError(t, err)
+
+	backup := backupDir(home, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	require.NoError(t, plan.Apply(backup, &Manifest{}))
+
+	dest, err := os.Readlink(filepath.Join(home, ".vimrc"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repo, ".vimrc"), dest)
+
+	dest, err = os.Readlink(filepath.Join(home, ".config", "git", "ignore"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repo, ".config", "git", "ignore"), dest)
+
+	assert.Equal(t, filepath.Join(home, ".openboot", "dotfiles-backup", "20240102-030405"), backup)
+	data, err := os.ReadFile(filepath.Join(backup, ".vimrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(data))
+}
+
+func TestLink_DoesNotTouchHomeOnDryRun(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(repo, ".vimrc"), "new")
+	writeFile(t, filepath.Join(home, ".vimrc"), "old")
+
+	require.NoError(t, Link(true, false))
+
+	data, err := os.ReadFile(filepath.Join(home, ".vimrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(data))
+	_, err = os.Stat(filepath.Join(home, ".openboot"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestIsIgnored(t *testing.T) {
+	patterns := []string{".git", "README*", "scripts/", "docs/*.txt"}
+	for i := range patterns {
+		patterns[i] = filepath.Clean(patterns[i])
+	}
+
+	tests := []struct {
+		rel      string
+		expected bool
+	}{
+		{".git", true},
+		{".git/HEAD", true},
+		{"README.md", true},
+		{"vim/README", true},
+		{"scripts", true},
+		{"scripts/install.sh", true},
+		{"docs/notes.txt", true},
+		{"docs/notes.md", false},
+		{".vimrc", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rel, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isIgnored(tt.rel, patterns))
+		})
+	}
+}
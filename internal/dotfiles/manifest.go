@@ -0,0 +1,351 @@
+package dotfiles
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/openbootdotdev/openboot/internal/system"
+)
+
+const manifestFile = "dotfiles-manifest.json"
+
+// Backup records where a path that was in the way of a link was moved to.
+type Backup struct {
+	Path   string `json:"path"`
+	Backup string `json:"backup"`
+}
+
+// RenderedFile is a generated file openboot wrote into $HOME, with the
+// sha256 of the content it wrote so later edits by the user can be detected.
+type RenderedFile struct {
+	FileLink
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Manifest records everything Link has changed in $HOME so that it can be
+// reported on and undone later. Dirs lists the directories Link had to
+// create; only those are removed again when they become empty.
+type Manifest struct {
+	Links    []FileLink     `json:"links"`
+	Rendered []RenderedFile `json:"rendered"`
+	Backups  []Backup       `json:"backups"`
+	Dirs     []string       `json:"dirs,omitempty"`
+}
+
+// ManifestPath returns the location of the link manifest.
+func ManifestPath() (string, error) {
+	home, err := system.HomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".openboot", manifestFile), nil
+}
+
+// LoadManifest reads the link manifest, returning an empty one if Link has
+// never run.
+func LoadManifest() (*Manifest, error) {
+	path, err := ManifestPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dotfiles manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse dotfiles manifest: %w", err)
+	}
+	return &m, nil
+}
+
+func (m *Manifest) Save() error {
+	path, err := ManifestPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal dotfiles manifest: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write dotfiles manifest: %w", err)
+	}
+	return nil
+}
+
+func (m *Manifest) addLink(link FileLink) {
+	m.Links = upsertLink(m.Links, link)
+}
+
+func (m *Manifest) addRendered(link FileLink, content []byte) {
+	r := RenderedFile{FileLink: link, SHA256: contentHash(content)}
+	for i, existing := range m.Rendered {
+		if existing.Target == link.Target {
+			m.Rendered[i] = r
+			return
+		}
+	}
+	m.Rendered = append(m.Rendered, r)
+}
+
+func (m *Manifest) isRendered(target string) bool {
+	for _, r := range m.Rendered {
+		if r.Target == target {
+			return true
+		}
+	}
+	return false
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// mkdirAll creates dir like os.MkdirAll and records every directory below
+// home that did not exist before.
+func (m *Manifest) mkdirAll(home, dir string) error {
+	var missing []string
+	for d := dir; len(d) > len(home) && d != home; d = filepath.Dir(d) {
+		if _, err := os.Lstat(d); err == nil {
+			break
+		}
+		missing = append(missing, d)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, d := range missing {
+		m.addDir(d)
+	}
+	return nil
+}
+
+func (m *Manifest) addDir(dir string) {
+	for _, d := range m.Dirs {
+		if d == dir {
+			return
+		}
+	}
+	m.Dirs = append(m.Dirs, dir)
+}
+
+func (m *Manifest) createdDir(dir string) bool {
+	for _, d := range m.Dirs {
+		if d == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// addBackup keeps the first backup recorded for a path, since that is the
+// user's original file rather than an earlier openboot render.
+func (m *Manifest) addBackup(path, backup string) {
+	for _, b := range m.Backups {
+		if b.Path == path {
+			return
+		}
+	}
+	m.Backups = append(m.Backups, Backup{Path: path, Backup: backup})
+}
+
+func (m *Manifest) removeLink(target string) {
+	m.Links = removeTarget(m.Links, target)
+}
+
+func upsertLink(links []FileLink, link FileLink) []FileLink {
+	for i, l := range links {
+		if l.Target == link.Target {
+			links[i] = link
+			return links
+		}
+	}
+	return append(links, link)
+}
+
+func removeTarget(links []FileLink, target string) []FileLink {
+	out := links[:0]
+	for _, l := range links {
+		if l.Target != target {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// BrokenLinks returns recorded links whose source has disappeared from the
+// repo or whose target no longer points at the source.
+func (m *Manifest) BrokenLinks() []FileLink {
+	var broken []FileLink
+	for _, l := range m.Links {
+		dest, err := os.Readlink(l.Target)
+		if err != nil {
+			continue
+		}
+		if dest != l.Source {
+			continue
+		}
+		if _, err := os.Stat(l.Source); os.IsNotExist(err) {
+			broken = append(broken, l)
+		}
+	}
+	return broken
+}
+
+// PruneBroken removes symlinks whose source was deleted from the repo.
+func (m *Manifest) PruneBroken(home string, dryRun bool) []FileLink {
+	broken := m.BrokenLinks()
+	for _, l := range broken {
+		if dryRun {
+			fmt.Printf("[DRY-RUN] Would remove broken link %s\n", l.Target)
+			continue
+		}
+		if err := os.Remove(l.Target); err != nil {
+			fmt.Printf("Warning: failed to remove %s: %v\n", l.Target, err)
+			continue
+		}
+		m.removeEmptyParents(home, l.Target)
+		m.removeLink(l.Target)
+		fmt.Printf("Removed broken link: %s\n", l.Target)
+	}
+	return broken
+}
+
+// Unlink removes every link and generated file recorded in the manifest and
+// moves backed-up files back into place. Links that were replaced by
+// something else since, and generated files the user has edited, are left
+// alone.
+func (m *Manifest) Unlink(home string, dryRun bool) error {
+	var failed int
+
+	for _, l := range m.Links {
+		dest, err := os.Readlink(l.Target)
+		if err != nil || dest != l.Source {
+			continue
+		}
+		if dryRun {
+			fmt.Printf("[DRY-RUN] Would remove link %s\n", l.Target)
+			continue
+		}
+		if err := os.Remove(l.Target); err != nil {
+			fmt.Printf("Warning: failed to remove %s: %v\n", l.Target, err)
+			failed++
+			continue
+		}
+		m.removeEmptyParents(home, l.Target)
+		fmt.Printf("Unlinked: %s\n", l.Target)
+	}
+
+	for _, r := range m.Rendered {
+		info, err := os.Lstat(r.Target)
+		if err != nil {
+			continue
+		}
+		if !info.Mode().IsRegular() {
+			fmt.Printf("Warning: %s is no longer a generated file, leaving it in place\n", r.Target)
+			continue
+		}
+		content, err := os.ReadFile(r.Target)
+		if err != nil {
+			fmt.Printf("Warning: failed to read %s: %v\n", r.Target, err)
+			failed++
+			continue
+		}
+		if r.SHA256 == "" || contentHash(content) != r.SHA256 {
+			fmt.Printf("Warning: %s was modified since it was rendered, leaving it in place\n", r.Target)
+			continue
+		}
+		if dryRun {
+			fmt.Printf("[DRY-RUN] Would remove rendered file %s\n", r.Target)
+			continue
+		}
+		if err := os.Remove(r.Target); err != nil {
+			fmt.Printf("Warning: failed to remove %s: %v\n", r.Target, err)
+			failed++
+			continue
+		}
+		m.removeEmptyParents(home, r.Target)
+		fmt.Printf("Removed: %s\n", r.Target)
+	}
+
+	var kept []Backup
+	for _, b := range m.Backups {
+		if dryRun {
+			fmt.Printf("[DRY-RUN] Would restore %s from %s\n", b.Path, b.Backup)
+			continue
+		}
+		if _, err := os.Lstat(b.Backup); err != nil {
+			continue
+		}
+		if _, err := os.Lstat(b.Path); err == nil {
+			fmt.Printf("Warning: %s exists, leaving backup at %s\n", b.Path, b.Backup)
+			kept = append(kept, b)
+			failed++
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(b.Path), 0755); err != nil {
+			kept = append(kept, b)
+			failed++
+			continue
+		}
+		if err := os.Rename(b.Backup, b.Path); err != nil {
+			fmt.Printf("Warning: failed to restore %s: %v\n", b.Path, err)
+			kept = append(kept, b)
+			failed++
+			continue
+		}
+		fmt.Printf("Restored: %s\n", b.Path)
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	m.Links = nil
+	m.Rendered = nil
+	m.Backups = kept
+
+	if failed > 0 {
+		return fmt.Errorf("%d dotfiles could not be unlinked or restored", failed)
+	}
+	return nil
+}
+
+// removeEmptyParents deletes directories left empty by removing path, up to
+// but not including home. Only directories Link created are removed;
+// directories that existed before are left even when empty.
+func (m *Manifest) removeEmptyParents(home, path string) {
+	dir := filepath.Dir(path)
+	for dir != home && len(dir) > len(home) && m.createdDir(dir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		m.removeDir(dir)
+		dir = filepath.Dir(dir)
+	}
+}
+
+func (m *Manifest) removeDir(dir string) {
+	out := m.Dirs[:0]
+	for _, d := range m.Dirs {
+		if d != dir {
+			out = append(out, d)
+		}
+	}
+	m.Dirs = out
+}
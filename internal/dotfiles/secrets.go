@@ -0,0 +1,194 @@
+package dotfiles
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/openbootdotdev/openboot/internal/system"
+)
+
+// EncryptedSuffix marks age-encrypted files in the dotfiles repo. They are
+// decrypted to their target with 0600 permissions instead of being linked.
+const EncryptedSuffix = ".age"
+
+const (
+	secretKeyFile       = "dotfiles.key"
+	passphraseEnv       = "OPENBOOT_DOTFILES_PASSPHRASE"
+	secretFileMode      = os.FileMode(0600)
+	secretKeyFileHeader = "# openboot dotfiles key, keep this file private\n"
+)
+
+// SecretKeys holds what is needed to encrypt and decrypt dotfile secrets:
+// either an X25519 identity from the local key file or a passphrase.
+type SecretKeys struct {
+	identities []age.Identity
+	recipients []age.Recipient
+}
+
+func isEncrypted(path string) bool {
+	return strings.HasSuffix(path, EncryptedSuffix)
+}
+
+// SecretKeyPath returns the location of the local age identity file.
+func SecretKeyPath() (string, error) {
+	home, err := system.HomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".openboot", secretKeyFile), nil
+}
+
+// GenerateSecretKey creates a new X25519 identity at SecretKeyPath and
+// returns its public recipient string. An existing key is never replaced.
+func GenerateSecretKey() (string, error) {
+	path, err := SecretKeyPath()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("key already exists: %s", path)
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	content := secretKeyFileHeader + "# public key: " + identity.Recipient().String() + "\n" + identity.String() + "\n"
+	if err := os.WriteFile(path, []byte(content), secretFileMode); err != nil {
+		return "", fmt.Errorf("failed to write key: %w", err)
+	}
+	return identity.Recipient().String(), nil
+}
+
+// LoadSecretKeys reads the local key file if there is one. Otherwise it uses
+// the passphrase from OPENBOOT_DOTFILES_PASSPHRASE, or asks prompt for it.
+func LoadSecretKeys(prompt Prompter) (*SecretKeys, error) {
+	path, err := SecretKeyPath()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err == nil {
+		defer f.Close()
+		identities, err := age.ParseIdentities(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		keys := &SecretKeys{identities: identities}
+		for _, id := range identities {
+			if x, ok := id.(*age.X25519Identity); ok {
+				keys.recipients = append(keys.recipients, x.Recipient())
+			}
+		}
+		return keys, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	passphrase := os.Getenv(passphraseEnv)
+	if passphrase == "" && prompt != nil {
+		passphrase, err = prompt("passphrase", "Dotfiles secrets passphrase")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("no secrets key: create %s with 'openboot dotfiles keygen' or set %s", path, passphraseEnv)
+	}
+	return NewPassphraseKeys(passphrase)
+}
+
+// NewPassphraseKeys returns keys that encrypt and decrypt with passphrase.
+func NewPassphraseKeys(passphrase string) (*SecretKeys, error) {
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretKeys{identities: []age.Identity{identity}, recipients: []age.Recipient{recipient}}, nil
+}
+
+func (k *SecretKeys) Encrypt(plaintext []byte) ([]byte, error) {
+	if len(k.recipients) == 0 {
+		return nil, fmt.Errorf("no recipients available for encryption")
+	}
+
+	var out bytes.Buffer
+	w, err := age.Encrypt(&out, k.recipients...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func (k *SecretKeys) Decrypt(ciphertext []byte) ([]byte, error) {
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), k.identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// DecryptSecrets decrypts every encrypted file in the plan and reads the
+// current content of each target.
+func (p *LinkPlan) DecryptSecrets(keys *SecretKeys) ([]TemplateRender, error) {
+	var renders []TemplateRender
+	for _, link := range p.Secrets {
+		ciphertext, err := os.ReadFile(link.Source)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := keys.Decrypt(ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s: %w", link.Source, err)
+		}
+
+		r, err := newRender(link, plaintext, secretFileMode)
+		if err != nil {
+			return nil, err
+		}
+		renders = append(renders, r)
+	}
+	return renders, nil
+}
+
+// EncryptInto encrypts the file at src and writes it to dst in the repo.
+// The plaintext file is left in place with its permissions tightened.
+func EncryptInto(src, dst string, keys *SecretKeys) error {
+	plaintext, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	ciphertext, err := keys.Encrypt(plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", src, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, ciphertext, 0644); err != nil {
+		return err
+	}
+	return os.Chmod(src, secretFileMode)
+}
@@ -0,0 +1,111 @@
+package dotfiles
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSecretKey_RoundTrip(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	recipient, err := GenerateSecretKey()
+	require.NoError(t, err)
+	assert.Contains(t, recipient, "age1")
+
+	path, err := SecretKeyPath()
+	require.NoError(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	_, err = GenerateSecretKey()
+	assert.Error(t, err, "existing key must not be overwritten")
+
+	keys, err := LoadSecretKeys(nil)
+	require.NoError(t, err)
+
+	ciphertext, err := keys.Encrypt([]byte("//registry.npmjs.org/:_authToken=secret"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), "secret")
+
+	plaintext, err := keys.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "//registry.npmjs.org/:_authToken=secret", string(plaintext))
+}
+
+func TestLoadSecretKeys_Passphrase(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(passphraseEnv, "")
+
+	_, err := LoadSecretKeys(nil)
+	assert.Error(t, err)
+
+	t.Setenv(passphraseEnv, "correct horse")
+	keys, err := LoadSecretKeys(nil)
+	require.NoError(t, err)
+
+	ciphertext, err := keys.Encrypt([]byte("token"))
+	require.NoError(t, err)
+
+	wrong, err := NewPassphraseKeys("wrong")
+	require.NoError(t, err)
+	_, err = wrong.Decrypt(ciphertext)
+	assert.Error(t, err)
+}
+
+func TestLinkPlan_DecryptSecrets(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	_, err := GenerateSecretKey()
+	require.NoError(t, err)
+	keys, err := LoadSecretKeys(nil)
+	require.NoError(t, err)
+
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(home, ".netrc"), "machine api.example.com password hunter2\n")
+	require.NoError(t, EncryptInto(filepath.Join(home, ".netrc"), filepath.Join(repo, ".netrc.age"), keys))
+	require.NoError(t, os.Remove(filepath.Join(home, ".netrc")))
+
+	plan, err := PlanLinks(repo, home)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Pending)
+	require.Len(t, plan.Secrets, 1)
+	assert.Equal(t, filepath.Join(home, ".netrc"), plan.Secrets[0].Target)
+
+	renders, err := plan.DecryptSecrets(keys)
+	require.NoError(t, err)
+	require.NoError(t, plan.writeRenders(renders, filepath.Join(home, "backup"), &Manifest{}))
+
+	data, err := os.ReadFile(filepath.Join(home, ".netrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "machine api.example.com password hunter2\n", string(data))
+	info, err := os.Stat(filepath.Join(home, ".netrc"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestRepoFileFor(t *testing.T) {
+	home := t.TempDir()
+	repo := filepath.Join(home, defaultDotfilesDir)
+	require.NoError(t, os.MkdirAll(repo, 0755))
+
+	dst, err := RepoFileFor(repo, home, filepath.Join(home, ".config", "gh", "hosts.yml"), "")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repo, ".config", "gh", "hosts.yml"), dst)
+
+	dst, err = RepoFileFor(repo, home, filepath.Join(home, ".npmrc"), "node")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repo, "node", ".npmrc"), dst)
+
+	_, err = RepoFileFor(repo, home, "/etc/hosts", "")
+	assert.Error(t, err)
+
+	writeFile(t, filepath.Join(repo, "vim", ".vimrc"), "")
+	_, err = RepoFileFor(repo, home, filepath.Join(home, ".npmrc"), "")
+	assert.Error(t, err)
+}
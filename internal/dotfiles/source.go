@@ -0,0 +1,239 @@
+package dotfiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/openbootdotdev/openboot/internal/system"
+)
+
+const sourceFile = "dotfiles.json"
+
+// Source describes where the dotfiles repo comes from and where it lives on
+// this machine.
+type Source struct {
+	// URL is the git remote to clone.
+	URL string `json:"url"`
+	// Ref is a branch, tag or commit to check out. Empty means the remote's
+	// default branch.
+	Ref string `json:"ref,omitempty"`
+	// Subdir is the directory inside the repo that mirrors $HOME.
+	Subdir string `json:"subdir,omitempty"`
+	// Path is where the repo is cloned. Empty means ~/.dotfiles.
+	Path string `json:"path,omitempty"`
+}
+
+// SourceFromEnv reads a Source from OPENBOOT_DOTFILES, OPENBOOT_DOTFILES_REF,
+// OPENBOOT_DOTFILES_SUBDIR and OPENBOOT_DOTFILES_PATH.
+func SourceFromEnv() Source {
+	return Source{
+		URL:    GetDotfilesURL(),
+		Ref:    os.Getenv("OPENBOOT_DOTFILES_REF"),
+		Subdir: os.Getenv("OPENBOOT_DOTFILES_SUBDIR"),
+		Path:   os.Getenv("OPENBOOT_DOTFILES_PATH"),
+	}
+}
+
+// Validate rejects subdirectories that escape the repo.
+func (s Source) Validate() error {
+	if s.Subdir == "" {
+		return nil
+	}
+	clean := filepath.Clean(s.Subdir)
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("dotfiles subdirectory must be inside the repo: %s", s.Subdir)
+	}
+	return nil
+}
+
+// CloneDir returns the absolute clone location, expanding a leading ~ and
+// defaulting to ~/.dotfiles.
+func (s Source) CloneDir() (string, error) {
+	home, err := system.HomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case s.Path == "":
+		return filepath.Join(home, defaultDotfilesDir), nil
+	case s.Path == "~":
+		return home, nil
+	case strings.HasPrefix(s.Path, "~/"):
+		return filepath.Join(home, s.Path[2:]), nil
+	case filepath.IsAbs(s.Path):
+		return filepath.Clean(s.Path), nil
+	default:
+		return filepath.Join(home, s.Path), nil
+	}
+}
+
+// LinkDir returns the directory whose contents are linked into $HOME.
+func (s Source) LinkDir() (string, error) {
+	dir, err := s.CloneDir()
+	if err != nil {
+		return "", err
+	}
+	if s.Subdir == "" {
+		return dir, nil
+	}
+	return filepath.Join(dir, filepath.Clean(s.Subdir)), nil
+}
+
+// SourcePath returns where the source of the cloned repo is recorded.
+func SourcePath() (string, error) {
+	home, err := system.HomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".openboot", sourceFile), nil
+}
+
+// LoadSource returns the source recorded by the last clone, or an empty
+// Source if none was recorded.
+func LoadSource() (Source, error) {
+	path, err := SourcePath()
+	if err != nil {
+		return Source{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Source{}, nil
+	}
+	if err != nil {
+		return Source{}, fmt.Errorf("failed to read dotfiles settings: %w", err)
+	}
+
+	var s Source
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Source{}, fmt.Errorf("failed to parse dotfiles settings: %w", err)
+	}
+	return s, nil
+}
+
+// Save records s so later commands find the repo and subdirectory.
+func (s Source) Save() error {
+	path, err := SourcePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal dotfiles settings: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write dotfiles settings: %w", err)
+	}
+	return nil
+}
+
+// CloneSource clones s.URL into its clone directory, checks out s.Ref and
+// records s for later commands. An existing clone is left as it is, and is
+// only recorded if its origin is s.URL.
+func CloneSource(s Source, dryRun bool) error {
+	if s.URL == "" {
+		return nil
+	}
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	dir, err := s.CloneDir()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dir); err == nil {
+		origin, err := gitOutput(dir, "config", "--get", "remote.origin.url")
+		if err != nil || origin == "" {
+			fmt.Printf("Dotfiles directory %s exists but has no origin remote, skipping clone\n", dir)
+			return nil
+		}
+		if !sameRepoURL(origin, s.URL) {
+			return fmt.Errorf("%s is a clone of %s, not %s", dir, origin, s.URL)
+		}
+		fmt.Printf("Dotfiles already exist at %s, skipping clone\n", dir)
+		if dryRun {
+			return nil
+		}
+		return s.Save()
+	}
+
+	if dryRun {
+		if s.Ref != "" {
+			fmt.Printf("[DRY-RUN] Would clone %s (%s) to %s\n", s.URL, s.Ref, dir)
+		} else {
+			fmt.Printf("[DRY-RUN] Would clone %s to %s\n", s.URL, dir)
+		}
+		return nil
+	}
+
+	cmd := exec.Command("git", "clone", s.URL, dir)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	if s.Ref != "" {
+		if output, err := exec.Command("git", "-C", dir, "checkout", s.Ref).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to check out %s: %s", s.Ref, strings.TrimSpace(string(output)))
+		}
+	}
+
+	if s.Subdir != "" {
+		linkDir, _ := s.LinkDir()
+		if info, err := os.Stat(linkDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("dotfiles subdirectory not found: %s", linkDir)
+		}
+	}
+
+	return s.Save()
+}
+
+// sameRepoURL compares two remote URLs, ignoring a trailing slash or .git.
+func sameRepoURL(a, b string) bool {
+	normalize := func(u string) string {
+		u = strings.TrimSuffix(strings.TrimSpace(u), "/")
+		return strings.TrimSuffix(u, ".git")
+	}
+	return normalize(a) == normalize(b)
+}
+
+// CurrentSource describes the cloned repo as it is now: the recorded
+// subdirectory and path plus the remote URL and checked-out ref read from
+// git. It returns nil if there is no clone.
+func CurrentSource() (*Source, error) {
+	s, err := LoadSource()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := s.CloneDir()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return nil, nil
+	}
+
+	if url, err := gitOutput(dir, "remote", "get-url", "origin"); err == nil {
+		s.URL = strings.TrimSpace(url)
+	}
+	if branch, err := gitOutput(dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+		s.Ref = strings.TrimSpace(branch)
+	}
+	if s.Ref == "HEAD" {
+		if commit, err := gitOutput(dir, "rev-parse", "HEAD"); err == nil {
+			s.Ref = strings.TrimSpace(commit)
+		}
+	}
+	return &s, nil
+}
@@ -0,0 +1,114 @@
+package dotfiles
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSource_CloneDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"", filepath.Join(home, ".dotfiles")},
+		{"~/src/dotfiles", filepath.Join(home, "src", "dotfiles")},
+		{"src/dotfiles", filepath.Join(home, "src", "dotfiles")},
+		{"/opt/dotfiles", "/opt/dotfiles"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			dir, err := Source{Path: tt.path}.CloneDir()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, dir)
+		})
+	}
+}
+
+func TestSource_Validate(t *testing.T) {
+	assert.NoError(t, Source{}.Validate())
+	assert.NoError(t, Source{Subdir: "home"}.Validate())
+	assert.NoError(t, Source{Subdir: "machines/work"}.Validate())
+	assert.Error(t, Source{Subdir: "../other"}.Validate())
+	assert.Error(t, Source{Subdir: "/etc"}.Validate())
+}
+
+func TestCloneSource_RefSubdirAndPath(t *testing.T) {
+	home := t.TempDir()
+	upstream := setupGitRepo(t, t.TempDir())
+	writeFile(t, filepath.Join(upstream, "home", ".vimrc"), "main")
+	runGit(t, upstream, "add", ".")
+	runGit(t, upstream, "commit", "--quiet", "-m", "main")
+	runGit(t, upstream, "checkout", "--quiet", "-b", "work")
+	writeFile(t, filepath.Join(upstream, "home", ".vimrc"), "work")
+	runGit(t, upstream, "commit", "--quiet", "-am", "work")
+	runGit(t, upstream, "checkout", "--quiet", "-")
+	t.Setenv("HOME", home)
+
+	source := Source{URL: upstream, Ref: "work", Subdir: "home", Path: "~/src/dotfiles"}
+	require.NoError(t, CloneSource(source, false))
+
+	data, err := os.ReadFile(filepath.Join(home, "src", "dotfiles", "home", ".vimrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "work", string(data))
+
+	repo, err := RepoPath()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "src", "dotfiles", "home"), repo)
+
+	current, err := CurrentSource()
+	require.NoError(t, err)
+	require.NotNil(t, current)
+	assert.Equal(t, upstream, current.URL)
+	assert.Equal(t, "work", current.Ref)
+	assert.Equal(t, "home", current.Subdir)
+}
+
+func TestCloneSource_MissingSubdir(t *testing.T) {
+	home := t.TempDir()
+	upstream := setupGitRepo(t, t.TempDir())
+	writeFile(t, filepath.Join(upstream, ".vimrc"), "set nu")
+	runGit(t, upstream, "add", ".")
+	runGit(t, upstream, "commit", "--quiet", "-m", "init")
+	t.Setenv("HOME", home)
+
+	err := CloneSource(Source{URL: upstream, Subdir: "home"}, false)
+	assert.ErrorContains(t, err, "subdirectory not found")
+}
+
+func TestCloneSource_ExistingCloneMustMatchURL(t *testing.T) {
+	home := t.TempDir()
+	upstream := setupGitRepo(t, t.TempDir())
+	writeFile(t, filepath.Join(upstream, ".vimrc"), "set nu")
+	runGit(t, upstream, "add", ".")
+	runGit(t, upstream, "commit", "--quiet", "-m", "init")
+	t.Setenv("HOME", home)
+
+	require.NoError(t, CloneSource(Source{URL: upstream}, false))
+
+	err := CloneSource(Source{URL: "https://example.com/other.git", Ref: "work"}, false)
+	assert.ErrorContains(t, err, "not https://example.com/other.git")
+	saved, err := LoadSource()
+	require.NoError(t, err)
+	assert.Equal(t, Source{URL: upstream}, saved, "a mismatched clone is not recorded")
+
+	require.NoError(t, CloneSource(Source{URL: upstream + ".git", Ref: "main"}, false))
+	saved, err = LoadSource()
+	require.NoError(t, err)
+	assert.Equal(t, "main", saved.Ref)
+}
+
+func TestCurrentSource_NoClone(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	current, err := CurrentSource()
+	require.NoError(t, err)
+	assert.Nil(t, current)
+}
@@ -0,0 +1,239 @@
+package dotfiles
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/openbootdotdev/openboot/internal/system"
+)
+
+// RepoStatus describes how $HOME and the dotfiles repo relate to each other
+// and to the repo's upstream.
+type RepoStatus struct {
+	Plan        *LinkPlan
+	Broken      []FileLink
+	Outdated    []FileLink
+	RenderError error
+	Modified    []string
+	Upstream    string
+	Ahead       int
+	Behind      int
+}
+
+// Healthy reports whether every dotfile is linked and rendered and the repo
+// has no local changes.
+func (s *RepoStatus) Healthy() bool {
+	return len(s.Plan.Pending) == 0 && len(s.Plan.Conflicts) == 0 &&
+		len(s.Broken) == 0 && len(s.Outdated) == 0 && s.RenderError == nil &&
+		len(s.Modified) == 0 && s.Ahead == 0 && s.Behind == 0
+}
+
+// GetStatus inspects link health, rendered templates and git state. With
+// fetch, the upstream is fetched first so ahead/behind counts are current.
+func GetStatus(repo, home string, fetch bool) (*RepoStatus, error) {
+	plan, err := PlanLinks(repo, home)
+	if err != nil {
+		return nil, err
+	}
+	status := &RepoStatus{Plan: plan}
+
+	manifest, err := LoadManifest()
+	if err != nil {
+		return nil, err
+	}
+	status.Broken = manifest.BrokenLinks()
+
+	if len(plan.Templates) > 0 {
+		data, err := LoadTemplateData(nil)
+		if err != nil {
+			return nil, err
+		}
+		renders, err := plan.RenderTemplates(data)
+		if err != nil {
+			status.RenderError = err
+		}
+		for _, r := range renders {
+			if r.Changed() {
+				status.Outdated = append(status.Outdated, r.Link)
+			}
+		}
+	}
+
+	if fetch {
+		if _, err := gitOutput(repo, "fetch", "--quiet"); err != nil {
+			return nil, err
+		}
+	}
+
+	porcelain, err := gitOutput(repo, "status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(porcelain, "\n") {
+		if strings.TrimSpace(line) != "" {
+			status.Modified = append(status.Modified, line)
+		}
+	}
+
+	if upstream, err := gitOutput(repo, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}"); err == nil {
+		status.Upstream = upstream
+		counts, err := gitOutput(repo, "rev-list", "--left-right", "--count", "HEAD...@{upstream}")
+		if err == nil {
+			status.Ahead, status.Behind = parseAheadBehind(counts)
+		}
+	}
+
+	return status, nil
+}
+
+func parseAheadBehind(output string) (int, int) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return 0, 0
+	}
+	ahead, _ := strconv.Atoi(fields[0])
+	behind, _ := strconv.Atoi(fields[1])
+	return ahead, behind
+}
+
+func gitOutput(repo string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(output)))
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// Pull fast-forwards the dotfiles repo, removes links to files that were
+// deleted upstream and links anything new.
+func Pull(dryRun bool) error {
+	repo, err := RepoPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(repo); os.IsNotExist(err) {
+		return fmt.Errorf("dotfiles directory not found: %s", repo)
+	}
+	home, err := system.HomeDir()
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Printf("[DRY-RUN] Would run git pull --ff-only in %s\n", repo)
+	} else {
+		cmd := exec.Command("git", "-C", repo, "pull", "--ff-only")
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("git pull failed: %w", err)
+		}
+	}
+
+	manifest, err := LoadManifest()
+	if err != nil {
+		return err
+	}
+	manifest.PruneBroken(home, dryRun)
+	if !dryRun {
+		if err := manifest.Save(); err != nil {
+			return err
+		}
+	}
+
+	return Link(dryRun, false)
+}
+
+// Add moves the file at path into the dotfiles repo, replaces it with a
+// symlink and commits it. It returns the file's new location in the repo.
+func Add(path, pkg string) (string, error) {
+	repo, err := RepoPath()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(repo); os.IsNotExist(err) {
+		return "", fmt.Errorf("dotfiles directory not found: %s", repo)
+	}
+	home, err := system.HomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Lstat(abs)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return "", fmt.Errorf("%s is already a symlink", path)
+	}
+
+	dst, err := RepoFileFor(repo, home, abs, pkg)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return "", fmt.Errorf("%s already exists in the dotfiles repo", dst)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(abs, dst); err != nil {
+		return "", fmt.Errorf("failed to move %s into the repo: %w", path, err)
+	}
+	if err := os.Symlink(dst, abs); err != nil {
+		if restoreErr := os.Rename(dst, abs); restoreErr != nil {
+			return "", fmt.Errorf("failed to link %s (file left at %s): %w", path, dst, err)
+		}
+		return "", fmt.Errorf("failed to link %s: %w", path, err)
+	}
+
+	manifest, err := LoadManifest()
+	if err != nil {
+		return "", err
+	}
+	manifest.addLink(FileLink{Source: dst, Target: abs})
+	if err := manifest.Save(); err != nil {
+		return "", err
+	}
+
+	rel, _ := filepath.Rel(repo, dst)
+	if err := Commit(repo, "Add "+rel, rel); err != nil {
+		return dst, err
+	}
+	return dst, nil
+}
+
+// Unlink removes every link and generated file openboot created and
+// restores the files that were backed up to make room for them.
+func Unlink(dryRun bool) error {
+	home, err := system.HomeDir()
+	if err != nil {
+		return err
+	}
+	manifest, err := LoadManifest()
+	if err != nil {
+		return err
+	}
+	if len(manifest.Links) == 0 && len(manifest.Rendered) == 0 && len(manifest.Backups) == 0 {
+		fmt.Println("No linked dotfiles recorded")
+		return nil
+	}
+
+	unlinkErr := manifest.Unlink(home, dryRun)
+	if !dryRun {
+		if err := manifest.Save(); err != nil {
+			return err
+		}
+	}
+	return unlinkErr
+}
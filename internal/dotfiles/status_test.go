@@ -0,0 +1,206 @@
+package dotfiles
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupGitRepo(t *testing.T, home string) string {
+	t.Helper()
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	repo := filepath.Join(home, defaultDotfilesDir)
+	require.NoError(t, os.MkdirAll(repo, 0755))
+	runGit(t, repo, "init", "--quiet")
+	return repo
+}
+
+func runGit(t *testing.T, repo string, args ...string) {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+func TestAdd_MovesFileAndLinksIt(t *testing.T) {
+	home := t.TempDir()
+	repo := setupGitRepo(t, home)
+	writeFile(t, filepath.Join(home, ".tmux.conf"), "set -g mouse on")
+
+	dst, err := Add(filepath.Join(home, ".tmux.conf"), "")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repo, ".tmux.conf"), dst)
+
+	link, err := os.Readlink(filepath.Join(home, ".tmux.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, dst, link)
+
+	out, err := gitOutput(repo, "log", "--format=%s")
+	require.NoError(t, err)
+	assert.Equal(t, "Add .tmux.conf", out)
+
+	manifest, err := LoadManifest()
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(home, ".tmux.conf")}, targets(manifest.Links))
+
+	_, err = Add(filepath.Join(home, ".tmux.conf"), "")
+	assert.Error(t, err, "adding a symlink again must fail")
+}
+
+func TestUnlink_RestoresBackups(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(repo, ".vimrc"), "new")
+	writeFile(t, filepath.Join(repo, ".config", "nvim", "init.lua"), "--")
+	writeFile(t, filepath.Join(home, ".vimrc"), "old")
+
+	require.NoError(t, Link(false, false))
+
+	link, err := os.Readlink(filepath.Join(home, ".vimrc"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repo, ".vimrc"), link)
+
+	require.NoError(t, Unlink(false))
+
+	data, err := os.ReadFile(filepath.Join(home, ".vimrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(data))
+	_, err = os.Lstat(filepath.Join(home, ".config"))
+	assert.True(t, os.IsNotExist(err), "empty directories created for links are removed")
+
+	manifest, err := LoadManifest()
+	require.NoError(t, err)
+	assert.Empty(t, manifest.Links)
+	assert.Empty(t, manifest.Backups)
+}
+
+func TestUnlink_LeavesReplacedLinks(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(repo, ".vimrc"), "new")
+
+	require.NoError(t, Link(false, false))
+	require.NoError(t, os.Remove(filepath.Join(home, ".vimrc")))
+	writeFile(t, filepath.Join(home, ".vimrc"), "mine")
+
+	require.NoError(t, Unlink(false))
+
+	data, err := os.ReadFile(filepath.Join(home, ".vimrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "mine", string(data))
+}
+
+func TestUnlink_OnlyRemovesUnmodifiedRenders(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(repo, ".gitconfig.tmpl"), "[user]\n")
+	writeFile(t, filepath.Join(repo, ".npmrc.tmpl"), "save-exact=true\n")
+	writeFile(t, filepath.Join(repo, ".curlrc.tmpl"), "silent\n")
+	writeFile(t, filepath.Join(home, ".gitconfig"), "[user]\n")
+
+	require.NoError(t, Link(false, false))
+
+	manifest, err := LoadManifest()
+	require.NoError(t, err)
+	var rendered []string
+	for _, r := range manifest.Rendered {
+		rendered = append(rendered, r.Target)
+	}
+	assert.ElementsMatch(t, []string{filepath.Join(home, ".npmrc"), filepath.Join(home, ".curlrc")}, rendered,
+		"an identical file the user already had is not recorded as rendered")
+
+	writeFile(t, filepath.Join(home, ".npmrc"), "save-exact=false\n")
+
+	require.NoError(t, Unlink(false))
+
+	data, err := os.ReadFile(filepath.Join(home, ".gitconfig"))
+	require.NoError(t, err)
+	assert.Equal(t, "[user]\n", string(data))
+
+	data, err = os.ReadFile(filepath.Join(home, ".npmrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "save-exact=false\n", string(data), "edited renders are left in place")
+
+	_, err = os.Lstat(filepath.Join(home, ".curlrc"))
+	assert.True(t, os.IsNotExist(err), "unmodified renders are removed")
+}
+
+func TestUnlink_KeepsPreexistingDirectories(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(repo, ".config", "git", "ignore"), "*.log")
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".config"), 0755))
+
+	require.NoError(t, Link(false, false))
+	require.NoError(t, Unlink(false))
+
+	_, err := os.Lstat(filepath.Join(home, ".config", "git"))
+	assert.True(t, os.IsNotExist(err), "directories created for links are removed")
+	info, err := os.Stat(filepath.Join(home, ".config"))
+	require.NoError(t, err, "directories that existed before linking are kept")
+	assert.True(t, info.IsDir())
+}
+
+func TestManifest_PruneBroken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(repo, ".vimrc"), "vim")
+	writeFile(t, filepath.Join(repo, ".config", "old", "rc"), "old")
+
+	require.NoError(t, Link(false, false))
+	require.NoError(t, os.RemoveAll(filepath.Join(repo, ".config")))
+
+	manifest, err := LoadManifest()
+	require.NoError(t, err)
+	broken := manifest.BrokenLinks()
+	assert.Equal(t, []string{filepath.Join(home, ".config", "old", "rc")}, targets(broken))
+
+	manifest.PruneBroken(home, false)
+	_, err = os.Lstat(filepath.Join(home, ".config"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, []string{filepath.Join(home, ".vimrc")}, targets(manifest.Links))
+}
+
+func TestGetStatus(t *testing.T) {
+	home := t.TempDir()
+	repo := setupGitRepo(t, home)
+	writeFile(t, filepath.Join(repo, ".vimrc"), "vim")
+	writeFile(t, filepath.Join(repo, ".zshrc"), "zsh")
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "--quiet", "-m", "init")
+
+	require.NoError(t, os.Symlink(filepath.Join(repo, ".vimrc"), filepath.Join(home, ".vimrc")))
+	writeFile(t, filepath.Join(repo, ".vimrc"), "vim changed")
+
+	status, err := GetStatus(repo, home, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{filepath.Join(home, ".vimrc")}, targets(status.Plan.Linked))
+	assert.Equal(t, []string{filepath.Join(home, ".zshrc")}, targets(status.Plan.Pending))
+	assert.Equal(t, []string{" M .vimrc"}, status.Modified)
+	assert.Empty(t, status.Upstream)
+	assert.False(t, status.Healthy())
+}
+
+func TestParseAheadBehind(t *testing.T) {
+	ahead, behind := parseAheadBehind("2\t5")
+	assert.Equal(t, 2, ahead)
+	assert.Equal(t, 5, behind)
+
+	ahead, behind = parseAheadBehind("")
+	assert.Equal(t, 0, ahead)
+	assert.Equal(t, 0, behind)
+}
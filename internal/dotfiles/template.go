@@ -0,0 +1,415 @@
+package dotfiles
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/openbootdotdev/openboot/internal/system"
+)
+
+// TemplateSuffix marks files in the dotfiles repo that are rendered with
+// text/template and written to $HOME instead of being symlinked.
+const TemplateSuffix = ".tmpl"
+
+const (
+	templateVarsFile   = "dotfiles-vars.json"
+	templateConfigFile = "dotfiles-config.json"
+)
+
+// Template variable keys filled from the openboot config by the installer.
+// They are available as {{ .Vars.<key> }} and answer {{ prompt "<key>" }}
+// without asking.
+const (
+	VarPreset     = "preset"      // preset used for the install
+	VarConfigUser = "config_user" // openboot.dev user of the remote config
+	VarConfigSlug = "config_slug" // slug of the remote config
+	VarConfigName = "config_name" // display name of the remote config
+	VarGitName    = "git_name"    // git user.name configured by openboot
+	VarGitEmail   = "git_email"   // git user.email configured by openboot
+)
+
+// Prompter asks the user for a template variable that has no stored value.
+type Prompter func(key, question string) (string, error)
+
+// TemplateData is the data passed to every dotfile template. Vars holds the
+// openboot config values (see VarPreset and friends) and prompted values.
+type TemplateData struct {
+	Hostname       string
+	Arch           string
+	HomebrewPrefix string
+	Home           string
+	User           string
+	GitName        string
+	GitEmail       string
+	Vars           map[string]string
+
+	prompt Prompter
+	stored map[string]string
+	dirty  bool
+}
+
+// TemplateRender is a generated file (a rendered template or a decrypted
+// secret) together with the current contents of its target.
+type TemplateRender struct {
+	Link        FileLink
+	Current     []byte
+	Rendered    []byte
+	Exists      bool
+	Mode        os.FileMode
+	CurrentMode os.FileMode
+}
+
+func (r *TemplateRender) Changed() bool {
+	return !r.Exists || !bytes.Equal(r.Current, r.Rendered)
+}
+
+func newRender(link FileLink, rendered []byte, mode os.FileMode) (TemplateRender, error) {
+	r := TemplateRender{Link: link, Rendered: rendered, Mode: mode}
+	info, err := os.Lstat(link.Target)
+	if err != nil || !info.Mode().IsRegular() {
+		return r, nil
+	}
+	current, err := os.ReadFile(link.Target)
+	if err != nil {
+		return r, err
+	}
+	r.Current = current
+	r.CurrentMode = info.Mode().Perm()
+	r.Exists = true
+	return r, nil
+}
+
+func isTemplate(path string) bool {
+	return strings.HasSuffix(path, TemplateSuffix)
+}
+
+// TemplateVarsPath returns where prompted template values are stored.
+func TemplateVarsPath() (string, error) {
+	home, err := system.HomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".openboot", templateVarsFile), nil
+}
+
+// TemplateConfigPath returns where the installer records the openboot config
+// variables.
+func TemplateConfigPath() (string, error) {
+	home, err := system.HomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".openboot", templateConfigFile), nil
+}
+
+// SaveConfigVars records the template variables derived from the openboot
+// config, so that later renders (dotfiles diff, pull, status) see the same
+// values as the install that produced them. Empty values are dropped.
+func SaveConfigVars(vars map[string]string) error {
+	path, err := TemplateConfigPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	set := map[string]string{}
+	for k, v := range vars {
+		if v != "" {
+			set[k] = v
+		}
+	}
+	raw, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal template config variables: %w", err)
+	}
+	if err := os.WriteFile(path, raw, 0600); err != nil {
+		return fmt.Errorf("failed to write template config variables: %w", err)
+	}
+	return nil
+}
+
+// LoadTemplateData collects machine facts, the openboot config variables
+// and stored answers. Config variables win over stored answers with the
+// same key. prompt is called for `{{ prompt "key" "Question" }}` values that
+// are still missing; it may be nil, in which case such templates fail to
+// render.
+func LoadTemplateData(prompt Prompter) (*TemplateData, error) {
+	data := &TemplateData{
+		Arch:           system.Architecture(),
+		HomebrewPrefix: system.HomebrewPrefix(),
+		Vars:           map[string]string{},
+		prompt:         prompt,
+		stored:         map[string]string{},
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		data.Hostname = strings.TrimSuffix(hostname, ".local")
+	}
+	if home, err := system.HomeDir(); err == nil {
+		data.Home = home
+	}
+	if u, err := user.Current(); err == nil {
+		data.User = u.Username
+	}
+	data.GitName, data.GitEmail = system.GetExistingGitConfig()
+
+	path, err := TemplateVarsPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := readVars(path, data.stored); err != nil {
+		return nil, err
+	}
+	configPath, err := TemplateConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	config := map[string]string{}
+	if err := readVars(configPath, config); err != nil {
+		return nil, err
+	}
+
+	for k, v := range data.stored {
+		data.Vars[k] = v
+	}
+	for k, v := range config {
+		data.Vars[k] = v
+	}
+	return data, nil
+}
+
+func readVars(path string, into map[string]string) error {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read template variables: %w", err)
+	}
+	if err := json.Unmarshal(raw, &into); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// Save persists prompted variables so the user is only asked once.
+func (d *TemplateData) Save() error {
+	if !d.dirty {
+		return nil
+	}
+
+	path, err := TemplateVarsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	raw, err := json.MarshalIndent(d.stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal template variables: %w", err)
+	}
+	if err := os.WriteFile(path, raw, 0600); err != nil {
+		return fmt.Errorf("failed to write template variables: %w", err)
+	}
+	d.dirty = false
+	return nil
+}
+
+func (d *TemplateData) lookup(key, question string) (string, error) {
+	if v, ok := d.Vars[key]; ok {
+		return v, nil
+	}
+	if d.prompt == nil {
+		return "", fmt.Errorf("template variable %q is not set", key)
+	}
+	if question == "" {
+		question = key
+	}
+
+	v, err := d.prompt(key, question)
+	if err != nil {
+		return "", err
+	}
+	d.Vars[key] = v
+	if d.stored == nil {
+		d.stored = map[string]string{}
+	}
+	d.stored[key] = v
+	d.dirty = true
+	return v, nil
+}
+
+// RenderTemplate renders the template at path with data.
+func RenderTemplate(path string, data *TemplateData) ([]byte, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	funcs := template.FuncMap{
+		"prompt": func(key string, question ...string) (string, error) {
+			return data.lookup(key, strings.Join(question, " "))
+		},
+		"env": os.Getenv,
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Funcs(funcs).Option("missingkey=error").Parse(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", path, err)
+	}
+	return out.Bytes(), nil
+}
+
+// RenderTemplates renders every template in the plan and reads the current
+// content of each target so callers can diff or apply the result.
+func (p *LinkPlan) RenderTemplates(data *TemplateData) ([]TemplateRender, error) {
+	var renders []TemplateRender
+	for _, link := range p.Templates {
+		rendered, err := RenderTemplate(link.Source, data)
+		if err != nil {
+			return nil, err
+		}
+
+		mode := os.FileMode(0644)
+		if info, err := os.Stat(link.Source); err == nil {
+			mode = info.Mode().Perm()
+		}
+		r, err := newRender(link, rendered, mode)
+		if err != nil {
+			return nil, err
+		}
+		renders = append(renders, r)
+	}
+	return renders, nil
+}
+
+// writeRenders writes changed files, moving any existing target into
+// backupDir first. Every file written is recorded in m; an unchanged target
+// is only recorded if openboot wrote it on an earlier run, so that a user's
+// own identical file is never treated as generated.
+func (p *LinkPlan) writeRenders(renders []TemplateRender, backupDir string, m *Manifest) error {
+	var failed []string
+	for _, r := range renders {
+		if !r.Changed() {
+			if m.isRendered(r.Link.Target) {
+				m.addRendered(r.Link, r.Rendered)
+			}
+			if r.CurrentMode != r.Mode {
+				if err := os.Chmod(r.Link.Target, r.Mode); err != nil {
+					fmt.Printf("Warning: failed to set permissions on %s: %v\n", r.Link.Target, err)
+				}
+			}
+			continue
+		}
+
+		if _, err := os.Lstat(r.Link.Target); err == nil {
+			dst, err := backupPath(p.Home, r.Link.Target, backupDir)
+			if err != nil {
+				return err
+			}
+			m.addBackup(r.Link.Target, dst)
+			fmt.Printf("Backed up: %s -> %s\n", r.Link.Target, dst)
+		}
+
+		if err := m.mkdirAll(p.Home, filepath.Dir(r.Link.Target)); err != nil {
+			fmt.Printf("Warning: failed to create %s: %v\n", filepath.Dir(r.Link.Target), err)
+			failed = append(failed, r.Link.Target)
+			continue
+		}
+		if err := os.WriteFile(r.Link.Target, r.Rendered, r.Mode); err != nil {
+			fmt.Printf("Warning: failed to render %s: %v\n", r.Link.Target, err)
+			failed = append(failed, r.Link.Target)
+			continue
+		}
+		m.addRendered(r.Link, r.Rendered)
+		fmt.Printf("Rendered: %s <- %s\n", r.Link.Target, r.Link.Source)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d templates failed to render", len(failed))
+	}
+	return nil
+}
+
+// DiffOp is the kind of a line in a LineDiff.
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffDelete
+	DiffInsert
+)
+
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// LineDiff returns a line-based diff turning a into b, using the longest
+// common subsequence of lines.
+func LineDiff(a, b string) []DiffLine {
+	x := splitLines(a)
+	y := splitLines(b)
+
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out []DiffLine
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			out = append(out, DiffLine{DiffEqual, x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, DiffLine{DiffDelete, x[i]})
+			i++
+		default:
+			out = append(out, DiffLine{DiffInsert, y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		out = append(out, DiffLine{DiffDelete, x[i]})
+	}
+	for ; j < len(y); j++ {
+		out = append(out, DiffLine{DiffInsert, y[j]})
+	}
+	return out
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,182 @@
+package dotfiles
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplate_MachineVariables(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".gitconfig.tmpl")
+	writeFile(t, path, "[user]\n  email = {{ .GitEmail }}\n# {{ .Arch }} {{ .HomebrewPrefix }}\n")
+
+	data := &TemplateData{GitEmail: "me@work.com", Arch: "arm64", HomebrewPrefix: "/opt/homebrew", Vars: map[string]string{}}
+	out, err := RenderTemplate(path, data)
+	require.NoError(t, err)
+	assert.Equal(t, "[user]\n  email = me@work.com\n# arm64 /opt/homebrew\n", string(out))
+}
+
+func TestRenderTemplate_PromptStoresValue(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	path := filepath.Join(home, "tmpl")
+	writeFile(t, path, `{{ prompt "work_email" "Work email" }} {{ prompt "work_email" }}`)
+
+	calls := 0
+	data, err := LoadTemplateData(func(key, question string) (string, error) {
+		calls++
+		assert.Equal(t, "work_email", key)
+		assert.Equal(t, "Work email", question)
+		return "me@corp.com", nil
+	})
+	require.NoError(t, err)
+
+	out, err := RenderTemplate(path, data)
+	require.NoError(t, err)
+	assert.Equal(t, "me@corp.com me@corp.com", string(out))
+	assert.Equal(t, 1, calls)
+
+	require.NoError(t, data.Save())
+	reloaded, err := LoadTemplateData(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "me@corp.com", reloaded.Vars["work_email"])
+}
+
+func TestLoadTemplateData_ConfigVars(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	require.NoError(t, SaveConfigVars(map[string]string{VarGitEmail: "me@work.com", VarPreset: "developer", VarConfigSlug: ""}))
+
+	path := filepath.Join(home, "tmpl")
+	writeFile(t, path, `{{ .Vars.preset }} {{ prompt "git_email" }} {{ prompt "team" }}`)
+
+	data, err := LoadTemplateData(func(key, question string) (string, error) {
+		assert.Equal(t, "team", key, "config variables are never prompted for")
+		return "platform", nil
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, data.Vars, VarConfigSlug)
+
+	out, err := RenderTemplate(path, data)
+	require.NoError(t, err)
+	assert.Equal(t, "developer me@work.com platform", string(out))
+
+	require.NoError(t, data.Save())
+	raw, err := os.ReadFile(filepath.Join(home, ".openboot", templateVarsFile))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"team": "platform"}`, string(raw), "config variables are not stored as answers")
+}
+
+func TestLink_DryRunDoesNotPrompt(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeFile(t, filepath.Join(home, defaultDotfilesDir, ".npmrc.tmpl"), `email={{ prompt "npm_email" }}`)
+
+	saved := InputPrompter
+	InputPrompter = func(key, question string) (string, error) {
+		t.Fatalf("prompted for %s during dry run", key)
+		return "", nil
+	}
+	t.Cleanup(func() { InputPrompter = saved })
+
+	require.NoError(t, Link(true, false))
+
+	_, err := os.Stat(filepath.Join(home, ".openboot", templateVarsFile))
+	assert.True(t, os.IsNotExist(err), "placeholder answers are not stored")
+	_, err = os.Stat(filepath.Join(home, ".npmrc"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRenderTemplate_MissingVariableWithoutPrompter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tmpl")
+	writeFile(t, path, `{{ prompt "token" }}`)
+
+	_, err := RenderTemplate(path, &TemplateData{Vars: map[string]string{}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `"token" is not set`)
+}
+
+func TestPlanLinks_TemplatesAreNotSymlinked(t *testing.T) {
+	home := t.TempDir()
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(repo, ".vimrc"), "set nu")
+	writeFile(t, filepath.Join(repo, ".zshrc.tmpl"), "export HOST={{ .Hostname }}\n")
+
+	plan, err := PlanLinks(repo, home)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{filepath.Join(home, ".vimrc")}, targets(plan.Pending))
+	require.Len(t, plan.Templates, 1)
+	assert.Equal(t, filepath.Join(home, ".zshrc"), plan.Templates[0].Target)
+}
+
+func TestLinkPlan_RenderAndWriteTemplates(t *testing.T) {
+	home := t.TempDir()
+	repo := filepath.Join(home, defaultDotfilesDir)
+	writeFile(t, filepath.Join(repo, ".zshrc.tmpl"), "export HOST={{ .Hostname }}\n")
+	writeFile(t, filepath.Join(home, ".zshrc"), "export HOST=old\n")
+
+	plan, err := PlanLinks(repo, home)
+	require.NoError(t, err)
+
+	renders, err := plan.RenderTemplates(&TemplateData{Hostname: "work-mbp", Vars: map[string]string{}})
+	require.NoError(t, err)
+	require.Len(t, renders, 1)
+	assert.True(t, renders[0].Changed())
+	assert.Equal(t, "export HOST=old\n", string(renders[0].Current))
+
+	backup := filepath.Join(home, "backup")
+	require.NoError(t, plan.writeRenders(renders, backup, &Manifest{}))
+
+	data, err := os.ReadFile(filepath.Join(home, ".zshrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "export HOST=work-mbp\n", string(data))
+
+	old, err := os.ReadFile(filepath.Join(backup, ".zshrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "export HOST=old\n", string(old))
+
+	renders, err = plan.RenderTemplates(&TemplateData{Hostname: "work-mbp", Vars: map[string]string{}})
+	require.NoError(t, err)
+	assert.False(t, renders[0].Changed())
+}
+
+func TestLineDiff(t *testing.T) {
+	lines := LineDiff("a\nb\nc\n", "a\nx\nc\nd\n")
+	assert.Equal(t, []DiffLine{
+		{DiffEqual, "a"},
+		{DiffDelete, "b"},
+		{DiffInsert, "x"},
+		{DiffEqual, "c"},
+		{DiffInsert, "d"},
+	}, lines)
+
+	assert.Empty(t, LineDiff("", ""))
+}
+
+func TestLink_UnattendedDoesNotPrompt(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeFile(t, filepath.Join(home, defaultDotfilesDir, ".npmrc.tmpl"), `email={{ prompt "npm_email" }}`)
+
+	saved, savedPassword := InputPrompter, PasswordPrompter
+	InputPrompter = func(key, question string) (string, error) {
+		t.Fatalf("prompted for %s while unattended", key)
+		return "", nil
+	}
+	PasswordPrompter = InputPrompter
+	t.Cleanup(func() { InputPrompter, PasswordPrompter = saved, savedPassword })
+
+	assert.Nil(t, defaultPrompter(true))
+	assert.Nil(t, defaultPasswordPrompter(true))
+
+	err := Link(false, true)
+	assert.ErrorContains(t, err, `"npm_email" is not set`)
+}
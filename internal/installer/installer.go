@@ -1,7 +1,9 @@
 package installer
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -129,7 +131,7 @@ func runCustomInstall(cfg *config.Config) error {
 	}
 
 	if err := stepInstallPackages(cfg); err != nil {
-		return err
+		ui.Error(fmt.Sprintf("Some packages failed: %v", err))
 	}
 
 	if len(cfg.RemoteConfig.Npm) > 0 {
@@ -138,8 +140,15 @@ func runCustomInstall(cfg *config.Config) error {
 		}
 	}
 
+	if cfg.RemoteConfig.DotfilesRepo != "" && !cfg.PackagesOnly {
+		fmt.Println()
+		if err := stepDotfiles(cfg); err != nil {
+			ui.Error(fmt.Sprintf("Dotfiles setup failed: %v", err))
+		}
+	}
+
 	fmt.Println()
-	ui.Muted("Dotfiles and shell setup will be handled by the install script.")
+	ui.Muted("Shell setup will be handled by the install script.")
 	fmt.Println()
 	return nil
 }
@@ -160,7 +169,7 @@ func runInteractiveInstall(cfg *config.Config) error {
 	}
 
 	if err := stepInstallPackages(cfg); err != nil {
-		return err
+		ui.Error(fmt.Sprintf("Some packages failed: %v", err))
 	}
 
 	if err := stepInstallNpmWithRetry(cfg); err != nil {
@@ -375,21 +384,44 @@ func stepInstallPackages(cfg *config.Config) error {
 	ui.Info(fmt.Sprintf("Installing %d packages (%d CLI, %d GUI)...", len(cliPkgs)+len(caskPkgs), len(cliPkgs), len(caskPkgs)))
 	fmt.Println()
 
-	if err := brew.InstallWithProgress(cliPkgs, caskPkgs, cfg.DryRun); err != nil {
-		ui.Error(fmt.Sprintf("Some packages failed: %v", err))
-	}
+	err := brew.InstallWithProgress(cliPkgs, caskPkgs, cfg.DryRun)
 
 	if !cfg.DryRun {
-		for _, pkg := range cliPkgs {
+		var installErr *brew.InstallError
+		switch {
+		case err == nil:
+			markInstalled(state, cliPkgs, caskPkgs, nil)
+			ui.Success("Package installation complete")
+		case errors.As(err, &installErr):
+			markInstalled(state, cliPkgs, caskPkgs, installErr)
+		}
+	}
+	fmt.Println()
+	return err
+}
+
+// markInstalled records every package except those listed in failed.
+func markInstalled(state *InstallState, cliPkgs, caskPkgs []string, failed *brew.InstallError) {
+	failedFormulae := map[string]bool{}
+	failedCasks := map[string]bool{}
+	if failed != nil {
+		for _, pkg := range failed.Formulae {
+			failedFormulae[pkg] = true
+		}
+		for _, pkg := range failed.Casks {
+			failedCasks[pkg] = true
+		}
+	}
+	for _, pkg := range cliPkgs {
+		if !failedFormulae[pkg] {
 			state.markFormula(pkg)
 		}
-		for _, pkg := range caskPkgs {
+	}
+	for _, pkg := range caskPkgs {
+		if !failedCasks[pkg] {
 			state.markCask(pkg)
 		}
-		ui.Success("Package installation complete")
 	}
-	fmt.Println()
-	return nil
 }
 
 func stepInstallNpm(cfg *config.Config) error {
@@ -487,9 +519,12 @@ func stepDotfiles(cfg *config.Config) error {
 	ui.Header("Step 6: Dotfiles")
 	fmt.Println()
 
-	dotfilesURL := dotfiles.GetDotfilesURL()
+	source, err := dotfilesSource(cfg)
+	if err != nil {
+		return err
+	}
 
-	if cfg.Dotfiles == "" && dotfilesURL == "" {
+	if cfg.Dotfiles == "" && source.URL == "" {
 		if cfg.Silent || (cfg.DryRun && !system.HasTTY()) {
 			ui.Muted("Skipping dotfiles (no URL provided)")
 			fmt.Println()
@@ -506,20 +541,33 @@ func stepDotfiles(cfg *config.Config) error {
 			return nil
 		}
 
-		dotfilesURL, err = ui.Input("Dotfiles repository URL", "https://github.com/username/dotfiles")
+		source.URL, err = ui.Input("Dotfiles repository URL", "https://github.com/username/dotfiles")
 		if err != nil {
 			return err
 		}
 	}
 
-	if dotfilesURL != "" {
-		if err := dotfiles.Clone(dotfilesURL, cfg.DryRun); err != nil {
+	if source.URL != "" {
+		if err := dotfiles.CloneSource(source, cfg.DryRun); err != nil {
 			return err
 		}
 	}
 
 	if cfg.Dotfiles == "link" || cfg.Dotfiles == "" {
-		if err := dotfiles.Link(cfg.DryRun); err != nil {
+		if cfg.DryRun && source.URL != "" {
+			if dir, err := source.CloneDir(); err == nil {
+				if _, err := os.Stat(dir); os.IsNotExist(err) {
+					fmt.Println()
+					return nil
+				}
+			}
+		}
+		if !cfg.DryRun {
+			if err := dotfiles.SaveConfigVars(templateConfigVars(cfg)); err != nil {
+				return err
+			}
+		}
+		if err := dotfiles.Link(cfg.DryRun, cfg.Silent); err != nil {
 			return err
 		}
 	}
@@ -531,6 +579,74 @@ func stepDotfiles(cfg *config.Config) error {
 	return nil
 }
 
+// templateConfigVars exposes the parts of the openboot config that dotfile
+// templates may need.
+func templateConfigVars(cfg *config.Config) map[string]string {
+	vars := map[string]string{
+		dotfiles.VarPreset:   cfg.Preset,
+		dotfiles.VarGitName:  cfg.GitName,
+		dotfiles.VarGitEmail: cfg.GitEmail,
+	}
+	if git := cfg.SnapshotGit; git != nil {
+		if vars[dotfiles.VarGitName] == "" {
+			vars[dotfiles.VarGitName] = git.UserName
+		}
+		if vars[dotfiles.VarGitEmail] == "" {
+			vars[dotfiles.VarGitEmail] = git.UserEmail
+		}
+	}
+	if rc := cfg.RemoteConfig; rc != nil {
+		vars[dotfiles.VarConfigUser] = rc.Username
+		vars[dotfiles.VarConfigSlug] = rc.Slug
+		vars[dotfiles.VarConfigName] = rc.Name
+		if vars[dotfiles.VarPreset] == "" {
+			vars[dotfiles.VarPreset] = rc.Preset
+		}
+	}
+	return vars
+}
+
+// dotfilesSource decides which repo to set up. The first source that names
+// a repo wins: command-line flags, then a snapshot, a remote config,
+// OPENBOOT_DOTFILES* and finally whatever the last clone recorded. Ref,
+// subdirectory and clone path always come from that same source, so a ref
+// meant for one repo is never applied to another. --dotfiles-ref,
+// --dotfiles-subdir and --dotfiles-path only take effect together with
+// --dotfiles-repo.
+func dotfilesSource(cfg *config.Config) (dotfiles.Source, error) {
+	flags := dotfiles.Source{
+		URL:    cfg.DotfilesRepo,
+		Ref:    cfg.DotfilesRef,
+		Subdir: cfg.DotfilesSubdir,
+		Path:   cfg.DotfilesPath,
+	}
+	if flags.URL == "" && (flags.Ref != "" || flags.Subdir != "" || flags.Path != "") {
+		ui.Warn("--dotfiles-ref, --dotfiles-subdir and --dotfiles-path are ignored without --dotfiles-repo")
+	}
+
+	candidates := []dotfiles.Source{flags}
+	if snap := cfg.SnapshotDotfiles; snap != nil {
+		candidates = append(candidates, dotfiles.Source{URL: snap.RepoURL, Ref: snap.Ref, Subdir: snap.Subdir, Path: snap.Path})
+	}
+	if rc := cfg.RemoteConfig; rc != nil {
+		candidates = append(candidates, dotfiles.Source{URL: rc.DotfilesRepo, Ref: rc.DotfilesRef, Subdir: rc.DotfilesSubdir})
+	}
+	candidates = append(candidates, dotfiles.SourceFromEnv())
+
+	saved, err := dotfiles.LoadSource()
+	if err != nil {
+		return dotfiles.Source{}, err
+	}
+	candidates = append(candidates, saved)
+
+	for _, source := range candidates {
+		if source.URL != "" {
+			return source, source.Validate()
+		}
+	}
+	return dotfiles.Source{}, nil
+}
+
 func stepShell(cfg *config.Config) error {
 	if cfg.Shell == "skip" {
 		return nil
@@ -680,6 +796,19 @@ func showCompletion(cfg *config.Config) {
 	fmt.Println()
 }
 
+// IncompleteError is returned when a restore went ahead but some of its
+// steps failed. The failures have already been reported.
+type IncompleteError struct {
+	Failed []string
+}
+
+func (e *IncompleteError) Error() string {
+	return "some steps failed: " + strings.Join(e.Failed, ", ")
+}
+
+// RunFromSnapshot installs everything in cfg that came from a snapshot.
+// A failing step doesn't stop the others; if any failed, an
+// *IncompleteError lists them.
 func RunFromSnapshot(cfg *config.Config) error {
 	fmt.Println()
 	ui.Header("OpenBoot — Restore from Snapshot")
@@ -690,47 +819,93 @@ func RunFromSnapshot(cfg *config.Config) error {
 		fmt.Println()
 	}
 
+	var failed []string
+
 	if len(cfg.SnapshotTaps) > 0 {
 		ui.Info(fmt.Sprintf("Adding %d taps...", len(cfg.SnapshotTaps)))
 		fmt.Println()
 		if err := brew.InstallTaps(cfg.SnapshotTaps, cfg.DryRun); err != nil {
 			ui.Warn(fmt.Sprintf("Some taps failed: %v", err))
+			failed = append(failed, "taps")
 		}
 		fmt.Println()
 	}
 
 	if err := stepInstallPackages(cfg); err != nil {
-		return err
+		ui.Error(fmt.Sprintf("Some packages failed: %v", err))
+		failed = append(failed, "packages")
 	}
 
 	if err := stepInstallNpmWithRetry(cfg); err != nil {
 		ui.Error(fmt.Sprintf("npm package installation failed: %v", err))
+		failed = append(failed, "npm")
 	}
 
 	if cfg.SnapshotGit != nil {
 		if err := stepRestoreGit(cfg); err != nil {
 			ui.Error(fmt.Sprintf("Git restore failed: %v", err))
+			failed = append(failed, "git")
 		}
 	}
 
 	if cfg.SnapshotShell != nil && cfg.SnapshotShell.OhMyZsh {
 		if err := stepRestoreShell(cfg); err != nil {
 			ui.Error(fmt.Sprintf("Shell restore failed: %v", err))
+			failed = append(failed, "shell")
 		}
 	} else {
 		if err := stepShell(cfg); err != nil {
 			ui.Error(fmt.Sprintf("Shell setup failed: %v", err))
+			failed = append(failed, "shell")
+		}
+	}
+
+	if cfg.SnapshotDotfiles != nil && cfg.SnapshotDotfiles.RepoURL != "" {
+		if err := stepDotfiles(cfg); err != nil {
+			ui.Error(fmt.Sprintf("Dotfiles setup failed: %v", err))
+			failed = append(failed, "dotfiles")
 		}
 	}
 
-	if err := stepMacOS(cfg); err != nil {
+	macosStep := stepMacOS
+	if len(cfg.SnapshotMacOSPrefs) > 0 {
+		macosStep = stepRestoreMacOS
+	}
+	if err := macosStep(cfg); err != nil {
 		ui.Error(fmt.Sprintf("macOS configuration failed: %v", err))
+		failed = append(failed, "macos")
 	}
 
+	if len(failed) > 0 {
+		return &IncompleteError{Failed: failed}
+	}
 	showCompletion(cfg)
 	return nil
 }
 
+// InstallPackages installs the taps and packages selected in cfg without
+// touching git, shell, dotfiles or macOS settings.
+func InstallPackages(cfg *config.Config) error {
+	var errs []error
+	if len(cfg.SnapshotTaps) > 0 {
+		if err := brew.InstallTaps(cfg.SnapshotTaps, cfg.DryRun); err != nil {
+			errs = append(errs, err)
+		}
+		fmt.Println()
+	}
+
+	if err := stepInstallPackages(cfg); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(categorizeSelectedPackages(cfg).npm) > 0 {
+		if err := stepInstallNpmWithRetry(cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func stepRestoreGit(cfg *config.Config) error {
 	ui.Header("Restore: Git Configuration")
 	fmt.Println()
@@ -809,6 +984,35 @@ func stepRestoreShell(cfg *config.Config) error {
 	return nil
 }
 
+// stepRestoreMacOS applies the macOS preferences captured in the snapshot
+// instead of openboot's defaults.
+func stepRestoreMacOS(cfg *config.Config) error {
+	if cfg.Macos == "skip" {
+		return nil
+	}
+
+	ui.Header("Restore: macOS Preferences")
+	fmt.Println()
+
+	prefs := make([]macos.Preference, 0, len(cfg.SnapshotMacOSPrefs))
+	for _, p := range cfg.SnapshotMacOSPrefs {
+		prefs = append(prefs, macos.Preference{Domain: p.Domain, Key: p.Key, Type: p.Type, Value: p.Value, Desc: p.Desc})
+	}
+	ui.Info(fmt.Sprintf("Applying %d preferences from snapshot", len(prefs)))
+	fmt.Println()
+
+	if err := macos.Configure(prefs, cfg.DryRun); err != nil {
+		return err
+	}
+
+	if !cfg.DryRun {
+		ui.Success("macOS preferences restored")
+		macos.RestartAffectedApps(cfg.DryRun)
+	}
+	fmt.Println()
+	return nil
+}
+
 func runUpdate(cfg *config.Config) error {
 	ui.Header("OpenBoot Update")
 	fmt.Println()
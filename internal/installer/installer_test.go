@@ -6,7 +6,9 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/openbootdotdev/openboot/internal/brew"
 	"github.com/openbootdotdev/openboot/internal/config"
+	"github.com/openbootdotdev/openboot/internal/dotfiles"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -179,6 +181,19 @@ func TestInstallState_MarkAndCheck(t *testing.T) {
 	assert.False(t, state.isNpmInstalled("eslint"))
 }
 
+func TestMarkInstalled_SkipsFailedPackages(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	state := newInstallState()
+	markInstalled(state, []string{"git", "broken"}, []string{"firefox", "slack"},
+		&brew.InstallError{Formulae: []string{"broken"}, Casks: []string{"slack"}})
+
+	assert.True(t, state.isFormulaInstalled("git"))
+	assert.False(t, state.isFormulaInstalled("broken"))
+	assert.True(t, state.isCaskInstalled("firefox"))
+	assert.False(t, state.isCaskInstalled("slack"))
+}
+
 func TestInstallState_SaveAndLoad(t *testing.T) {
 	tmpDir := t.TempDir()
 	t.Setenv("HOME", tmpDir)
@@ -371,3 +386,60 @@ func TestInstallTimeConstants(t *testing.T) {
 	assert.Equal(t, 30, estimatedSecondsPerCask)
 	assert.Equal(t, 5, estimatedSecondsPerNpm)
 }
+
+func TestDotfilesSource_Precedence(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("OPENBOOT_DOTFILES", "https://example.com/env.git")
+	t.Setenv("OPENBOOT_DOTFILES_REF", "")
+	t.Setenv("OPENBOOT_DOTFILES_SUBDIR", "")
+	t.Setenv("OPENBOOT_DOTFILES_PATH", "")
+
+	cfg := &config.Config{
+		DotfilesRef: "flag-ref",
+		SnapshotDotfiles: &config.SnapshotDotfilesConfig{
+			RepoURL: "https://example.com/snapshot.git",
+			Ref:     "snapshot-ref",
+			Subdir:  "home",
+		},
+		RemoteConfig: &config.RemoteConfig{
+			DotfilesRepo:   "https://example.com/remote.git",
+			DotfilesSubdir: "remote",
+		},
+	}
+
+	source, err := dotfilesSource(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/snapshot.git", source.URL)
+	assert.Equal(t, "snapshot-ref", source.Ref, "ref flags without --dotfiles-repo are ignored")
+	assert.Equal(t, "home", source.Subdir)
+
+	cfg.DotfilesRepo = "https://example.com/flag.git"
+	source, err = dotfilesSource(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, dotfiles.Source{URL: "https://example.com/flag.git", Ref: "flag-ref"}, source)
+
+	source, err = dotfilesSource(&config.Config{RemoteConfig: &config.RemoteConfig{
+		DotfilesRepo: "https://example.com/remote.git",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, dotfiles.Source{URL: "https://example.com/remote.git"}, source)
+
+	t.Setenv("OPENBOOT_DOTFILES_REF", "env-ref")
+	source, err = dotfilesSource(&config.Config{SnapshotDotfiles: &config.SnapshotDotfilesConfig{
+		RepoURL: "https://example.com/snapshot.git",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, dotfiles.Source{URL: "https://example.com/snapshot.git"}, source,
+		"fields from a lower-precedence source never mix into the chosen one")
+
+	source, err = dotfilesSource(&config.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, dotfiles.Source{URL: "https://example.com/env.git", Ref: "env-ref"}, source)
+}
+
+func TestDotfilesSource_RejectsEscapingSubdir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	_, err := dotfilesSource(&config.Config{DotfilesRepo: "https://example.com/d.git", DotfilesSubdir: "../x"})
+	assert.Error(t, err)
+}
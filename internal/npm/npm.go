@@ -1,6 +1,7 @@
 package npm
 
 import (
+	"encoding/json"
 	"fmt"
 	"os/exec"
 	"strconv"
@@ -68,6 +69,61 @@ func GetInstalledPackages() (map[string]bool, error) {
 	return packages, nil
 }
 
+// GetInstalledVersions returns the version of each global package.
+func GetInstalledVersions() (map[string]string, error) {
+	output, err := exec.Command("npm", "list", "-g", "--depth=0", "--json").Output()
+	if err != nil && len(output) == 0 {
+		return nil, err
+	}
+
+	var result struct {
+		Dependencies map[string]struct {
+			Version string `json:"version"`
+		} `json:"dependencies"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse npm list: %w", err)
+	}
+
+	versions := make(map[string]string, len(result.Dependencies))
+	for name, dep := range result.Dependencies {
+		versions[name] = dep.Version
+	}
+	return versions, nil
+}
+
+// GlobalRoot returns the directory global packages are installed in.
+func GlobalRoot() (string, error) {
+	out, err := exec.Command("npm", "root", "-g").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CacheDir returns npm's cache directory.
+func CacheDir() (string, error) {
+	out, err := exec.Command("npm", "config", "get", "cache").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// VerifyCache runs 'npm cache verify', which garbage-collects cache
+// entries nothing refers to any more.
+func VerifyCache(dryRun bool) error {
+	if dryRun {
+		ui.Info("Would garbage-collect the npm cache:")
+		fmt.Println("    npm cache verify")
+		return nil
+	}
+	if output, err := exec.Command("npm", "cache", "verify").CombinedOutput(); err != nil {
+		return fmt.Errorf("npm cache verify: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
 func Install(packages []string, dryRun bool) error {
 	if len(packages) == 0 {
 		return nil
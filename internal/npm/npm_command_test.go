@@ -57,6 +57,19 @@ func TestGetInstalledPackages_ParsesList(t *testing.T) {
 	assert.False(t, packages["corepack"])
 }
 
+func TestGetInstalledVersions_ParsesJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"echo '{\"dependencies\":{\"typescript\":{\"version\":\"5.4.5\"},\"@scope/pkg\":{\"version\":\"1.0.0\"}}}'\n" +
+		"exit 1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "npm"), []byte(script), 0755))
+	t.Setenv("PATH", tmpDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	versions, err := GetInstalledVersions()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"typescript": "5.4.5", "@scope/pkg": "1.0.0"}, versions)
+}
+
 func TestGetNodeVersion_ParsesVersion(t *testing.T) {
 	setupFakeNodeNpm(t)
 
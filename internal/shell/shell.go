@@ -1,6 +1,7 @@
 package shell
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
@@ -202,3 +203,58 @@ source $ZSH/oh-my-zsh.sh
 
 	return nil
 }
+
+// pluginsLineRe matches an uncommented plugins=(...) line, skipping the
+// "# Example format: plugins=(...)" comment in the stock Oh-My-Zsh .zshrc.
+var pluginsLineRe = regexp.MustCompile(`(?m)^[ \t]*plugins=\(([^)]*)\)`)
+
+// EnabledPlugins returns the Oh-My-Zsh plugins listed in ~/.zshrc. A
+// missing .zshrc has none.
+func EnabledPlugins() ([]string, error) {
+	home, err := system.HomeDir()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filepath.Join(home, ".zshrc"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .zshrc: %w", err)
+	}
+	m := pluginsLineRe.FindStringSubmatch(string(content))
+	if m == nil {
+		return nil, nil
+	}
+	return strings.Fields(m[1]), nil
+}
+
+// SetPlugins replaces the first plugins=(...) line in ~/.zshrc, which must
+// already have one.
+func SetPlugins(plugins []string, dryRun bool) error {
+	line := fmt.Sprintf("plugins=(%s)", strings.Join(plugins, " "))
+	if dryRun {
+		fmt.Printf("[DRY-RUN] Would set %s\n", line)
+		return nil
+	}
+
+	home, err := system.HomeDir()
+	if err != nil {
+		return err
+	}
+	zshrcPath := filepath.Join(home, ".zshrc")
+	content, err := os.ReadFile(zshrcPath)
+	if err != nil {
+		return fmt.Errorf("failed to read .zshrc: %w", err)
+	}
+	loc := pluginsLineRe.FindIndex(content)
+	if loc == nil {
+		return fmt.Errorf("no plugins=(...) line in %s", zshrcPath)
+	}
+	start := loc[0] + bytes.Index(content[loc[0]:loc[1]], []byte("plugins="))
+	updated := append(append(append([]byte{}, content[:start]...), line...), content[loc[1]:]...)
+	if err := os.WriteFile(zshrcPath, updated, 0644); err != nil {
+		return fmt.Errorf("failed to write .zshrc: %w", err)
+	}
+	return nil
+}
@@ -267,3 +267,69 @@ source $ZSH/oh-my-zsh.sh
 	assert.Contains(t, string(result), `ZSH_THEME="robbyrussell"`)
 	assert.Contains(t, string(result), `plugins=(git)`)
 }
+
+func TestEnabledPlugins(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	plugins, err := EnabledPlugins()
+	require.NoError(t, err)
+	assert.Empty(t, plugins)
+
+	zshrc := "export ZSH=\"$HOME/.oh-my-zsh\"\nplugins=(git  docker\n  kubectl)\nsource $ZSH/oh-my-zsh.sh\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpHome, ".zshrc"), []byte(zshrc), 0644))
+
+	plugins, err = EnabledPlugins()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"git", "docker", "kubectl"}, plugins)
+}
+
+func TestSetPlugins(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	zshrcPath := filepath.Join(tmpHome, ".zshrc")
+	require.NoError(t, os.WriteFile(zshrcPath, []byte("ZSH_THEME=\"robbyrussell\"\nplugins=(git docker)\n"), 0644))
+
+	require.NoError(t, SetPlugins([]string{"git"}, true))
+	content, err := os.ReadFile(zshrcPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "plugins=(git docker)")
+
+	require.NoError(t, SetPlugins([]string{"git"}, false))
+	content, err = os.ReadFile(zshrcPath)
+	require.NoError(t, err)
+	assert.Equal(t, "ZSH_THEME=\"robbyrussell\"\nplugins=(git)\n", string(content))
+
+	require.NoError(t, os.WriteFile(zshrcPath, []byte("ZSH_THEME=\"robbyrussell\"\n"), 0644))
+	assert.Error(t, SetPlugins([]string{"git"}, false))
+}
+
+func TestPlugins_IgnoresExampleComment(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	zshrcPath := filepath.Join(tmpHome, ".zshrc")
+	zshrc := `export ZSH="$HOME/.oh-my-zsh"
+ZSH_THEME="robbyrussell"
+
+# Which plugins would you like to load?
+# Standard plugins can be found in $ZSH/plugins/
+# Custom plugins may be added to $ZSH_CUSTOM/plugins/
+# Example format: plugins=(rails git textmate ruby lighthouse)
+# Add wisely, as too many plugins slow down shell startup.
+plugins=(git docker kubectl)
+
+source $ZSH/oh-my-zsh.sh
+`
+	require.NoError(t, os.WriteFile(zshrcPath, []byte(zshrc), 0644))
+
+	plugins, err := EnabledPlugins()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"git", "docker", "kubectl"}, plugins)
+
+	require.NoError(t, SetPlugins([]string{"git", "kubectl"}, false))
+	content, err := os.ReadFile(zshrcPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "# Example format: plugins=(rails git textmate ruby lighthouse)\n")
+	assert.Contains(t, string(content), "\nplugins=(git kubectl)\n")
+	assert.NotContains(t, string(content), "docker")
+}
@@ -1,92 +1,33 @@
 package snapshot
 
 import (
+	"bytes"
+	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/openbootdotdev/openboot/internal/dotfiles"
 	"github.com/openbootdotdev/openboot/internal/macos"
 	"github.com/openbootdotdev/openboot/internal/system"
 )
 
+// Capture takes a snapshot of the current system without reporting progress.
 func Capture() (*Snapshot, error) {
-	hostname, err := os.Hostname()
-	if err != nil {
-		hostname = "unknown"
-	}
-
-	formulae, err := CaptureFormulae()
-	if err != nil {
-		return nil, err
-	}
-
-	casks, err := CaptureCasks()
-	if err != nil {
-		return nil, err
-	}
-
-	taps, err := CaptureTaps()
-	if err != nil {
-		return nil, err
-	}
-
-	npmPkgs, err := CaptureNpm()
-	if err != nil {
-		return nil, err
-	}
-
-	prefs, err := CaptureMacOSPrefs()
-	if err != nil {
-		return nil, err
-	}
-
-	shellSnap, err := CaptureShell()
-	if err != nil {
-		return nil, err
-	}
-
-	gitSnap, err := CaptureGit()
-	if err != nil {
-		return nil, err
-	}
-
-	devTools, err := CaptureDevTools()
-	if err != nil {
-		return nil, err
-	}
-
-	return &Snapshot{
-		Version:    1,
-		CapturedAt: time.Now(),
-		Hostname:   hostname,
-		Packages: PackageSnapshot{
-			Formulae: formulae,
-			Casks:    casks,
-			Taps:     taps,
-			Npm:      npmPkgs,
-		},
-		MacOSPrefs:    prefs,
-		Shell:         *shellSnap,
-		Git:           *gitSnap,
-		DevTools:      devTools,
-		MatchedPreset: "",
-		CatalogMatch: CatalogMatch{
-			Matched:   []string{},
-			Unmatched: []string{},
-			MatchRate: 0,
-		},
-	}, nil
+	return CaptureWithProgress(nil)
 }
 
 // ScanStep represents progress information for a single capture step.
 type ScanStep struct {
 	Name   string `json:"name"`   // e.g. "Homebrew Formulae"
-	Index  int    `json:"index"`  // 0-7
-	Total  int    `json:"total"`  // always 8
+	Index  int    `json:"index"`  // 0-9
+	Total  int    `json:"total"`  // always 10
 	Status string `json:"status"` // "scanning" | "done" | "error"
 	Count  int    `json:"count"`  // items found (only meaningful on "done")
 }
@@ -97,6 +38,13 @@ type captureStep struct {
 	count   func(interface{}) int
 }
 
+// captureConcurrency bounds how many capture steps, domain exports and
+// version probes run at once.
+var captureConcurrency = 4
+
+// CaptureWithProgress runs the capture steps concurrently, at most
+// captureConcurrency at a time. callback is never called concurrently, but
+// several steps can be "scanning" at once.
 func CaptureWithProgress(callback func(step ScanStep)) (*Snapshot, error) {
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -111,26 +59,35 @@ func CaptureWithProgress(callback func(step ScanStep)) (*Snapshot, error) {
 		{"macOS Preferences", func() (interface{}, error) { return CaptureMacOSPrefs() }, func(v interface{}) int { return len(v.([]MacOSPref)) }},
 		{"Shell Environment", func() (interface{}, error) { return CaptureShell() }, func(v interface{}) int { return 1 }},
 		{"Git Configuration", func() (interface{}, error) { return CaptureGit() }, func(v interface{}) int { return 1 }},
+		{"Dotfiles", func() (interface{}, error) { return CaptureDotfiles() }, func(v interface{}) int { return 1 }},
 		{"Dev Tools", func() (interface{}, error) { return CaptureDevTools() }, func(v interface{}) int { return len(v.([]DevTool)) }},
+		{"System Info", func() (interface{}, error) { return CaptureMachine() }, func(v interface{}) int { return 1 }},
 	}
 
-	results := make([]interface{}, len(steps))
-	for i, step := range steps {
-		if callback != nil {
-			callback(ScanStep{Name: step.name, Index: i, Total: len(steps), Status: "scanning", Count: 0})
+	var callbackMu sync.Mutex
+	report := func(step ScanStep) {
+		if callback == nil {
+			return
 		}
+		callbackMu.Lock()
+		defer callbackMu.Unlock()
+		callback(step)
+	}
+
+	results := make([]interface{}, len(steps))
+	forEachParallel(len(steps), captureConcurrency, func(i int) {
+		step := steps[i]
+		report(ScanStep{Name: step.name, Index: i, Total: len(steps), Status: "scanning", Count: 0})
 
 		result, err := step.capture()
 		results[i] = result
 
-		if callback != nil {
-			if err != nil {
-				callback(ScanStep{Name: step.name, Index: i, Total: len(steps), Status: "error", Count: 0})
-			} else {
-				callback(ScanStep{Name: step.name, Index: i, Total: len(steps), Status: "done", Count: step.count(result)})
-			}
+		if err != nil {
+			report(ScanStep{Name: step.name, Index: i, Total: len(steps), Status: "error", Count: 0})
+		} else {
+			report(ScanStep{Name: step.name, Index: i, Total: len(steps), Status: "done", Count: step.count(result)})
 		}
-	}
+	})
 
 	formulae := results[0].([]string)
 	casks := results[1].([]string)
@@ -139,12 +96,15 @@ func CaptureWithProgress(callback func(step ScanStep)) (*Snapshot, error) {
 	prefs := results[4].([]MacOSPref)
 	shellSnap := results[5].(*ShellSnapshot)
 	gitSnap := results[6].(*GitSnapshot)
-	devTools := results[7].([]DevTool)
+	dotfilesSnap := results[7].(*DotfilesSnapshot)
+	devTools := results[8].([]DevTool)
+	machine := results[9].(*MachineInfo)
 
 	return &Snapshot{
-		Version:    1,
+		Version:    CurrentVersion,
 		CapturedAt: time.Now(),
 		Hostname:   hostname,
+		Machine:    machine,
 		Packages: PackageSnapshot{
 			Formulae: formulae,
 			Casks:    casks,
@@ -154,6 +114,7 @@ func CaptureWithProgress(callback func(step ScanStep)) (*Snapshot, error) {
 		MacOSPrefs:    prefs,
 		Shell:         *shellSnap,
 		Git:           *gitSnap,
+		Dotfiles:      *dotfilesSnap,
 		DevTools:      devTools,
 		MatchedPreset: "",
 		CatalogMatch: CatalogMatch{
@@ -232,20 +193,37 @@ func CaptureTaps() ([]string, error) {
 	return captureBrewList("tap")
 }
 
+// CaptureMacOSPrefs reads the tracked preferences with one `defaults
+// export` per domain rather than one `defaults read` per key.
 func CaptureMacOSPrefs() ([]MacOSPref, error) {
-	prefs := []MacOSPref{}
+	var domains []string
+	seen := map[string]bool{}
+	for _, p := range macos.DefaultPreferences {
+		if !seen[p.Domain] {
+			seen[p.Domain] = true
+			domains = append(domains, p.Domain)
+		}
+	}
 
+	values := make([]map[string]string, len(domains))
+	forEachParallel(len(domains), captureConcurrency, func(i int) {
+		values[i] = exportDefaults(domains[i])
+	})
+	byDomain := make(map[string]map[string]string, len(domains))
+	for i, d := range domains {
+		byDomain[d] = values[i]
+	}
+
+	prefs := []MacOSPref{}
 	for _, p := range macos.DefaultPreferences {
-		cmd := exec.Command("defaults", "read", p.Domain, p.Key)
-		output, err := cmd.Output()
-		if err != nil {
+		value, ok := byDomain[p.Domain][p.Key]
+		if !ok {
 			continue
 		}
-
 		prefs = append(prefs, MacOSPref{
 			Domain: p.Domain,
 			Key:    p.Key,
-			Value:  strings.TrimSpace(string(output)),
+			Value:  value,
 			Desc:   p.Desc,
 		})
 	}
@@ -253,6 +231,80 @@ func CaptureMacOSPrefs() ([]MacOSPref, error) {
 	return prefs, nil
 }
 
+// exportDefaults returns a domain's top-level values formatted the way
+// `defaults read` prints them. It returns nil if the domain can't be read.
+func exportDefaults(domain string) map[string]string {
+	output, err := exec.Command("defaults", "export", domain, "-").Output()
+	if err != nil {
+		return nil
+	}
+	values, err := parseDefaultsExport(output)
+	if err != nil {
+		return nil
+	}
+	return values
+}
+
+// parseDefaultsExport parses the XML plist written by `defaults export`.
+// Only scalar values are returned: booleans as 1/0 like `defaults read`,
+// numbers and strings as written.
+func parseDefaultsExport(data []byte) (map[string]string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+
+	values := map[string]string{}
+	depth := 0
+	var key string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse defaults export: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			// The plist is <plist><dict>...</dict></plist>; top-level keys
+			// and values sit at depth 3.
+			if depth != 3 {
+				continue
+			}
+			switch t.Name.Local {
+			case "key":
+				var k string
+				if err := dec.DecodeElement(&k, &t); err != nil {
+					return nil, fmt.Errorf("failed to parse defaults export: %w", err)
+				}
+				key = k
+				depth--
+			case "true", "false":
+				if err := dec.Skip(); err != nil {
+					return nil, fmt.Errorf("failed to parse defaults export: %w", err)
+				}
+				depth--
+				if t.Name.Local == "true" {
+					values[key] = "1"
+				} else {
+					values[key] = "0"
+				}
+			case "string", "integer", "real":
+				var v string
+				if err := dec.DecodeElement(&v, &t); err != nil {
+					return nil, fmt.Errorf("failed to parse defaults export: %w", err)
+				}
+				depth--
+				values[key] = strings.TrimSpace(v)
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return values, nil
+}
+
 func CaptureShell() (*ShellSnapshot, error) {
 	snap := &ShellSnapshot{
 		Default: os.Getenv("SHELL"),
@@ -308,6 +360,34 @@ func CaptureGit() (*GitSnapshot, error) {
 	return snap, nil
 }
 
+// CaptureDotfiles records the remote, ref and layout of the cloned dotfiles
+// repo. It returns an empty snapshot when no repo is cloned.
+func CaptureDotfiles() (*DotfilesSnapshot, error) {
+	snap := &DotfilesSnapshot{}
+
+	source, err := dotfiles.CurrentSource()
+	if err != nil || source == nil {
+		return snap, nil
+	}
+
+	snap.RepoURL = source.URL
+	snap.Ref = source.Ref
+	snap.Subdir = source.Subdir
+	if source.Path != "" {
+		if home, err := system.HomeDir(); err == nil {
+			if dir, err := source.CloneDir(); err == nil {
+				if rel, err := filepath.Rel(home, dir); err == nil && !strings.HasPrefix(rel, "..") {
+					snap.Path = "~/" + rel
+				} else {
+					snap.Path = dir
+				}
+			}
+		}
+	}
+
+	return snap, nil
+}
+
 // RestoreGit sets git user.name/email if not already configured.
 func RestoreGit(git GitSnapshot) error {
 	existingName, existingEmail := system.GetExistingGitConfig()
@@ -345,29 +425,54 @@ var devToolCommands = []struct {
 }
 
 func CaptureDevTools() ([]DevTool, error) {
-	tools := []DevTool{}
-
-	for _, dt := range devToolCommands {
+	found := make([]*DevTool, len(devToolCommands))
+	forEachParallel(len(devToolCommands), captureConcurrency, func(i int) {
+		dt := devToolCommands[i]
 		if _, err := exec.LookPath(dt.name); err != nil {
-			continue
+			return
 		}
 
 		cmd := exec.Command(dt.name, dt.args...)
 		output, err := cmd.Output()
 		if err != nil {
-			continue
+			return
 		}
 
-		version := parseVersion(dt.name, strings.TrimSpace(string(output)))
-		tools = append(tools, DevTool{
+		found[i] = &DevTool{
 			Name:    dt.name,
-			Version: version,
-		})
-	}
+			Version: parseVersion(dt.name, strings.TrimSpace(string(output))),
+		}
+	})
 
+	tools := []DevTool{}
+	for _, t := range found {
+		if t != nil {
+			tools = append(tools, *t)
+		}
+	}
 	return tools, nil
 }
 
+// forEachParallel calls fn(0..n-1) with at most limit calls in flight and
+// waits for all of them.
+func forEachParallel(n, limit int, fn func(i int)) {
+	if limit < 1 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
 func sanitizePath(path string) string {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -1,7 +1,9 @@
 package snapshot
 
 import (
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -307,3 +309,95 @@ func TestParseVersion_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestCaptureDotfiles_NoRepo(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	snap, err := CaptureDotfiles()
+	assert.NoError(t, err)
+	assert.Equal(t, DotfilesSnapshot{}, *snap)
+}
+
+func TestParseDefaultsExport(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>AppleShowAllExtensions</key>
+	<true/>
+	<key>NSAutomaticSpellingCorrectionEnabled</key>
+	<false/>
+	<key>KeyRepeat</key>
+	<integer>2</integer>
+	<key>tilesize</key>
+	<real>36.5</real>
+	<key>AppleShowScrollBars</key>
+	<string>Always</string>
+	<key>persistent-apps</key>
+	<array>
+		<dict>
+			<key>nested</key>
+			<string>ignored</string>
+		</dict>
+	</array>
+	<key>orientation</key>
+	<string>left</string>
+</dict>
+</plist>`)
+
+	values, err := parseDefaultsExport(data)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"AppleShowAllExtensions":               "1",
+		"NSAutomaticSpellingCorrectionEnabled": "0",
+		"KeyRepeat":                            "2",
+		"tilesize":                             "36.5",
+		"AppleShowScrollBars":                  "Always",
+		"orientation":                          "left",
+	}, values)
+}
+
+func TestForEachParallel_BoundsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	done := make([]bool, 20)
+
+	forEachParallel(len(done), 3, func(i int) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		done[i] = true
+		mu.Unlock()
+	})
+
+	assert.LessOrEqual(t, peak, 3)
+	assert.Greater(t, peak, 1)
+	for i, d := range done {
+		assert.True(t, d, "item %d not run", i)
+	}
+}
+
+func TestCaptureWithProgress_ReportsEveryStep(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	inCallback := false
+	var done []int
+	snap, err := CaptureWithProgress(func(step ScanStep) {
+		assert.False(t, inCallback, "callback must not be called concurrently")
+		inCallback = true
+		defer func() { inCallback = false }()
+		if step.Status == "done" {
+			done = append(done, step.Index)
+		}
+	})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, snap)
+	assert.ElementsMatch(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, done)
+}
@@ -0,0 +1,240 @@
+package snapshot
+
+import "sort"
+
+// ListDiff holds the items only present on one side of a comparison.
+type ListDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+func (d ListDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// ValueChange is a single value that differs between two snapshots.
+type ValueChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// PrefChange is a macOS preference that was added, removed or changed. From
+// is empty for added preferences and To is empty for removed ones.
+type PrefChange struct {
+	Domain string `json:"domain"`
+	Key    string `json:"key"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// ToolChange is a dev tool whose version differs. From or To is empty when
+// the tool is only installed on one side.
+type ToolChange struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type ShellDiff struct {
+	Default *ValueChange `json:"default,omitempty"`
+	OhMyZsh *ValueChange `json:"oh_my_zsh,omitempty"`
+	Theme   *ValueChange `json:"theme,omitempty"`
+	Plugins ListDiff     `json:"plugins"`
+}
+
+type GitDiff struct {
+	UserName  *ValueChange `json:"user_name,omitempty"`
+	UserEmail *ValueChange `json:"user_email,omitempty"`
+}
+
+type DotfilesDiff struct {
+	RepoURL *ValueChange `json:"repo_url,omitempty"`
+	Ref     *ValueChange `json:"ref,omitempty"`
+}
+
+// MachineDiff holds the differences between the machines two snapshots
+// were captured on. It explains other differences rather than being one,
+// so it doesn't count towards SnapshotDiff.Empty.
+type MachineDiff struct {
+	OSVersion       *ValueChange `json:"os_version,omitempty"`
+	OSBuild         *ValueChange `json:"os_build,omitempty"`
+	Arch            *ValueChange `json:"arch,omitempty"`
+	HomebrewPrefix  *ValueChange `json:"homebrew_prefix,omitempty"`
+	HomebrewVersion *ValueChange `json:"homebrew_version,omitempty"`
+	OpenbootVersion *ValueChange `json:"openboot_version,omitempty"`
+	CLTVersion      *ValueChange `json:"clt_version,omitempty"`
+	Rosetta         *ValueChange `json:"rosetta,omitempty"`
+}
+
+func (d MachineDiff) Empty() bool {
+	return d.OSVersion == nil && d.OSBuild == nil && d.Arch == nil &&
+		d.HomebrewPrefix == nil && d.HomebrewVersion == nil &&
+		d.OpenbootVersion == nil && d.CLTVersion == nil && d.Rosetta == nil
+}
+
+// SnapshotDiff describes how snapshot B differs from snapshot A. "Added"
+// means present in B but not in A.
+type SnapshotDiff struct {
+	Formulae   ListDiff     `json:"formulae"`
+	Casks      ListDiff     `json:"casks"`
+	Taps       ListDiff     `json:"taps"`
+	Npm        ListDiff     `json:"npm"`
+	MacOSPrefs []PrefChange `json:"macos_prefs"`
+	Shell      ShellDiff    `json:"shell"`
+	Git        GitDiff      `json:"git"`
+	Dotfiles   DotfilesDiff `json:"dotfiles"`
+	DevTools   []ToolChange `json:"dev_tools"`
+	Machine    MachineDiff  `json:"machine"`
+}
+
+// Empty reports whether the two snapshots are equivalent.
+func (d *SnapshotDiff) Empty() bool {
+	return d.Formulae.Empty() && d.Casks.Empty() && d.Taps.Empty() && d.Npm.Empty() &&
+		len(d.MacOSPrefs) == 0 &&
+		d.Shell.Default == nil && d.Shell.OhMyZsh == nil && d.Shell.Theme == nil && d.Shell.Plugins.Empty() &&
+		d.Git.UserName == nil && d.Git.UserEmail == nil &&
+		d.Dotfiles.RepoURL == nil && d.Dotfiles.Ref == nil &&
+		len(d.DevTools) == 0
+}
+
+// Diff compares two snapshots.
+func Diff(a, b *Snapshot) *SnapshotDiff {
+	d := &SnapshotDiff{
+		Formulae:   diffLists(a.Packages.Formulae, b.Packages.Formulae),
+		Casks:      diffLists(a.Packages.Casks, b.Packages.Casks),
+		Taps:       diffLists(a.Packages.Taps, b.Packages.Taps),
+		Npm:        diffLists(a.Packages.Npm, b.Packages.Npm),
+		MacOSPrefs: diffPrefs(a.MacOSPrefs, b.MacOSPrefs),
+		DevTools:   diffTools(a.DevTools, b.DevTools),
+	}
+
+	d.Shell.Default = diffValue(a.Shell.Default, b.Shell.Default)
+	d.Shell.OhMyZsh = diffValue(yesNo(a.Shell.OhMyZsh), yesNo(b.Shell.OhMyZsh))
+	d.Shell.Theme = diffValue(a.Shell.Theme, b.Shell.Theme)
+	d.Shell.Plugins = diffLists(a.Shell.Plugins, b.Shell.Plugins)
+
+	d.Git.UserName = diffValue(a.Git.UserName, b.Git.UserName)
+	d.Git.UserEmail = diffValue(a.Git.UserEmail, b.Git.UserEmail)
+
+	d.Dotfiles.RepoURL = diffValue(a.Dotfiles.RepoURL, b.Dotfiles.RepoURL)
+	d.Dotfiles.Ref = diffValue(a.Dotfiles.Ref, b.Dotfiles.Ref)
+
+	d.Machine = diffMachine(a.Machine, b.Machine)
+
+	return d
+}
+
+// diffMachine compares machine info, treating a missing side as unknown
+// rather than empty so old snapshots don't show every field as changed.
+func diffMachine(a, b *MachineInfo) MachineDiff {
+	if a == nil || b == nil {
+		return MachineDiff{}
+	}
+	return MachineDiff{
+		OSVersion:       diffValue(a.OSVersion, b.OSVersion),
+		OSBuild:         diffValue(a.OSBuild, b.OSBuild),
+		Arch:            diffValue(a.Arch, b.Arch),
+		HomebrewPrefix:  diffValue(a.HomebrewPrefix, b.HomebrewPrefix),
+		HomebrewVersion: diffValue(a.HomebrewVersion, b.HomebrewVersion),
+		OpenbootVersion: diffValue(a.OpenbootVersion, b.OpenbootVersion),
+		CLTVersion:      diffValue(a.CLTVersion, b.CLTVersion),
+		Rosetta:         diffValue(yesNo(a.Rosetta), yesNo(b.Rosetta)),
+	}
+}
+
+func diffLists(a, b []string) ListDiff {
+	inA := make(map[string]bool, len(a))
+	for _, item := range a {
+		inA[item] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, item := range b {
+		inB[item] = true
+	}
+
+	d := ListDiff{Added: []string{}, Removed: []string{}}
+	for item := range inB {
+		if !inA[item] {
+			d.Added = append(d.Added, item)
+		}
+	}
+	for item := range inA {
+		if !inB[item] {
+			d.Removed = append(d.Removed, item)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	return d
+}
+
+func diffValue(a, b string) *ValueChange {
+	if a == b {
+		return nil
+	}
+	return &ValueChange{From: a, To: b}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func diffPrefs(a, b []MacOSPref) []PrefChange {
+	type prefKey struct{ domain, key string }
+
+	before := make(map[prefKey]string, len(a))
+	for _, p := range a {
+		before[prefKey{p.Domain, p.Key}] = p.Value
+	}
+	after := make(map[prefKey]string, len(b))
+	for _, p := range b {
+		after[prefKey{p.Domain, p.Key}] = p.Value
+	}
+
+	changes := []PrefChange{}
+	for k, to := range after {
+		if from, ok := before[k]; !ok || from != to {
+			changes = append(changes, PrefChange{Domain: k.domain, Key: k.key, From: from, To: to})
+		}
+	}
+	for k, from := range before {
+		if _, ok := after[k]; !ok {
+			changes = append(changes, PrefChange{Domain: k.domain, Key: k.key, From: from})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Domain != changes[j].Domain {
+			return changes[i].Domain < changes[j].Domain
+		}
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+func diffTools(a, b []DevTool) []ToolChange {
+	before := make(map[string]string, len(a))
+	for _, t := range a {
+		before[t.Name] = t.Version
+	}
+	after := make(map[string]string, len(b))
+	for _, t := range b {
+		after[t.Name] = t.Version
+	}
+
+	changes := []ToolChange{}
+	for name, to := range after {
+		if from := before[name]; from != to {
+			changes = append(changes, ToolChange{Name: name, From: from, To: to})
+		}
+	}
+	for name, from := range before {
+		if _, ok := after[name]; !ok {
+			changes = append(changes, ToolChange{Name: name, From: from})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
@@ -0,0 +1,100 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDiff_Identical tests that identical snapshots produce an empty diff.
+func TestDiff_Identical(t *testing.T) {
+	snap := &Snapshot{
+		Packages: PackageSnapshot{Formulae: []string{"git"}, Casks: []string{"firefox"}},
+		Shell:    ShellSnapshot{Default: "/bin/zsh", OhMyZsh: true, Plugins: []string{"git"}},
+		Git:      GitSnapshot{UserName: "Ada", UserEmail: "ada@example.com"},
+		DevTools: []DevTool{{Name: "go", Version: "1.22.0"}},
+	}
+
+	d := Diff(snap, snap)
+	assert.True(t, d.Empty())
+}
+
+// TestDiff_Packages tests added and removed packages are sorted per type.
+func TestDiff_Packages(t *testing.T) {
+	a := &Snapshot{Packages: PackageSnapshot{
+		Formulae: []string{"git", "wget", "curl"},
+		Taps:     []string{"homebrew/cask-fonts"},
+		Npm:      []string{"typescript"},
+	}}
+	b := &Snapshot{Packages: PackageSnapshot{
+		Formulae: []string{"git", "jq", "fzf"},
+		Casks:    []string{"docker"},
+		Npm:      []string{"typescript"},
+	}}
+
+	d := Diff(a, b)
+	assert.Equal(t, []string{"fzf", "jq"}, d.Formulae.Added)
+	assert.Equal(t, []string{"curl", "wget"}, d.Formulae.Removed)
+	assert.Equal(t, []string{"docker"}, d.Casks.Added)
+	assert.Equal(t, []string{"homebrew/cask-fonts"}, d.Taps.Removed)
+	assert.True(t, d.Npm.Empty())
+	assert.False(t, d.Empty())
+}
+
+// TestDiff_Settings tests prefs, shell, git and dev tool changes.
+func TestDiff_Settings(t *testing.T) {
+	a := &Snapshot{
+		MacOSPrefs: []MacOSPref{
+			{Domain: "com.apple.dock", Key: "autohide", Value: "0"},
+			{Domain: "com.apple.finder", Key: "ShowPathbar", Value: "1"},
+		},
+		Shell:    ShellSnapshot{Default: "/bin/zsh", OhMyZsh: true, Theme: "robbyrussell", Plugins: []string{"git"}},
+		Git:      GitSnapshot{UserName: "Ada", UserEmail: "ada@example.com"},
+		DevTools: []DevTool{{Name: "go", Version: "1.21.0"}, {Name: "ruby", Version: "3.2.0"}},
+	}
+	b := &Snapshot{
+		MacOSPrefs: []MacOSPref{
+			{Domain: "com.apple.dock", Key: "autohide", Value: "1"},
+			{Domain: "NSGlobalDomain", Key: "KeyRepeat", Value: "2"},
+		},
+		Shell:    ShellSnapshot{Default: "/bin/zsh", OhMyZsh: true, Theme: "agnoster", Plugins: []string{"git", "docker"}},
+		Git:      GitSnapshot{UserName: "Ada", UserEmail: "ada@work.example.com"},
+		DevTools: []DevTool{{Name: "go", Version: "1.22.0"}, {Name: "node", Version: "20.0.0"}},
+	}
+
+	d := Diff(a, b)
+
+	assert.Equal(t, []PrefChange{
+		{Domain: "NSGlobalDomain", Key: "KeyRepeat", From: "", To: "2"},
+		{Domain: "com.apple.dock", Key: "autohide", From: "0", To: "1"},
+		{Domain: "com.apple.finder", Key: "ShowPathbar", From: "1", To: ""},
+	}, d.MacOSPrefs)
+
+	assert.Nil(t, d.Shell.Default)
+	assert.Nil(t, d.Shell.OhMyZsh)
+	assert.Equal(t, &ValueChange{From: "robbyrussell", To: "agnoster"}, d.Shell.Theme)
+	assert.Equal(t, []string{"docker"}, d.Shell.Plugins.Added)
+
+	assert.Nil(t, d.Git.UserName)
+	assert.Equal(t, &ValueChange{From: "ada@example.com", To: "ada@work.example.com"}, d.Git.UserEmail)
+
+	assert.Equal(t, []ToolChange{
+		{Name: "go", From: "1.21.0", To: "1.22.0"},
+		{Name: "node", From: "", To: "20.0.0"},
+		{Name: "ruby", From: "3.2.0", To: ""},
+	}, d.DevTools)
+}
+
+func TestDiff_Machine(t *testing.T) {
+	a := &Snapshot{Machine: &MachineInfo{OSVersion: "14.5", Arch: "x86_64", HomebrewPrefix: "/usr/local"}}
+	b := &Snapshot{Machine: &MachineInfo{OSVersion: "15.0", Arch: "arm64", HomebrewPrefix: "/opt/homebrew", Rosetta: true}}
+
+	d := Diff(a, b)
+	assert.Equal(t, &ValueChange{From: "14.5", To: "15.0"}, d.Machine.OSVersion)
+	assert.Equal(t, &ValueChange{From: "x86_64", To: "arm64"}, d.Machine.Arch)
+	assert.Equal(t, &ValueChange{From: "no", To: "yes"}, d.Machine.Rosetta)
+	assert.Nil(t, d.Machine.CLTVersion)
+	assert.True(t, d.Empty(), "machine differences alone don't make snapshots different")
+
+	assert.True(t, Diff(&Snapshot{}, b).Machine.Empty(), "a snapshot without machine info isn't compared")
+}
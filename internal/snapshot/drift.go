@@ -0,0 +1,107 @@
+package snapshot
+
+// PackageDrift lists packages the desired state expects but the machine
+// lacks, and packages the machine has that the desired state doesn't.
+type PackageDrift struct {
+	Missing []string `json:"missing"`
+	Extra   []string `json:"extra"`
+}
+
+func (d PackageDrift) Count() int {
+	return len(d.Missing) + len(d.Extra)
+}
+
+// Drift describes how a live machine differs from a desired snapshot. Only
+// settings present in the desired snapshot are compared, so a remote config
+// that lists packages alone never reports shell or preference drift. For
+// value changes From is the desired value and To the live one.
+type Drift struct {
+	Formulae   PackageDrift `json:"formulae"`
+	Casks      PackageDrift `json:"casks"`
+	Taps       PackageDrift `json:"taps"`
+	Npm        PackageDrift `json:"npm"`
+	MacOSPrefs []PrefChange `json:"macos_prefs"`
+	Shell      ShellDiff    `json:"shell"`
+	Git        GitDiff      `json:"git"`
+	Dotfiles   DotfilesDiff `json:"dotfiles"`
+	DevTools   []ToolChange `json:"dev_tools"`
+}
+
+// Count returns the number of drifted items.
+func (d *Drift) Count() int {
+	n := d.Formulae.Count() + d.Casks.Count() + d.Taps.Count() + d.Npm.Count() +
+		len(d.MacOSPrefs) + len(d.DevTools) +
+		len(d.Shell.Plugins.Added) + len(d.Shell.Plugins.Removed)
+	for _, c := range []*ValueChange{
+		d.Shell.Default, d.Shell.OhMyZsh, d.Shell.Theme,
+		d.Git.UserName, d.Git.UserEmail,
+		d.Dotfiles.RepoURL, d.Dotfiles.Ref,
+	} {
+		if c != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// DetectDrift compares the live machine against the desired snapshot.
+func DetectDrift(desired, live *Snapshot) *Drift {
+	diff := Diff(desired, live)
+	d := &Drift{
+		Formulae:   PackageDrift{Missing: diff.Formulae.Removed, Extra: diff.Formulae.Added},
+		Casks:      PackageDrift{Missing: diff.Casks.Removed, Extra: diff.Casks.Added},
+		Taps:       PackageDrift{Missing: diff.Taps.Removed, Extra: diff.Taps.Added},
+		Npm:        PackageDrift{Missing: diff.Npm.Removed, Extra: diff.Npm.Added},
+		MacOSPrefs: []PrefChange{},
+		DevTools:   []ToolChange{},
+	}
+
+	for _, p := range diff.MacOSPrefs {
+		if p.From != "" {
+			d.MacOSPrefs = append(d.MacOSPrefs, p)
+		}
+	}
+
+	if desired.Shell.Default != "" || desired.Shell.OhMyZsh {
+		d.Shell = diff.Shell
+		if desired.Shell.Default == "" {
+			d.Shell.Default = nil
+		}
+	} else {
+		d.Shell.Plugins = ListDiff{Added: []string{}, Removed: []string{}}
+	}
+
+	if desired.Git.UserName != "" {
+		d.Git.UserName = diff.Git.UserName
+	}
+	if desired.Git.UserEmail != "" {
+		d.Git.UserEmail = diff.Git.UserEmail
+	}
+
+	if desired.Dotfiles.RepoURL != "" {
+		d.Dotfiles = diff.Dotfiles
+		if desired.Dotfiles.Ref == "" {
+			d.Dotfiles.Ref = nil
+		}
+	}
+
+	for _, t := range diff.DevTools {
+		if t.From != "" {
+			d.DevTools = append(d.DevTools, t)
+		}
+	}
+
+	return d
+}
+
+// IgnoreInstalledFormulae drops missing formulae that are installed, just
+// not as leaves (for example because another formula depends on them).
+func (d *Drift) IgnoreInstalledFormulae(installed map[string]bool) {
+	missing := []string{}
+	for _, name := range d.Formulae.Missing {
+		if !installed[name] {
+			missing = append(missing, name)
+		}
+	}
+	d.Formulae.Missing = missing
+}
@@ -0,0 +1,76 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDetectDrift_PackagesOnly tests that a packages-only desired state
+// ignores live settings it doesn't describe.
+func TestDetectDrift_PackagesOnly(t *testing.T) {
+	desired := &Snapshot{Packages: PackageSnapshot{
+		Formulae: []string{"git", "jq"},
+		Casks:    []string{"docker"},
+	}}
+	live := &Snapshot{
+		Packages: PackageSnapshot{
+			Formulae: []string{"git", "wget"},
+			Casks:    []string{"docker"},
+		},
+		MacOSPrefs: []MacOSPref{{Domain: "com.apple.dock", Key: "autohide", Value: "1"}},
+		Shell:      ShellSnapshot{Default: "/bin/zsh", OhMyZsh: true, Theme: "agnoster"},
+		Git:        GitSnapshot{UserName: "Ada", UserEmail: "ada@example.com"},
+		DevTools:   []DevTool{{Name: "go", Version: "1.22.0"}},
+	}
+
+	d := DetectDrift(desired, live)
+	assert.Equal(t, []string{"jq"}, d.Formulae.Missing)
+	assert.Equal(t, []string{"wget"}, d.Formulae.Extra)
+	assert.Empty(t, d.MacOSPrefs)
+	assert.Nil(t, d.Shell.Theme)
+	assert.Nil(t, d.Git.UserEmail)
+	assert.Empty(t, d.DevTools)
+	assert.Equal(t, 2, d.Count())
+}
+
+// TestDetectDrift_Settings tests drift in settings the desired state describes.
+func TestDetectDrift_Settings(t *testing.T) {
+	desired := &Snapshot{
+		MacOSPrefs: []MacOSPref{
+			{Domain: "com.apple.dock", Key: "autohide", Value: "0"},
+			{Domain: "com.apple.finder", Key: "ShowPathbar", Value: "1"},
+		},
+		Shell:    ShellSnapshot{Default: "/bin/zsh", OhMyZsh: true, Theme: "robbyrussell", Plugins: []string{"git"}},
+		Git:      GitSnapshot{UserEmail: "ada@example.com"},
+		DevTools: []DevTool{{Name: "go", Version: "1.22.0"}},
+	}
+	live := &Snapshot{
+		MacOSPrefs: []MacOSPref{
+			{Domain: "com.apple.dock", Key: "autohide", Value: "1"},
+			{Domain: "NSGlobalDomain", Key: "KeyRepeat", Value: "2"},
+		},
+		Shell:    ShellSnapshot{Default: "/bin/zsh", OhMyZsh: true, Theme: "robbyrussell", Plugins: []string{"git", "docker"}},
+		Git:      GitSnapshot{UserName: "Ada", UserEmail: "ada@work.example.com"},
+		DevTools: []DevTool{{Name: "go", Version: "1.22.0"}, {Name: "node", Version: "20.0.0"}},
+	}
+
+	d := DetectDrift(desired, live)
+	assert.Equal(t, []PrefChange{
+		{Domain: "com.apple.dock", Key: "autohide", From: "0", To: "1"},
+		{Domain: "com.apple.finder", Key: "ShowPathbar", From: "1", To: ""},
+	}, d.MacOSPrefs)
+	assert.Equal(t, []string{"docker"}, d.Shell.Plugins.Added)
+	assert.Nil(t, d.Git.UserName)
+	assert.Equal(t, &ValueChange{From: "ada@example.com", To: "ada@work.example.com"}, d.Git.UserEmail)
+	assert.Empty(t, d.DevTools)
+	assert.Equal(t, 4, d.Count())
+}
+
+// TestDrift_IgnoreInstalledFormulae tests that dependency-installed formulae
+// are not reported missing.
+func TestDrift_IgnoreInstalledFormulae(t *testing.T) {
+	d := &Drift{Formulae: PackageDrift{Missing: []string{"openssl@3", "jq"}}}
+	d.IgnoreInstalledFormulae(map[string]bool{"openssl@3": true})
+	assert.Equal(t, []string{"jq"}, d.Formulae.Missing)
+}
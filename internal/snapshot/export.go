@@ -0,0 +1,115 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openbootdotdev/openboot/internal/macos"
+)
+
+// An Exporter writes a snapshot in another tool's format, so a machine can
+// be set up without openboot.
+type Exporter interface {
+	// Name is the format name passed to 'openboot snapshot --export'.
+	Name() string
+	// Write writes snap to w. It must not depend on the current machine,
+	// so the same snapshot always exports the same way.
+	Write(w io.Writer, snap *Snapshot) error
+}
+
+var exporters = map[string]Exporter{}
+
+// RegisterExporter makes e available by name. Registering a name twice
+// panics.
+func RegisterExporter(e Exporter) {
+	if _, ok := exporters[e.Name()]; ok {
+		panic(fmt.Sprintf("snapshot: exporter %q registered twice", e.Name()))
+	}
+	exporters[e.Name()] = e
+}
+
+// LookupExporter returns the exporter for format.
+func LookupExporter(format string) (Exporter, error) {
+	if e, ok := exporters[format]; ok {
+		return e, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q (want %s)", format, strings.Join(ExportFormats(), ", "))
+}
+
+// ExportFormats lists the registered format names, sorted.
+func ExportFormats() []string {
+	names := make([]string, 0, len(exporters))
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// exportHeader is the comment every export starts with, without the
+// comment marker.
+func exportHeader(snap *Snapshot) string {
+	header := "Generated by openboot from a snapshot captured " + snap.CapturedAt.UTC().Format(time.RFC3339)
+	if desc := snap.Machine.Describe(); desc != "" {
+		header += " on " + desc
+	}
+	return header
+}
+
+// PrefType returns the type of a captured preference: "bool", "int",
+// "float" or "string". Known preferences take the type recorded in
+// macos.DefaultPreferences; only unknown keys are guessed from the value,
+// since captured booleans are stored as "1" and "0".
+func PrefType(pref MacOSPref) string {
+	for _, known := range macos.DefaultPreferences {
+		if known.Domain == pref.Domain && known.Key == pref.Key {
+			return known.Type
+		}
+	}
+	return prefValueType(pref.Value)
+}
+
+// prefValueType guesses the type of a preference value, which is stored as
+// a string.
+func prefValueType(value string) string {
+	switch value {
+	case "true", "false":
+		return "bool"
+	}
+	switch {
+	case intPattern.MatchString(value):
+		return "int"
+	case floatPattern.MatchString(value):
+		return "float"
+	default:
+		return "string"
+	}
+}
+
+// prefBool reports whether a bool preference is on. Captured values are
+// "1" and "0"; hand-edited snapshots may use "true" and "false".
+func prefBool(value string) bool {
+	switch strings.ToLower(value) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+var (
+	intPattern   = regexp.MustCompile(`^-?[0-9]+$`)
+	floatPattern = regexp.MustCompile(`^-?[0-9]+\.[0-9]+$`)
+)
+
+// dotfilesDir is where an export clones the dotfiles repo, either
+// absolute or starting with "~/".
+func dotfilesDir(d DotfilesSnapshot) string {
+	if d.Path != "" {
+		return d.Path
+	}
+	return "~/.dotfiles"
+}
@@ -0,0 +1,147 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterExporter(ansibleExporter{})
+}
+
+// ansibleExporter writes a playbook for the local machine using the
+// community.general homebrew, npm, git_config and osx_defaults modules.
+type ansibleExporter struct{}
+
+func (ansibleExporter) Name() string { return "ansible" }
+
+func (ansibleExporter) Write(w io.Writer, snap *Snapshot) error {
+	var b bytes.Buffer
+	p := func(format string, args ...any) { fmt.Fprintf(&b, format+"\n", args...) }
+	list := func(indent string, items []string) {
+		for _, item := range items {
+			p("%s- %s", indent, yamlString(item))
+		}
+	}
+
+	p("# %s", exportHeader(snap))
+	p("# Run with: ansible-playbook playbook.yml")
+	p("# Needs the community.general collection: ansible-galaxy collection install community.general")
+	p("- name: Restore openboot snapshot")
+	p("  hosts: localhost")
+	p("  connection: local")
+	p("  gather_facts: false")
+	p("  tasks:")
+
+	if len(snap.Packages.Taps) > 0 {
+		p("    - name: Add Homebrew taps")
+		p("      community.general.homebrew_tap:")
+		p("        name:")
+		list("          ", snap.Packages.Taps)
+	}
+	if len(snap.Packages.Formulae) > 0 {
+		p("    - name: Install Homebrew formulae")
+		p("      community.general.homebrew:")
+		p("        name:")
+		list("          ", snap.Packages.Formulae)
+		p("        state: present")
+	}
+	if len(snap.Packages.Casks) > 0 {
+		p("    - name: Install Homebrew casks")
+		p("      community.general.homebrew_cask:")
+		p("        name:")
+		list("          ", snap.Packages.Casks)
+		p("        state: present")
+	}
+	if len(snap.Packages.Npm) > 0 {
+		p("    - name: Install npm global packages")
+		p("      community.general.npm:")
+		p(`        name: "{{ item }}"`)
+		p("        global: true")
+		p("      loop:")
+		list("        ", snap.Packages.Npm)
+	}
+
+	for _, c := range []struct{ key, value string }{
+		{"user.name", snap.Git.UserName},
+		{"user.email", snap.Git.UserEmail},
+	} {
+		if c.value == "" {
+			continue
+		}
+		p("    - name: Set git %s", c.key)
+		p("      community.general.git_config:")
+		p("        name: %s", c.key)
+		p("        scope: global")
+		p("        value: %s", yamlString(c.value))
+	}
+
+	if snap.Shell.OhMyZsh {
+		p("    - name: Install Oh-My-Zsh")
+		p("      ansible.builtin.shell: >-")
+		p(`        sh -c "$(curl -fsSL https://raw.githubusercontent.com/ohmyzsh/ohmyzsh/master/tools/install.sh)" "" --unattended`)
+		p("      args:")
+		p("        creates: ~/.oh-my-zsh")
+		if snap.Shell.Theme != "" {
+			p("    - name: Set the Oh-My-Zsh theme")
+			p("      ansible.builtin.lineinfile:")
+			p("        path: ~/.zshrc")
+			p("        regexp: '^ZSH_THEME='")
+			p("        line: %s", yamlString(`ZSH_THEME="`+snap.Shell.Theme+`"`))
+		}
+		if len(snap.Shell.Plugins) > 0 {
+			p("    - name: Set the Oh-My-Zsh plugins")
+			p("      ansible.builtin.lineinfile:")
+			p("        path: ~/.zshrc")
+			p("        regexp: '^plugins=\\('")
+			p("        line: %s", yamlString("plugins=("+strings.Join(snap.Shell.Plugins, " ")+")"))
+		}
+	}
+
+	if snap.Dotfiles.RepoURL != "" {
+		p("    - name: Clone dotfiles")
+		p("      ansible.builtin.git:")
+		p("        repo: %s", yamlString(snap.Dotfiles.RepoURL))
+		p("        dest: %s", yamlString(dotfilesDir(snap.Dotfiles)))
+		if snap.Dotfiles.Ref != "" {
+			p("        version: %s", yamlString(snap.Dotfiles.Ref))
+		}
+		p("        update: false")
+	}
+
+	if len(snap.MacOSPrefs) > 0 {
+		p("    - name: Set macOS preferences")
+		p("      community.general.osx_defaults:")
+		p(`        domain: "{{ item.domain }}"`)
+		p(`        key: "{{ item.key }}"`)
+		p(`        type: "{{ item.type }}"`)
+		p(`        value: "{{ item.value }}"`)
+		p("      loop:")
+		for _, pref := range snap.MacOSPrefs {
+			p("        - domain: %s", yamlString(pref.Domain))
+			p("          key: %s", yamlString(pref.Key))
+			typ, value := PrefType(pref), pref.Value
+			if typ == "bool" {
+				value = fmt.Sprint(prefBool(pref.Value))
+			}
+			p("          type: %s", typ)
+			p("          value: %s", yamlString(value))
+		}
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// yamlString quotes s as a YAML double-quoted scalar, which accepts JSON
+// string syntax.
+func yamlString(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,130 @@
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterExporter(nixDarwinExporter{})
+}
+
+// nixDarwinExporter writes a darwin-configuration.nix. Homebrew packages
+// and preferences map onto nix-darwin options; npm globals, git identity,
+// Oh-My-Zsh and dotfiles have no nix-darwin equivalent and are left as
+// comments.
+type nixDarwinExporter struct{}
+
+func (nixDarwinExporter) Name() string { return "nix-darwin" }
+
+func (nixDarwinExporter) Write(w io.Writer, snap *Snapshot) error {
+	var b bytes.Buffer
+	p := func(format string, args ...any) { fmt.Fprintf(&b, format+"\n", args...) }
+	list := func(name string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		p("    %s = [", name)
+		for _, item := range items {
+			p("      %s", nixString(item))
+		}
+		p("    ];")
+	}
+
+	p("# %s", exportHeader(snap))
+	p("# Apply with: darwin-rebuild switch")
+	p("{ pkgs, ... }:")
+	p("")
+	p("{")
+	p("  homebrew = {")
+	p("    enable = true;")
+	p(`    onActivation.cleanup = "none";`)
+	list("taps", snap.Packages.Taps)
+	list("brews", snap.Packages.Formulae)
+	list("casks", snap.Packages.Casks)
+	p("  };")
+
+	if len(snap.MacOSPrefs) > 0 {
+		p("")
+		p("  system.defaults.CustomUserPreferences = {")
+		var domains []string
+		byDomain := map[string][]MacOSPref{}
+		for _, pref := range snap.MacOSPrefs {
+			if _, ok := byDomain[pref.Domain]; !ok {
+				domains = append(domains, pref.Domain)
+			}
+			byDomain[pref.Domain] = append(byDomain[pref.Domain], pref)
+		}
+		for _, domain := range domains {
+			p("    %s = {", nixString(domain))
+			for _, pref := range byDomain[domain] {
+				p("      %s = %s;", nixString(pref.Key), nixValue(pref))
+			}
+			p("    };")
+		}
+		p("  };")
+	}
+
+	if snap.Shell.Default != "" && strings.HasSuffix(snap.Shell.Default, "zsh") {
+		p("")
+		p("  programs.zsh.enable = true;")
+	}
+
+	var notes []string
+	if len(snap.Packages.Npm) > 0 {
+		notes = append(notes, "npm globals: npm install -g "+strings.Join(snap.Packages.Npm, " "))
+	}
+	if snap.Git.UserName != "" {
+		notes = append(notes, "git user.name: "+snap.Git.UserName)
+	}
+	if snap.Git.UserEmail != "" {
+		notes = append(notes, "git user.email: "+snap.Git.UserEmail)
+	}
+	if snap.Shell.OhMyZsh {
+		note := "Oh-My-Zsh"
+		if snap.Shell.Theme != "" {
+			note += ", theme " + snap.Shell.Theme
+		}
+		if len(snap.Shell.Plugins) > 0 {
+			note += ", plugins " + strings.Join(snap.Shell.Plugins, " ")
+		}
+		notes = append(notes, note)
+	}
+	if snap.Dotfiles.RepoURL != "" {
+		note := "dotfiles: " + snap.Dotfiles.RepoURL
+		if snap.Dotfiles.Ref != "" {
+			note += " (" + snap.Dotfiles.Ref + ")"
+		}
+		notes = append(notes, note)
+	}
+	if len(notes) > 0 {
+		p("")
+		p("  # Not managed by nix-darwin; set these up by hand or with home-manager:")
+		for _, note := range notes {
+			p("  #   %s", strings.ReplaceAll(note, "\n", " "))
+		}
+	}
+
+	p("}")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// nixString quotes s as a Nix string, escaping interpolation.
+func nixString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "${", `\${`, "\n", `\n`).Replace(s) + `"`
+}
+
+func nixValue(pref MacOSPref) string {
+	switch PrefType(pref) {
+	case "bool":
+		return fmt.Sprint(prefBool(pref.Value))
+	case "int", "float":
+		return pref.Value
+	default:
+		return nixString(pref.Value)
+	}
+}
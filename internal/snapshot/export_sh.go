@@ -0,0 +1,167 @@
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterExporter(shExporter{})
+}
+
+// shExporter writes a self-contained bash script. Every step checks
+// before it changes anything, so the script can be re-run safely.
+type shExporter struct{}
+
+func (shExporter) Name() string { return "sh" }
+
+func (shExporter) Write(w io.Writer, snap *Snapshot) error {
+	var b bytes.Buffer
+	p := func(format string, args ...any) { fmt.Fprintf(&b, format+"\n", args...) }
+
+	p("#!/usr/bin/env bash")
+	p("# %s", exportHeader(snap))
+	p("# Safe to re-run: anything already installed or set is left alone.")
+	p("set -euo pipefail")
+	p("")
+	p(`if ! command -v brew >/dev/null 2>&1; then`)
+	p(`  echo "==> Installing Homebrew"`)
+	p(`  NONINTERACTIVE=1 /bin/bash -c "$(curl -fsSL https://raw.githubusercontent.com/Homebrew/install/HEAD/install.sh)"`)
+	p(`  if [ -x /opt/homebrew/bin/brew ]; then eval "$(/opt/homebrew/bin/brew shellenv)"; else eval "$(/usr/local/bin/brew shellenv)"; fi`)
+	p(`fi`)
+
+	if len(snap.Packages.Taps) > 0 {
+		p("")
+		p(`echo "==> Homebrew taps"`)
+		p("for tap in %s; do", shWords(snap.Packages.Taps))
+		p(`  brew tap | grep -qxF "$tap" || brew tap "$tap"`)
+		p("done")
+	}
+	if len(snap.Packages.Formulae) > 0 {
+		p("")
+		p(`echo "==> Homebrew formulae"`)
+		p("for formula in %s; do", shWords(snap.Packages.Formulae))
+		p(`  brew list --formula "${formula##*/}" >/dev/null 2>&1 || brew install "$formula"`)
+		p("done")
+	}
+	if len(snap.Packages.Casks) > 0 {
+		p("")
+		p(`echo "==> Homebrew casks"`)
+		p("for cask in %s; do", shWords(snap.Packages.Casks))
+		p(`  brew list --cask "${cask##*/}" >/dev/null 2>&1 || brew install --cask "$cask"`)
+		p("done")
+	}
+	if len(snap.Packages.Npm) > 0 {
+		p("")
+		p(`echo "==> npm global packages"`)
+		p(`if command -v npm >/dev/null 2>&1; then`)
+		p("  for pkg in %s; do", shWords(snap.Packages.Npm))
+		p(`    npm ls -g --depth=0 "$pkg" >/dev/null 2>&1 || npm install -g "$pkg"`)
+		p("  done")
+		p("else")
+		p(`  echo "npm not found, skipping npm packages" >&2`)
+		p("fi")
+	}
+
+	if snap.Git.UserName != "" || snap.Git.UserEmail != "" {
+		p("")
+		p(`echo "==> Git identity"`)
+		if snap.Git.UserName != "" {
+			p(`[ -n "$(git config --global user.name || true)" ] || git config --global user.name %s`, shQuote(snap.Git.UserName))
+		}
+		if snap.Git.UserEmail != "" {
+			p(`[ -n "$(git config --global user.email || true)" ] || git config --global user.email %s`, shQuote(snap.Git.UserEmail))
+		}
+	}
+
+	if snap.Shell.OhMyZsh {
+		p("")
+		p(`echo "==> Oh-My-Zsh"`)
+		p(`if [ ! -d "$HOME/.oh-my-zsh" ]; then`)
+		p(`  sh -c "$(curl -fsSL https://raw.githubusercontent.com/ohmyzsh/ohmyzsh/master/tools/install.sh)" "" --unattended`)
+		p("fi")
+		if snap.Shell.Theme != "" {
+			p(`sed -i '' 's|^ZSH_THEME=.*|ZSH_THEME=%s|' "$HOME/.zshrc"`, shQuoteInSingle(sedEscape(`"`+snap.Shell.Theme+`"`)))
+		}
+		if len(snap.Shell.Plugins) > 0 {
+			p(`sed -i '' 's|^plugins=(.*|plugins=(%s)|' "$HOME/.zshrc"`, shQuoteInSingle(sedEscape(strings.Join(snap.Shell.Plugins, " "))))
+		}
+	}
+
+	if snap.Dotfiles.RepoURL != "" {
+		p("")
+		p(`echo "==> Dotfiles"`)
+		p("dotfiles=%s", shPath(dotfilesDir(snap.Dotfiles)))
+		p(`if [ ! -d "$dotfiles" ]; then`)
+		p(`  git clone %s "$dotfiles"`, shQuote(snap.Dotfiles.RepoURL))
+		if snap.Dotfiles.Ref != "" {
+			p(`  git -C "$dotfiles" checkout %s`, shQuote(snap.Dotfiles.Ref))
+		}
+		p("fi")
+		p(`echo "Dotfiles cloned to $dotfiles; they are not linked into your home directory."`)
+	}
+
+	if len(snap.MacOSPrefs) > 0 {
+		p("")
+		p(`echo "==> macOS preferences"`)
+		p(`set_default() {`)
+		p(`  [ "$(defaults read "$1" "$2" 2>/dev/null || true)" = "$4" ] || defaults write "$1" "$2" "-$3" "$4"`)
+		p(`}`)
+		for _, pref := range snap.MacOSPrefs {
+			typ, value := PrefType(pref), pref.Value
+			if typ == "bool" {
+				// defaults read prints booleans as 1 and 0.
+				value = "0"
+				if prefBool(pref.Value) {
+					value = "1"
+				}
+			}
+			line := fmt.Sprintf("set_default %s %s %s %s", shQuote(pref.Domain), shQuote(pref.Key), typ, shQuote(value))
+			if pref.Desc != "" {
+				line += "  # " + strings.ReplaceAll(pref.Desc, "\n", " ")
+			}
+			p("%s", line)
+		}
+		p(`killall Dock Finder SystemUIServer >/dev/null 2>&1 || true`)
+	}
+
+	p("")
+	p(`echo "==> Done"`)
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// shQuote quotes s as a single bash word.
+func shQuote(s string) string {
+	return "'" + shQuoteInSingle(s) + "'"
+}
+
+// shQuoteInSingle escapes s for use inside an existing single-quoted
+// string.
+func shQuoteInSingle(s string) string {
+	return strings.ReplaceAll(s, "'", `'\''`)
+}
+
+func shWords(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = shQuote(item)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// sedEscape escapes s for the replacement side of a sed s||| command.
+func sedEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "|", `\|`, "&", `\&`).Replace(s)
+}
+
+// shPath quotes a path, expanding a leading "~/" to $HOME.
+func shPath(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return `"$HOME"/` + shQuote(rest)
+	}
+	return shQuote(path)
+}
@@ -0,0 +1,124 @@
+package snapshot
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func exportFixture() *Snapshot {
+	return &Snapshot{
+		Version:    CurrentVersion,
+		CapturedAt: time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC),
+		Hostname:   "ada-mbp",
+		Machine:    &MachineInfo{OSVersion: "14.5", OSBuild: "23F79", Arch: "arm64", HomebrewVersion: "4.3.1"},
+		Packages: PackageSnapshot{
+			Formulae: []string{"git", "ripgrep", "hashicorp/tap/terraform"},
+			Casks:    []string{"firefox", "visual-studio-code"},
+			Taps:     []string{"hashicorp/tap"},
+			Npm:      []string{"typescript", "@vue/cli"},
+		},
+		MacOSPrefs: []MacOSPref{
+			{Domain: "com.apple.dock", Key: "autohide", Value: "1", Desc: "Auto-hide the Dock"},
+			{Domain: "com.apple.dock", Key: "tilesize", Value: "48"},
+			{Domain: "NSGlobalDomain", Key: "KeyRepeat", Value: "2"},
+			{Domain: "com.apple.screencapture", Key: "location", Value: "~/Screenshots"},
+		},
+		Shell:    ShellSnapshot{Default: "/bin/zsh", OhMyZsh: true, Theme: "powerlevel10k/powerlevel10k", Plugins: []string{"git", "docker"}},
+		Git:      GitSnapshot{UserName: "Ada O'Brien", UserEmail: "ada@example.com"},
+		Dotfiles: DotfilesSnapshot{RepoURL: "https://github.com/ada/dotfiles", Ref: "main"},
+	}
+}
+
+func TestExporters_Golden(t *testing.T) {
+	files := map[string]string{
+		"sh":         "export.sh.golden",
+		"ansible":    "export.ansible.yml.golden",
+		"nix-darwin": "export.darwin-configuration.nix.golden",
+	}
+	assert.Equal(t, []string{"ansible", "nix-darwin", "sh"}, ExportFormats())
+
+	for format, file := range files {
+		t.Run(format, func(t *testing.T) {
+			e, err := LookupExporter(format)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, e.Write(&buf, exportFixture()))
+
+			path := filepath.Join("testdata", file)
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+			}
+			want, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), buf.String(), "%s is out of date — run 'go test ./internal/snapshot -run Golden -update'", path)
+		})
+	}
+}
+
+func TestExporters_EmptySnapshot(t *testing.T) {
+	for _, format := range ExportFormats() {
+		e, err := LookupExporter(format)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		assert.NoError(t, e.Write(&buf, &Snapshot{}), format)
+		assert.NotEmpty(t, buf.String(), format)
+	}
+}
+
+func TestShExport_IsValidBash(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	var buf bytes.Buffer
+	require.NoError(t, shExporter{}.Write(&buf, exportFixture()))
+
+	cmd := exec.Command("bash", "-n")
+	cmd.Stdin = &buf
+	out, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
+
+func TestLookupExporter_Unknown(t *testing.T) {
+	_, err := LookupExporter("puppet")
+	assert.ErrorContains(t, err, `unknown export format "puppet" (want ansible, nix-darwin, sh)`)
+}
+
+func TestPrefType(t *testing.T) {
+	// Known keys take the recorded type, even for captured "1"/"0" booleans.
+	assert.Equal(t, "bool", PrefType(MacOSPref{Domain: "com.apple.dock", Key: "autohide", Value: "1"}))
+	assert.Equal(t, "int", PrefType(MacOSPref{Domain: "com.apple.dock", Key: "tilesize", Value: "48"}))
+	assert.Equal(t, "string", PrefType(MacOSPref{Domain: "com.apple.finder", Key: "FXPreferredViewStyle", Value: "Nlsv"}))
+
+	// Unknown keys are guessed from the value.
+	unknown := func(value string) string {
+		return PrefType(MacOSPref{Domain: "com.example.app", Key: "Setting", Value: value})
+	}
+	assert.Equal(t, "bool", unknown("true"))
+	assert.Equal(t, "int", unknown("1"))
+	assert.Equal(t, "int", unknown("-3"))
+	assert.Equal(t, "float", unknown("0.5"))
+	assert.Equal(t, "string", unknown("NaN"))
+	assert.Equal(t, "string", unknown("Nlsv"))
+	assert.Equal(t, "string", unknown(""))
+}
+
+func TestShQuote(t *testing.T) {
+	assert.Equal(t, `'it'\''s'`, shQuote("it's"))
+	assert.Equal(t, `"$HOME"/'.dotfiles'`, shPath("~/.dotfiles"))
+	assert.Equal(t, `'/opt/dots'`, shPath("/opt/dots"))
+}
+
+func TestNixString(t *testing.T) {
+	assert.Equal(t, `"a \"b\" \${c}"`, nixString(`a "b" ${c}`))
+}
@@ -0,0 +1,215 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openbootdotdev/openboot/internal/system"
+)
+
+// historyIDFormat names history entries after the time they were saved.
+// Entries saved within the same second get a "-N" suffix; see historyIDLess.
+const historyIDFormat = "20060102-150405"
+
+// LatestRef resolves to the most recent history entry.
+const LatestRef = "latest"
+
+// HistoryEntry is one saved snapshot under ~/.openboot/snapshots.
+type HistoryEntry struct {
+	ID      string
+	Path    string
+	SavedAt time.Time
+}
+
+// RetentionPolicy decides which history entries prune keeps. An entry is
+// kept if any rule keeps it; the newest entry is always kept.
+type RetentionPolicy struct {
+	KeepLast   int
+	KeepDaily  int
+	KeepWeekly int
+}
+
+// DefaultRetention is applied every time a snapshot is saved.
+var DefaultRetention = RetentionPolicy{KeepLast: 10, KeepDaily: 7, KeepWeekly: 4}
+
+// HistoryDir returns where snapshot history is kept.
+func HistoryDir() string {
+	home, err := system.HomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".openboot", "snapshots")
+}
+
+// ListHistory returns saved snapshots, newest first.
+func ListHistory() ([]HistoryEntry, error) {
+	dir := HistoryDir()
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot history: %w", err)
+	}
+
+	var entries []HistoryEntry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		id := strings.TrimSuffix(f.Name(), ".json")
+		savedAt, err := time.ParseInLocation(historyIDFormat, id[:min(len(id), len(historyIDFormat))], time.Local)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, HistoryEntry{ID: id, Path: filepath.Join(dir, f.Name()), SavedAt: savedAt})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return historyIDLess(entries[j].ID, entries[i].ID) })
+	return entries, nil
+}
+
+// historyIDLess orders IDs chronologically, comparing the "-N" suffix of
+// same-second entries numerically so that -10 sorts after -9.
+func historyIDLess(a, b string) bool {
+	baseA, seqA := splitHistoryID(a)
+	baseB, seqB := splitHistoryID(b)
+	if baseA != baseB {
+		return baseA < baseB
+	}
+	return seqA < seqB
+}
+
+// splitHistoryID returns the timestamp part of id and its sequence number,
+// which is 1 for the first entry saved in a second.
+func splitHistoryID(id string) (string, int) {
+	if len(id) <= len(historyIDFormat) {
+		return id, 1
+	}
+	base, suffix := id[:len(historyIDFormat)], id[len(historyIDFormat):]
+	n, err := strconv.Atoi(strings.TrimPrefix(suffix, "-"))
+	if err != nil || !strings.HasPrefix(suffix, "-") {
+		return id, 1
+	}
+	return base, n
+}
+
+// ResolveRef finds the history entry for ref, which may be "latest", a full
+// ID or an unambiguous ID prefix.
+func ResolveRef(ref string) (*HistoryEntry, error) {
+	entries, err := ListHistory()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no snapshots saved yet — run 'openboot snapshot --local' first")
+	}
+	if ref == LatestRef || ref == "" {
+		return &entries[0], nil
+	}
+
+	var matches []HistoryEntry
+	for _, e := range entries {
+		if e.ID == ref {
+			return &e, nil
+		}
+		if strings.HasPrefix(e.ID, ref) {
+			matches = append(matches, e)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no snapshot with ID %s", ref)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, fmt.Errorf("snapshot ID %s is ambiguous (%d matches)", ref, len(matches))
+	}
+}
+
+// LoadRef loads the history entry ref resolves to.
+func LoadRef(ref string) (*Snapshot, error) {
+	entry, err := ResolveRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	return LoadFile(entry.Path)
+}
+
+// newHistoryID returns an unused ID for a snapshot saved at now.
+func newHistoryID(dir string, now time.Time) string {
+	base := now.Format(historyIDFormat)
+	id := base
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filepath.Join(dir, id+".json")); os.IsNotExist(err) {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// Prune deletes history entries the policy doesn't keep and returns them.
+func Prune(policy RetentionPolicy, dryRun bool) ([]HistoryEntry, error) {
+	entries, err := ListHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	keep := retained(entries, policy)
+	var removed []HistoryEntry
+	for _, e := range entries {
+		if keep[e.ID] {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(e.Path); err != nil {
+				return removed, fmt.Errorf("failed to remove snapshot %s: %w", e.ID, err)
+			}
+		}
+		removed = append(removed, e)
+	}
+	return removed, nil
+}
+
+// retained returns the IDs the policy keeps. entries must be newest first.
+func retained(entries []HistoryEntry, policy RetentionPolicy) map[string]bool {
+	keep := make(map[string]bool)
+	if len(entries) == 0 {
+		return keep
+	}
+	keep[entries[0].ID] = true
+
+	for i := 0; i < policy.KeepLast && i < len(entries); i++ {
+		keep[entries[i].ID] = true
+	}
+
+	keepNewestPerBucket(entries, policy.KeepDaily, keep, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	})
+	keepNewestPerBucket(entries, policy.KeepWeekly, keep, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%02d", year, week)
+	})
+
+	return keep
+}
+
+func keepNewestPerBucket(entries []HistoryEntry, buckets int, keep map[string]bool, bucket func(time.Time) string) {
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if len(seen) >= buckets {
+			return
+		}
+		b := bucket(e.SavedAt)
+		if seen[b] {
+			continue
+		}
+		seen[b] = true
+		keep[e.ID] = true
+	}
+}
@@ -0,0 +1,193 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func historyEntries(times ...time.Time) []HistoryEntry {
+	entries := make([]HistoryEntry, len(times))
+	for i, t := range times {
+		entries[i] = HistoryEntry{ID: t.Format(historyIDFormat), SavedAt: t}
+	}
+	return entries
+}
+
+func TestRetained_KeepLast(t *testing.T) {
+	base := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	entries := historyEntries(base, base.Add(-time.Minute), base.Add(-2*time.Minute))
+
+	keep := retained(entries, RetentionPolicy{KeepLast: 2})
+
+	assert.True(t, keep[entries[0].ID])
+	assert.True(t, keep[entries[1].ID])
+	assert.False(t, keep[entries[2].ID])
+}
+
+func TestRetained_KeepDailyKeepsNewestPerDay(t *testing.T) {
+	day1 := time.Date(2024, 3, 10, 18, 0, 0, 0, time.Local)
+	day0 := time.Date(2024, 3, 9, 18, 0, 0, 0, time.Local)
+	entries := historyEntries(day1, day1.Add(-time.Hour), day0, day0.Add(-time.Hour))
+
+	keep := retained(entries, RetentionPolicy{KeepDaily: 2})
+
+	assert.True(t, keep[entries[0].ID])
+	assert.False(t, keep[entries[1].ID])
+	assert.True(t, keep[entries[2].ID])
+	assert.False(t, keep[entries[3].ID])
+}
+
+func TestRetained_KeepWeekly(t *testing.T) {
+	now := time.Date(2024, 3, 13, 12, 0, 0, 0, time.Local)
+	entries := historyEntries(now, now.AddDate(0, 0, -1), now.AddDate(0, 0, -7), now.AddDate(0, 0, -14))
+
+	keep := retained(entries, RetentionPolicy{KeepWeekly: 2})
+
+	assert.True(t, keep[entries[0].ID])
+	assert.False(t, keep[entries[1].ID])
+	assert.True(t, keep[entries[2].ID])
+	assert.False(t, keep[entries[3].ID])
+}
+
+func TestRetained_AlwaysKeepsNewest(t *testing.T) {
+	entries := historyEntries(time.Now(), time.Now().Add(-time.Hour))
+
+	keep := retained(entries, RetentionPolicy{})
+
+	assert.Len(t, keep, 1)
+	assert.True(t, keep[entries[0].ID])
+}
+
+func TestSaveLocal_WritesHistoryAndLocalPath(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	snap := &Snapshot{Version: 1, Hostname: "test"}
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+
+	first, err := saveLocalAt(snap, now)
+	require.NoError(t, err)
+	second, err := saveLocalAt(snap, now)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(HistoryDir(), "20240310-120000.json"), first)
+	assert.Equal(t, filepath.Join(HistoryDir(), "20240310-120000-2.json"), second)
+	assert.FileExists(t, LocalPath())
+
+	entries, err := ListHistory()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "20240310-120000-2", entries[0].ID)
+	assert.Equal(t, now, entries[0].SavedAt)
+}
+
+func TestLoadLocal_ReturnsLatest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	_, err := saveLocalAt(&Snapshot{Hostname: "old"}, now.Add(-time.Hour))
+	require.NoError(t, err)
+	_, err = saveLocalAt(&Snapshot{Hostname: "new"}, now)
+	require.NoError(t, err)
+
+	snap, err := LoadLocal()
+	require.NoError(t, err)
+	assert.Equal(t, "new", snap.Hostname)
+}
+
+func TestLoadLocal_FallsBackToLocalPath(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, os.MkdirAll(filepath.Dir(LocalPath()), 0700))
+	require.NoError(t, os.WriteFile(LocalPath(), []byte(`{"hostname":"legacy"}`), 0644))
+
+	snap, err := LoadLocal()
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", snap.Hostname)
+}
+
+func TestResolveRef(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	_, err := ResolveRef(LatestRef)
+	assert.Error(t, err)
+
+	for _, ts := range []time.Time{
+		time.Date(2024, 3, 9, 8, 0, 0, 0, time.Local),
+		time.Date(2024, 3, 10, 8, 0, 0, 0, time.Local),
+		time.Date(2024, 3, 10, 9, 0, 0, 0, time.Local),
+	} {
+		_, err := saveLocalAt(&Snapshot{}, ts)
+		require.NoError(t, err)
+	}
+
+	entry, err := ResolveRef(LatestRef)
+	require.NoError(t, err)
+	assert.Equal(t, "20240310-090000", entry.ID)
+
+	entry, err = ResolveRef("20240309")
+	require.NoError(t, err)
+	assert.Equal(t, "20240309-080000", entry.ID)
+
+	_, err = ResolveRef("20240310")
+	assert.ErrorContains(t, err, "ambiguous")
+
+	_, err = ResolveRef("20991231")
+	assert.ErrorContains(t, err, "no snapshot")
+}
+
+func TestListHistory_SameSecondSortsNumerically(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	for i := 0; i < 11; i++ {
+		_, err := saveLocalAt(&Snapshot{}, now)
+		require.NoError(t, err)
+	}
+	_, err := saveLocalAt(&Snapshot{}, now.Add(-time.Second))
+	require.NoError(t, err)
+
+	entries, err := ListHistory()
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	assert.Equal(t, "20240310-120000-11", entries[0].ID)
+	assert.Equal(t, "20240310-120000-10", entries[1].ID)
+	assert.Equal(t, "20240310-120000-9", entries[2].ID)
+
+	ids := make(map[string]bool)
+	for _, e := range entries {
+		ids[e.ID] = true
+	}
+	assert.False(t, ids["20240310-120000"], "retention prunes the oldest same-second entry")
+	assert.False(t, ids["20240310-115959"])
+}
+
+func TestPrune(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	for i := 0; i < 3; i++ {
+		_, err := saveLocalAt(&Snapshot{}, now.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, err)
+	}
+
+	removed, err := Prune(RetentionPolicy{KeepLast: 1}, true)
+	require.NoError(t, err)
+	assert.Len(t, removed, 2)
+
+	entries, err := ListHistory()
+	require.NoError(t, err)
+	assert.Len(t, entries, 3, "dry run must not delete anything")
+
+	removed, err = Prune(RetentionPolicy{KeepLast: 1}, false)
+	require.NoError(t, err)
+	assert.Len(t, removed, 2)
+
+	entries, err = ListHistory()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "20240310-120200", entries[0].ID)
+}
@@ -0,0 +1,71 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SchemaID is where the JSON Schema for CurrentVersion is published.
+var SchemaID = fmt.Sprintf("https://openboot.dev/schemas/snapshot.v%d.json", CurrentVersion)
+
+// JSONSchema returns a JSON Schema for CurrentVersion generated from the
+// Snapshot type. Fields without omitempty are required.
+func JSONSchema() ([]byte, error) {
+	schema := schemaFor(reflect.TypeOf(Snapshot{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = SchemaID
+	schema["title"] = "OpenBoot snapshot"
+	schema["properties"].(map[string]any)["version"] = map[string]any{
+		"type":  "integer",
+		"const": CurrentVersion,
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func schemaFor(t reflect.Type) map[string]any {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": []string{"array", "null"}, "items": schemaFor(t.Elem())}
+	case reflect.Ptr:
+		return schemaFor(t.Elem())
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" || !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			properties[name] = schemaFor(f.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]any{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		}
+	default:
+		panic(fmt.Sprintf("jsonschema: unsupported type %s", t))
+	}
+}
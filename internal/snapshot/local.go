@@ -5,20 +5,28 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/openbootdotdev/openboot/internal/system"
 )
 
 func LocalPath() string {
-	home, err := os.UserHomeDir()
+	home, err := system.HomeDir()
 	if err != nil {
 		return ""
 	}
 	return filepath.Join(home, ".openboot", "snapshot.json")
 }
 
+// SaveLocal adds snap to the snapshot history, updates snapshot.json to
+// match and prunes history with DefaultRetention. It returns the path of
+// the new history entry.
 func SaveLocal(snap *Snapshot) (string, error) {
-	path := LocalPath()
+	return saveLocalAt(snap, time.Now())
+}
 
-	dir := filepath.Dir(path)
+func saveLocalAt(snap *Snapshot, now time.Time) (string, error) {
+	dir := HistoryDir()
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return "", fmt.Errorf("failed to create snapshot directory: %w", err)
 	}
@@ -28,15 +36,31 @@ func SaveLocal(snap *Snapshot) (string, error) {
 		return "", fmt.Errorf("failed to marshal snapshot: %w", err)
 	}
 
+	path := filepath.Join(dir, newHistoryID(dir, now)+".json")
 	if err := os.WriteFile(path, data, 0644); err != nil {
 		return "", fmt.Errorf("failed to write snapshot file: %w", err)
 	}
+	if err := os.WriteFile(LocalPath(), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write snapshot file: %w", err)
+	}
 
+	if _, err := Prune(DefaultRetention, false); err != nil {
+		return path, err
+	}
 	return path, nil
 }
 
+// LoadLocal loads the latest saved snapshot, falling back to snapshot.json
+// when there is no history yet.
 func LoadLocal() (*Snapshot, error) {
-	return LoadFile(LocalPath())
+	entries, err := ListHistory()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return LoadFile(LocalPath())
+	}
+	return LoadFile(entries[0].Path)
 }
 
 func LoadFile(path string) (*Snapshot, error) {
@@ -48,10 +72,10 @@ func LoadFile(path string) (*Snapshot, error) {
 		return nil, fmt.Errorf("failed to read snapshot file: %w", err)
 	}
 
-	var snap Snapshot
-	if err := json.Unmarshal(data, &snap); err != nil {
+	snap, err := Parse(data)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse snapshot file: %w", err)
 	}
 
-	return &snap, nil
+	return snap, nil
 }
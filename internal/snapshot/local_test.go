@@ -28,6 +28,12 @@ func TestLocalPath_Format(t *testing.T) {
 	}
 }
 
+func TestLocalPath_UsesHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	assert.Equal(t, filepath.Join(home, ".openboot", "snapshot.json"), LocalPath())
+}
+
 // TestSaveLocal_CreatesDirectory tests that SaveLocal creates the directory.
 func TestSaveLocal_CreatesDirectory(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -94,7 +100,7 @@ func TestLoadFile_ValidSnapshot(t *testing.T) {
 	assert.NotNil(t, loaded)
 
 	// Verify loaded snapshot
-	assert.Equal(t, snap.Version, loaded.Version)
+	assert.Equal(t, CurrentVersion, loaded.Version)
 	assert.Equal(t, snap.Hostname, loaded.Hostname)
 	assert.Equal(t, snap.Packages.Formulae, loaded.Packages.Formulae)
 	assert.Equal(t, snap.Shell.Default, loaded.Shell.Default)
@@ -240,7 +246,7 @@ func TestLoadFile_WithAllFields(t *testing.T) {
 	assert.NotNil(t, loaded)
 
 	// Verify all fields
-	assert.Equal(t, snap.Version, loaded.Version)
+	assert.Equal(t, CurrentVersion, loaded.Version)
 	assert.Equal(t, snap.Hostname, loaded.Hostname)
 	assert.Equal(t, len(snap.Packages.Formulae), len(loaded.Packages.Formulae))
 	assert.Equal(t, len(snap.MacOSPrefs), len(loaded.MacOSPrefs))
@@ -347,7 +353,7 @@ func TestLoadFile_RoundTrip(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify round trip
-	assert.Equal(t, original.Version, loaded.Version)
+	assert.Equal(t, CurrentVersion, loaded.Version)
 	assert.Equal(t, original.Hostname, loaded.Hostname)
 	assert.Equal(t, original.Packages.Formulae, loaded.Packages.Formulae)
 	assert.Equal(t, original.Shell.Default, loaded.Shell.Default)
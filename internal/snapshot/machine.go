@@ -0,0 +1,241 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// MachineInfo describes the Mac a snapshot was captured on. Every field is
+// best effort and left empty when it can't be determined.
+type MachineInfo struct {
+	OSVersion string `json:"os_version,omitempty"`
+	OSBuild   string `json:"os_build,omitempty"`
+	// Arch is the hardware architecture, "arm64" or "x86_64", even when
+	// openboot itself runs under Rosetta.
+	Arch            string `json:"arch,omitempty"`
+	HomebrewPrefix  string `json:"homebrew_prefix,omitempty"`
+	HomebrewVersion string `json:"homebrew_version,omitempty"`
+	OpenbootVersion string `json:"openboot_version,omitempty"`
+	CLTVersion      string `json:"clt_version,omitempty"`
+	Rosetta         bool   `json:"rosetta,omitempty"`
+}
+
+const rosettaPath = "/Library/Apple/usr/share/rosetta/rosetta"
+
+// CaptureMachine describes the current Mac. OpenbootVersion is left for
+// the caller to fill in.
+func CaptureMachine() (*MachineInfo, error) {
+	m := &MachineInfo{
+		OSVersion: commandOutput("sw_vers", "-productVersion"),
+		OSBuild:   commandOutput("sw_vers", "-buildVersion"),
+		Arch:      hardwareArch(),
+	}
+
+	if _, err := exec.LookPath("brew"); err == nil {
+		m.HomebrewPrefix = commandOutput("brew", "--prefix")
+		m.HomebrewVersion = parseBrewVersion(commandOutput("brew", "--version"))
+	}
+
+	m.CLTVersion = parseCLTVersion(commandOutput("pkgutil", "--pkg-info=com.apple.pkg.CLTools_Executables"))
+
+	if m.Arch == "arm64" {
+		if _, err := os.Stat(rosettaPath); err == nil {
+			m.Rosetta = true
+		}
+	}
+	return m, nil
+}
+
+func commandOutput(name string, args ...string) string {
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// hardwareArch reports arm64 on Apple silicon even when running as an
+// x86_64 binary under Rosetta, where uname would say x86_64.
+func hardwareArch() string {
+	if commandOutput("sysctl", "-n", "hw.optional.arm64") == "1" {
+		return "arm64"
+	}
+	if arch := commandOutput("uname", "-m"); arch != "" {
+		return arch
+	}
+	if runtime.GOARCH == "amd64" {
+		return "x86_64"
+	}
+	return runtime.GOARCH
+}
+
+// parseBrewVersion turns "Homebrew 4.3.1\n..." into "4.3.1".
+func parseBrewVersion(output string) string {
+	line, _, _ := strings.Cut(output, "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "Homebrew" {
+		return ""
+	}
+	return fields[1]
+}
+
+// parseCLTVersion picks the version out of pkgutil --pkg-info output.
+func parseCLTVersion(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "version:"); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// caskArchitectures maps each cask to the architectures it is limited to
+// ("arm" or "intel"); casks that run anywhere are left out. It is a
+// variable so tests can avoid calling brew.
+var caskArchitectures = brewCaskArchitectures
+
+func brewCaskArchitectures(casks []string) (map[string][]string, error) {
+	if len(casks) == 0 {
+		return nil, nil
+	}
+	args := append([]string{"info", "--cask", "--json=v2"}, casks...)
+	out, err := exec.Command("brew", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("brew info: %w", err)
+	}
+
+	var info struct {
+		Casks []struct {
+			Token     string `json:"token"`
+			DependsOn struct {
+				Arch []struct {
+					Type string `json:"type"`
+				} `json:"arch"`
+			} `json:"depends_on"`
+		} `json:"casks"`
+	}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse brew info: %w", err)
+	}
+
+	archs := map[string][]string{}
+	for _, c := range info.Casks {
+		for _, a := range c.DependsOn.Arch {
+			archs[c.Token] = append(archs[c.Token], a.Type)
+		}
+	}
+	return archs, nil
+}
+
+// CheckCompatibility warns about likely problems restoring snap onto the
+// machine described by here. Snapshots without machine info get no
+// warnings.
+func CheckCompatibility(snap *Snapshot, here *MachineInfo) []string {
+	from := snap.Machine
+	if from == nil || here == nil {
+		return nil
+	}
+	var warnings []string
+
+	if from.OSVersion != "" && here.OSVersion != "" && compareOSVersions(from.OSVersion, here.OSVersion) > 0 {
+		warnings = append(warnings, fmt.Sprintf("Captured on macOS %s, newer than this Mac's %s; some casks and preferences may not be available", from.OSVersion, here.OSVersion))
+	}
+
+	if from.Arch != "" && here.Arch != "" && from.Arch != here.Arch {
+		warnings = append(warnings, fmt.Sprintf("Captured on %s, this Mac is %s", from.Arch, here.Arch))
+		if here.Arch == "arm64" && !here.Rosetta {
+			warnings = append(warnings, "Rosetta is not installed; Intel-only apps won't run until it is (softwareupdate --install-rosetta)")
+		}
+		if unsupported := casksUnsupportedOn(here.Arch, snap.Packages.Casks); len(unsupported) > 0 {
+			warnings = append(warnings, fmt.Sprintf("Casks that can't be installed on %s: %s", here.Arch, strings.Join(unsupported, ", ")))
+		}
+	}
+
+	if from.HomebrewPrefix != "" && here.HomebrewPrefix != "" && from.HomebrewPrefix != here.HomebrewPrefix {
+		warnings = append(warnings, fmt.Sprintf("Homebrew lives in %s here but was in %s; dotfiles may reference the old path", here.HomebrewPrefix, from.HomebrewPrefix))
+	}
+
+	if from.CLTVersion != "" && here.CLTVersion == "" {
+		warnings = append(warnings, "Xcode Command Line Tools are not installed; formulae built from source need them (xcode-select --install)")
+	}
+
+	return warnings
+}
+
+// casksUnsupportedOn returns the casks limited to another architecture.
+// If brew can't be asked, none are reported.
+func casksUnsupportedOn(arch string, casks []string) []string {
+	archs, err := caskArchitectures(casks)
+	if err != nil {
+		return nil
+	}
+	want := "intel"
+	if arch == "arm64" {
+		want = "arm"
+	}
+	var unsupported []string
+	for _, cask := range casks {
+		if a := archs[cask]; len(a) > 0 && !containsString(a, want) {
+			unsupported = append(unsupported, cask)
+		}
+	}
+	return unsupported
+}
+
+// compareOSVersions compares dotted version strings numerically.
+func compareOSVersions(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var na, nb int
+		if i < len(pa) {
+			na, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			nb, _ = strconv.Atoi(pb[i])
+		}
+		if na != nb {
+			if na > nb {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe summarises the machine in one line, e.g.
+// "macOS 14.5 (23F79), arm64, Homebrew 4.3.1".
+func (m *MachineInfo) Describe() string {
+	if m == nil {
+		return ""
+	}
+	var parts []string
+	if m.OSVersion != "" {
+		ver := "macOS " + m.OSVersion
+		if m.OSBuild != "" {
+			ver += " (" + m.OSBuild + ")"
+		}
+		parts = append(parts, ver)
+	}
+	if m.Arch != "" {
+		parts = append(parts, m.Arch)
+	}
+	if m.HomebrewVersion != "" {
+		parts = append(parts, "Homebrew "+m.HomebrewVersion)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,81 @@
+package snapshot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBrewVersion(t *testing.T) {
+	assert.Equal(t, "4.3.1", parseBrewVersion("Homebrew 4.3.1\nHomebrew/homebrew-core (git revision 1a2b)"))
+	assert.Equal(t, "4.3.1-12-gabcdef", parseBrewVersion("Homebrew 4.3.1-12-gabcdef"))
+	assert.Empty(t, parseBrewVersion(""))
+	assert.Empty(t, parseBrewVersion("command not found"))
+}
+
+func TestParseCLTVersion(t *testing.T) {
+	output := `package-id: com.apple.pkg.CLTools_Executables
+version: 15.3.0.0.1.1708646388
+volume: /
+location: /
+install-time: 1710000000`
+	assert.Equal(t, "15.3.0.0.1.1708646388", parseCLTVersion(output))
+	assert.Empty(t, parseCLTVersion("No receipt for 'com.apple.pkg.CLTools_Executables' found at '/'."))
+}
+
+func TestCompareOSVersions(t *testing.T) {
+	assert.Equal(t, 0, compareOSVersions("14.5", "14.5"))
+	assert.Equal(t, 0, compareOSVersions("14", "14.0"))
+	assert.Equal(t, 1, compareOSVersions("15.0", "14.6.1"))
+	assert.Equal(t, -1, compareOSVersions("14.5", "14.10"))
+}
+
+func stubCaskArchitectures(t *testing.T, archs map[string][]string, err error) {
+	t.Helper()
+	orig := caskArchitectures
+	caskArchitectures = func([]string) (map[string][]string, error) { return archs, err }
+	t.Cleanup(func() { caskArchitectures = orig })
+}
+
+func TestCheckCompatibility_IntelCasksOnAppleSilicon(t *testing.T) {
+	stubCaskArchitectures(t, map[string][]string{"old-app": {"intel"}, "new-app": {"arm"}}, nil)
+
+	snap := &Snapshot{
+		Machine:  &MachineInfo{OSVersion: "13.6", Arch: "x86_64"},
+		Packages: PackageSnapshot{Casks: []string{"firefox", "old-app", "new-app"}},
+	}
+	warnings := CheckCompatibility(snap, &MachineInfo{OSVersion: "14.5", Arch: "arm64"})
+
+	assert.Equal(t, []string{
+		"Captured on x86_64, this Mac is arm64",
+		"Rosetta is not installed; Intel-only apps won't run until it is (softwareupdate --install-rosetta)",
+		"Casks that can't be installed on arm64: old-app",
+	}, warnings)
+}
+
+func TestCheckCompatibility_NewerMacOS(t *testing.T) {
+	stubCaskArchitectures(t, nil, errors.New("brew should not be asked"))
+
+	snap := &Snapshot{Machine: &MachineInfo{OSVersion: "15.1", Arch: "arm64", CLTVersion: "16.0", HomebrewPrefix: "/opt/homebrew"}}
+	warnings := CheckCompatibility(snap, &MachineInfo{OSVersion: "14.5", Arch: "arm64", HomebrewPrefix: "/opt/homebrew"})
+
+	assert.Len(t, warnings, 2)
+	assert.Contains(t, warnings[0], "Captured on macOS 15.1, newer than this Mac's 14.5")
+	assert.Contains(t, warnings[1], "Command Line Tools are not installed")
+}
+
+func TestCheckCompatibility_NoMachineInfo(t *testing.T) {
+	assert.Empty(t, CheckCompatibility(&Snapshot{}, &MachineInfo{Arch: "arm64"}))
+
+	same := &MachineInfo{OSVersion: "14.5", Arch: "arm64", HomebrewPrefix: "/opt/homebrew"}
+	assert.Empty(t, CheckCompatibility(&Snapshot{Machine: same}, same))
+}
+
+func TestMachineInfo_Describe(t *testing.T) {
+	m := &MachineInfo{OSVersion: "14.5", OSBuild: "23F79", Arch: "arm64", HomebrewVersion: "4.3.1"}
+	assert.Equal(t, "macOS 14.5 (23F79), arm64, Homebrew 4.3.1", m.Describe())
+
+	var missing *MachineInfo
+	assert.Empty(t, missing.Describe())
+}
@@ -0,0 +1,336 @@
+package snapshot
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MergeStrategy decides which items from several snapshots end up in the
+// merged one.
+type MergeStrategy string
+
+const (
+	// MergeUnion keeps items found in any snapshot.
+	MergeUnion MergeStrategy = "union"
+	// MergeIntersection keeps items found in every snapshot.
+	MergeIntersection MergeStrategy = "intersection"
+	// MergeMajority keeps items found in more than half of the snapshots.
+	MergeMajority MergeStrategy = "majority"
+)
+
+// ParseMergeStrategy validates a strategy name.
+func ParseMergeStrategy(s string) (MergeStrategy, error) {
+	switch MergeStrategy(s) {
+	case MergeUnion, MergeIntersection, MergeMajority:
+		return MergeStrategy(s), nil
+	default:
+		return "", fmt.Errorf("unknown merge strategy %q (want union, intersection or majority)", s)
+	}
+}
+
+func (s MergeStrategy) keeps(count, total int) bool {
+	switch s {
+	case MergeIntersection:
+		return count == total
+	case MergeMajority:
+		return count*2 > total
+	default:
+		return count > 0
+	}
+}
+
+// ItemCount is how many of the merged snapshots contain an item or value.
+type ItemCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// MergeConflict is a setting the snapshots disagree on with no single most
+// common value. Values are most common first; the merged snapshot uses
+// the first one until Resolve picks another.
+type MergeConflict struct {
+	Field  string      `json:"field"`
+	Domain string      `json:"domain,omitempty"`
+	Key    string      `json:"key,omitempty"`
+	Values []ItemCount `json:"values"`
+}
+
+// MergeResult is the outcome of Merge.
+type MergeResult struct {
+	Snapshot *Snapshot `json:"snapshot"`
+	// Candidates holds everything seen in any snapshot, with every value
+	// of a conflicting preference, for review in the snapshot editor.
+	Candidates *Snapshot `json:"-"`
+	Total      int       `json:"total"`
+	// Counts maps "formulae", "casks", "taps", "npm", "shell.plugins" and
+	// "dev_tools" to how often each item appears, most common first.
+	Counts    map[string][]ItemCount `json:"counts"`
+	Conflicts []MergeConflict        `json:"conflicts"`
+
+	dotfiles map[string]DotfilesSnapshot
+}
+
+// Merge combines snapshots with strategy. Per-field rules:
+//   - package lists, taps, shell plugins, preferences and dev tools are
+//     kept according to strategy;
+//   - a preference's value, a dev tool's version, the default shell, the
+//     theme and the dotfiles repo take the most common value;
+//   - Oh-My-Zsh is kept according to strategy;
+//   - git identity, hostname and machine info describe one Mac and are
+//     left empty.
+func Merge(snaps []*Snapshot, strategy MergeStrategy) (*MergeResult, error) {
+	if len(snaps) < 2 {
+		return nil, fmt.Errorf("need at least two snapshots to merge")
+	}
+	total := len(snaps)
+
+	res := &MergeResult{
+		Snapshot:   &Snapshot{Version: CurrentVersion, CapturedAt: time.Now()},
+		Candidates: &Snapshot{Version: CurrentVersion, CapturedAt: time.Now()},
+		Total:      total,
+		Counts:     map[string][]ItemCount{},
+		dotfiles:   map[string]DotfilesSnapshot{},
+	}
+	out, cand := res.Snapshot, res.Candidates
+
+	lists := []struct {
+		name string
+		get  func(*Snapshot) []string
+		set  func(*Snapshot, []string)
+	}{
+		{"formulae", func(s *Snapshot) []string { return s.Packages.Formulae }, func(s *Snapshot, v []string) { s.Packages.Formulae = v }},
+		{"casks", func(s *Snapshot) []string { return s.Packages.Casks }, func(s *Snapshot, v []string) { s.Packages.Casks = v }},
+		{"taps", func(s *Snapshot) []string { return s.Packages.Taps }, func(s *Snapshot, v []string) { s.Packages.Taps = v }},
+		{"npm", func(s *Snapshot) []string { return s.Packages.Npm }, func(s *Snapshot, v []string) { s.Packages.Npm = v }},
+		{"shell.plugins", func(s *Snapshot) []string { return s.Shell.Plugins }, func(s *Snapshot, v []string) { s.Shell.Plugins = v }},
+	}
+	for _, l := range lists {
+		counts := countItems(snaps, l.get)
+		res.Counts[l.name] = counts
+		var kept, all []string
+		for _, c := range counts {
+			all = append(all, c.Name)
+			if strategy.keeps(c.Count, total) {
+				kept = append(kept, c.Name)
+			}
+		}
+		sort.Strings(kept)
+		sort.Strings(all)
+		l.set(out, kept)
+		l.set(cand, all)
+	}
+
+	ohMyZsh := 0
+	for _, s := range snaps {
+		if s.Shell.OhMyZsh {
+			ohMyZsh++
+		}
+	}
+	out.Shell.OhMyZsh = ohMyZsh > 0 && strategy.keeps(ohMyZsh, total)
+	cand.Shell.OhMyZsh = ohMyZsh > 0
+	out.Shell.Default = res.pickValue("shell.default", snaps, func(s *Snapshot) string { return s.Shell.Default })
+	out.Shell.Theme = res.pickValue("shell.theme", snaps, func(s *Snapshot) string { return s.Shell.Theme })
+	allPlugins := cand.Shell.Plugins
+	cand.Shell = out.Shell
+	cand.Shell.Plugins = allPlugins
+
+	for _, s := range snaps {
+		if s.Dotfiles.RepoURL != "" {
+			if _, ok := res.dotfiles[s.Dotfiles.RepoURL]; !ok {
+				res.dotfiles[s.Dotfiles.RepoURL] = s.Dotfiles
+			}
+		}
+	}
+	if url := res.pickValue("dotfiles.repo_url", snaps, func(s *Snapshot) string { return s.Dotfiles.RepoURL }); url != "" {
+		out.Dotfiles = res.dotfiles[url]
+	}
+	cand.Dotfiles = out.Dotfiles
+
+	res.mergePrefs(snaps, strategy)
+	res.mergeDevTools(snaps, strategy)
+
+	return res, nil
+}
+
+// Resolve sets a conflicting field to value, which must be one of the
+// conflict's values.
+func (r *MergeResult) Resolve(c MergeConflict, value string) error {
+	found := false
+	for _, v := range c.Values {
+		if v.Name == value {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("%q is not one of the values for %s", value, c.Field)
+	}
+
+	out := r.Snapshot
+	switch c.Field {
+	case "shell.default":
+		out.Shell.Default = value
+	case "shell.theme":
+		out.Shell.Theme = value
+	case "dotfiles.repo_url":
+		out.Dotfiles = r.dotfiles[value]
+	case "macos_prefs":
+		for i, p := range out.MacOSPrefs {
+			if p.Domain == c.Domain && p.Key == c.Key {
+				out.MacOSPrefs[i].Value = value
+			}
+		}
+	case "dev_tools":
+		for i, t := range out.DevTools {
+			if t.Name == c.Key {
+				out.DevTools[i].Version = value
+			}
+		}
+	default:
+		return fmt.Errorf("cannot resolve %s", c.Field)
+	}
+	return nil
+}
+
+func (r *MergeResult) mergePrefs(snaps []*Snapshot, strategy MergeStrategy) {
+	type prefKey struct{ domain, key string }
+	var order []prefKey
+	present := map[prefKey]int{}
+	values := map[prefKey][]string{}
+	desc := map[prefKey]string{}
+
+	for _, s := range snaps {
+		seen := map[prefKey]bool{}
+		for _, p := range s.MacOSPrefs {
+			k := prefKey{p.Domain, p.Key}
+			if _, ok := present[k]; !ok {
+				order = append(order, k)
+				desc[k] = p.Desc
+			}
+			if !seen[k] {
+				present[k]++
+				seen[k] = true
+			}
+			values[k] = append(values[k], p.Value)
+		}
+	}
+
+	for _, k := range order {
+		counts := rankValues(values[k])
+		for _, c := range counts {
+			r.Candidates.MacOSPrefs = append(r.Candidates.MacOSPrefs, MacOSPref{Domain: k.domain, Key: k.key, Value: c.Name, Desc: desc[k]})
+		}
+		if !strategy.keeps(present[k], r.Total) {
+			continue
+		}
+		r.Snapshot.MacOSPrefs = append(r.Snapshot.MacOSPrefs, MacOSPref{Domain: k.domain, Key: k.key, Value: counts[0].Name, Desc: desc[k]})
+		if tied(counts) {
+			r.Conflicts = append(r.Conflicts, MergeConflict{Field: "macos_prefs", Domain: k.domain, Key: k.key, Values: counts})
+		}
+	}
+}
+
+func (r *MergeResult) mergeDevTools(snaps []*Snapshot, strategy MergeStrategy) {
+	versions := map[string][]string{}
+	present := map[string]int{}
+	for _, s := range snaps {
+		seen := map[string]bool{}
+		for _, t := range s.DevTools {
+			versions[t.Name] = append(versions[t.Name], t.Version)
+			if !seen[t.Name] {
+				present[t.Name]++
+				seen[t.Name] = true
+			}
+		}
+	}
+
+	var names []string
+	for name := range present {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var counts []ItemCount
+	for _, name := range names {
+		counts = append(counts, ItemCount{Name: name, Count: present[name]})
+		ranked := rankValues(versions[name])
+		r.Candidates.DevTools = append(r.Candidates.DevTools, DevTool{Name: name, Version: ranked[0].Name})
+		if !strategy.keeps(present[name], r.Total) {
+			continue
+		}
+		r.Snapshot.DevTools = append(r.Snapshot.DevTools, DevTool{Name: name, Version: ranked[0].Name})
+		if tied(ranked) {
+			r.Conflicts = append(r.Conflicts, MergeConflict{Field: "dev_tools", Key: name, Values: ranked})
+		}
+	}
+	sortCounts(counts)
+	r.Counts["dev_tools"] = counts
+}
+
+// pickValue returns the most common non-empty value of a field, recording
+// a conflict when there is a tie.
+func (r *MergeResult) pickValue(field string, snaps []*Snapshot, get func(*Snapshot) string) string {
+	var vals []string
+	for _, s := range snaps {
+		if v := get(s); v != "" {
+			vals = append(vals, v)
+		}
+	}
+	if len(vals) == 0 {
+		return ""
+	}
+	counts := rankValues(vals)
+	if tied(counts) {
+		r.Conflicts = append(r.Conflicts, MergeConflict{Field: field, Values: counts})
+	}
+	return counts[0].Name
+}
+
+// countItems counts in how many snapshots each item appears.
+func countItems(snaps []*Snapshot, get func(*Snapshot) []string) []ItemCount {
+	counts := map[string]int{}
+	for _, s := range snaps {
+		seen := map[string]bool{}
+		for _, item := range get(s) {
+			if !seen[item] {
+				counts[item]++
+				seen[item] = true
+			}
+		}
+	}
+	out := make([]ItemCount, 0, len(counts))
+	for name, n := range counts {
+		out = append(out, ItemCount{Name: name, Count: n})
+	}
+	sortCounts(out)
+	return out
+}
+
+// rankValues counts values, most common first; ties keep first-seen order.
+func rankValues(vals []string) []ItemCount {
+	var out []ItemCount
+	index := map[string]int{}
+	for _, v := range vals {
+		if i, ok := index[v]; ok {
+			out[i].Count++
+			continue
+		}
+		index[v] = len(out)
+		out = append(out, ItemCount{Name: v, Count: 1})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	return out
+}
+
+func tied(counts []ItemCount) bool {
+	return len(counts) > 1 && counts[0].Count == counts[1].Count
+}
+
+func sortCounts(counts []ItemCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+}
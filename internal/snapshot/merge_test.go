@@ -0,0 +1,129 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mergeTestSnapshots() []*Snapshot {
+	return []*Snapshot{
+		{
+			Hostname: "a",
+			Packages: PackageSnapshot{Formulae: []string{"git", "go", "jq"}, Casks: []string{"firefox"}},
+			MacOSPrefs: []MacOSPref{
+				{Domain: "com.apple.dock", Key: "autohide", Value: "true"},
+				{Domain: "NSGlobalDomain", Key: "KeyRepeat", Value: "2"},
+			},
+			Shell:    ShellSnapshot{Default: "/bin/zsh", OhMyZsh: true, Theme: "robbyrussell", Plugins: []string{"git"}},
+			Git:      GitSnapshot{UserName: "A", UserEmail: "a@example.com"},
+			Dotfiles: DotfilesSnapshot{RepoURL: "https://example.com/team.git", Ref: "main"},
+			DevTools: []DevTool{{Name: "go", Version: "1.22"}},
+		},
+		{
+			Packages: PackageSnapshot{Formulae: []string{"git", "go"}, Casks: []string{"slack"}},
+			MacOSPrefs: []MacOSPref{
+				{Domain: "com.apple.dock", Key: "autohide", Value: "false"},
+				{Domain: "NSGlobalDomain", Key: "KeyRepeat", Value: "2"},
+			},
+			Shell:    ShellSnapshot{Default: "/bin/zsh", OhMyZsh: true, Theme: "agnoster"},
+			DevTools: []DevTool{{Name: "go", Version: "1.21"}},
+		},
+		{
+			Packages:   PackageSnapshot{Formulae: []string{"git", "node"}},
+			MacOSPrefs: []MacOSPref{{Domain: "NSGlobalDomain", Key: "KeyRepeat", Value: "1"}},
+			Shell:      ShellSnapshot{Default: "/bin/bash"},
+			DevTools:   []DevTool{{Name: "go", Version: "1.22"}, {Name: "node", Version: "20"}},
+		},
+	}
+}
+
+func TestMerge_Strategies(t *testing.T) {
+	tests := []struct {
+		strategy MergeStrategy
+		formulae []string
+		casks    []string
+	}{
+		{MergeUnion, []string{"git", "go", "jq", "node"}, []string{"firefox", "slack"}},
+		{MergeIntersection, []string{"git"}, nil},
+		{MergeMajority, []string{"git", "go"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			res, err := Merge(mergeTestSnapshots(), tt.strategy)
+			require.NoError(t, err)
+			assert.Equal(t, tt.formulae, res.Snapshot.Packages.Formulae)
+			assert.Equal(t, tt.casks, res.Snapshot.Packages.Casks)
+			assert.Equal(t, []string{"git", "go", "jq", "node"}, res.Candidates.Packages.Formulae)
+		})
+	}
+}
+
+func TestMerge_Counts(t *testing.T) {
+	res, err := Merge(mergeTestSnapshots(), MergeMajority)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, res.Total)
+	assert.Equal(t, []ItemCount{{"git", 3}, {"go", 2}, {"jq", 1}, {"node", 1}}, res.Counts["formulae"])
+	assert.Equal(t, []ItemCount{{"go", 3}, {"node", 1}}, res.Counts["dev_tools"])
+}
+
+func TestMerge_PerFieldRules(t *testing.T) {
+	res, err := Merge(mergeTestSnapshots(), MergeMajority)
+	require.NoError(t, err)
+	out := res.Snapshot
+
+	assert.Equal(t, "/bin/zsh", out.Shell.Default)
+	assert.True(t, out.Shell.OhMyZsh)
+	assert.Empty(t, out.Shell.Plugins, "only one of three has the plugin")
+	assert.Equal(t, "https://example.com/team.git", out.Dotfiles.RepoURL)
+	assert.Equal(t, "main", out.Dotfiles.Ref)
+	assert.Equal(t, []DevTool{{Name: "go", Version: "1.22"}}, out.DevTools)
+	assert.Empty(t, out.Hostname)
+	assert.Empty(t, out.Git)
+	assert.Equal(t, CurrentVersion, out.Version)
+
+	require.Len(t, out.MacOSPrefs, 2)
+	assert.Equal(t, MacOSPref{Domain: "com.apple.dock", Key: "autohide", Value: "true"}, out.MacOSPrefs[0])
+	assert.Equal(t, MacOSPref{Domain: "NSGlobalDomain", Key: "KeyRepeat", Value: "2"}, out.MacOSPrefs[1])
+	assert.Len(t, res.Candidates.MacOSPrefs, 4, "every value of every preference is a candidate")
+}
+
+func TestMerge_ConflictsAndResolve(t *testing.T) {
+	res, err := Merge(mergeTestSnapshots(), MergeMajority)
+	require.NoError(t, err)
+
+	var fields []string
+	for _, c := range res.Conflicts {
+		fields = append(fields, c.Field)
+	}
+	assert.ElementsMatch(t, []string{"shell.theme", "macos_prefs"}, fields)
+
+	for _, c := range res.Conflicts {
+		switch c.Field {
+		case "shell.theme":
+			require.NoError(t, res.Resolve(c, "agnoster"))
+		case "macos_prefs":
+			assert.Equal(t, "autohide", c.Key)
+			require.NoError(t, res.Resolve(c, "false"))
+			assert.Error(t, res.Resolve(c, "maybe"))
+		}
+	}
+	assert.Equal(t, "agnoster", res.Snapshot.Shell.Theme)
+	assert.Equal(t, "false", res.Snapshot.MacOSPrefs[0].Value)
+}
+
+func TestMerge_NeedsTwoSnapshots(t *testing.T) {
+	_, err := Merge(mergeTestSnapshots()[:1], MergeUnion)
+	assert.Error(t, err)
+}
+
+func TestParseMergeStrategy(t *testing.T) {
+	s, err := ParseMergeStrategy("intersection")
+	require.NoError(t, err)
+	assert.Equal(t, MergeIntersection, s)
+
+	_, err = ParseMergeStrategy("vote")
+	assert.Error(t, err)
+}
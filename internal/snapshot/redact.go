@@ -0,0 +1,194 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Email redaction modes.
+const (
+	EmailKeep   = "keep"
+	EmailHash   = "hash"
+	EmailRemove = "remove"
+)
+
+// RedactionRules decide what is stripped from a snapshot before it leaves
+// the machine, either uploaded or exported with --json.
+type RedactionRules struct {
+	DropHostname bool `json:"drop_hostname"`
+	DropGitName  bool `json:"drop_git_name"`
+	// DropDotfilesRepo leaves out the whole dotfiles section, whose repo
+	// URL often names a client or organisation.
+	DropDotfilesRepo bool `json:"drop_dotfiles_repo"`
+	// Email is one of EmailKeep, EmailHash or EmailRemove.
+	Email string `json:"email"`
+	// Exclude holds glob patterns (as in path.Match) matched against
+	// formulae, casks, taps and npm packages. A formula from an excluded
+	// tap is excluded too. The dotfiles repo URL is dropped when a pattern
+	// matches the URL, the URL without its scheme, or any of its path
+	// segments.
+	Exclude []string `json:"exclude"`
+}
+
+// Redaction records one change Apply made.
+type Redaction struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+	// Action is "removed" or "hashed".
+	Action string `json:"action"`
+}
+
+// RedactionRulesPath returns where the user's redaction rules are kept.
+func RedactionRulesPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".openboot", "redact.json")
+}
+
+// LoadRedactionRules reads the user's rules. Without a rules file nothing
+// is redacted.
+func LoadRedactionRules() (RedactionRules, error) {
+	rules := RedactionRules{Email: EmailKeep}
+	data, err := os.ReadFile(RedactionRulesPath())
+	if os.IsNotExist(err) {
+		return rules, nil
+	}
+	if err != nil {
+		return rules, fmt.Errorf("failed to read redaction rules: %w", err)
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return rules, fmt.Errorf("failed to parse %s: %w", RedactionRulesPath(), err)
+	}
+	return rules, rules.Validate()
+}
+
+// Validate checks the email mode and exclude patterns.
+func (r RedactionRules) Validate() error {
+	switch r.Email {
+	case "", EmailKeep, EmailHash, EmailRemove:
+	default:
+		return fmt.Errorf("invalid email redaction %q (want %s, %s or %s)", r.Email, EmailKeep, EmailHash, EmailRemove)
+	}
+	for _, p := range r.Exclude {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// Apply returns a redacted copy of snap and what was changed. snap itself
+// is not modified.
+func (r RedactionRules) Apply(snap *Snapshot) (*Snapshot, []Redaction) {
+	out := *snap
+	var changes []Redaction
+
+	if r.DropHostname && out.Hostname != "" {
+		changes = append(changes, Redaction{Field: "hostname", Value: out.Hostname, Action: "removed"})
+		out.Hostname = ""
+	}
+
+	if r.DropGitName && out.Git.UserName != "" {
+		changes = append(changes, Redaction{Field: "git.user_name", Value: out.Git.UserName, Action: "removed"})
+		out.Git.UserName = ""
+	}
+
+	if email := out.Git.UserEmail; email != "" {
+		switch r.Email {
+		case EmailHash:
+			out.Git.UserEmail = HashEmail(email)
+			changes = append(changes, Redaction{Field: "git.user_email", Value: email, Action: "hashed"})
+		case EmailRemove:
+			out.Git.UserEmail = ""
+			changes = append(changes, Redaction{Field: "git.user_email", Value: email, Action: "removed"})
+		}
+	}
+
+	if url := out.Dotfiles.RepoURL; url != "" && (r.DropDotfilesRepo || r.excludesRepoURL(url)) {
+		changes = append(changes, Redaction{Field: "dotfiles.repo_url", Value: url, Action: "removed"})
+		out.Dotfiles = DotfilesSnapshot{}
+	}
+
+	out.Packages.Formulae, changes = r.filter("packages.formulae", snap.Packages.Formulae, changes)
+	out.Packages.Casks, changes = r.filter("packages.casks", snap.Packages.Casks, changes)
+	out.Packages.Taps, changes = r.filter("packages.taps", snap.Packages.Taps, changes)
+	out.Packages.Npm, changes = r.filter("packages.npm", snap.Packages.Npm, changes)
+
+	// The catalog match repeats package names, so it has to be filtered
+	// the same way without reporting the names twice.
+	out.CatalogMatch.Matched, _ = r.filter("", snap.CatalogMatch.Matched, nil)
+	out.CatalogMatch.Unmatched, _ = r.filter("", snap.CatalogMatch.Unmatched, nil)
+
+	return &out, changes
+}
+
+// Excludes reports whether name matches one of the exclude patterns, or
+// is a formula from a tap that does.
+func (r RedactionRules) Excludes(name string) bool {
+	candidates := []string{name}
+	if parts := strings.Split(name, "/"); len(parts) == 3 {
+		candidates = append(candidates, parts[0]+"/"+parts[1])
+	}
+	for _, p := range r.Exclude {
+		for _, c := range candidates {
+			if ok, _ := path.Match(p, c); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// excludesRepoURL matches the exclude patterns against a repo URL, so that
+// "acme-*" drops https://github.com/acme-corp/dotfiles.git.
+func (r RedactionRules) excludesRepoURL(url string) bool {
+	trimmed := strings.TrimSuffix(url, ".git")
+	if i := strings.Index(trimmed, "://"); i >= 0 {
+		trimmed = trimmed[i+3:]
+	}
+	if i := strings.Index(trimmed, "@"); i >= 0 && !strings.Contains(trimmed[:i], "/") {
+		trimmed = trimmed[i+1:]
+	}
+	candidates := []string{url, trimmed}
+	candidates = append(candidates, strings.FieldsFunc(trimmed, func(c rune) bool { return c == '/' || c == ':' })...)
+	for _, p := range r.Exclude {
+		for _, c := range candidates {
+			if ok, _ := path.Match(p, c); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r RedactionRules) filter(field string, items []string, changes []Redaction) ([]string, []Redaction) {
+	if len(r.Exclude) == 0 || items == nil {
+		return items, changes
+	}
+	kept := make([]string, 0, len(items))
+	for _, item := range items {
+		if r.Excludes(item) {
+			changes = append(changes, Redaction{Field: field, Value: item, Action: "removed"})
+			continue
+		}
+		kept = append(kept, item)
+	}
+	return kept, changes
+}
+
+// HashEmail replaces an email with an unsalted digest, so the same person
+// hashes the same way across snapshots. It only hides the address from
+// casual readers: anyone who can guess the address can confirm it by
+// hashing it. Use EmailRemove when the address must not be recoverable.
+func HashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
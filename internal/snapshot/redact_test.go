@@ -0,0 +1,130 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func redactTestSnapshot() *Snapshot {
+	return &Snapshot{
+		Version:  CurrentVersion,
+		Hostname: "alice-acme-mbp",
+		Git:      GitSnapshot{UserName: "Alice", UserEmail: "Alice@Example.com"},
+		Packages: PackageSnapshot{
+			Formulae: []string{"git", "acme-corp/tools/deployer", "foo-internal"},
+			Casks:    []string{"firefox"},
+			Taps:     []string{"homebrew/cask", "acme-corp/tools"},
+			Npm:      []string{"typescript"},
+		},
+		CatalogMatch: CatalogMatch{
+			Matched:   []string{"git", "firefox"},
+			Unmatched: []string{"acme-corp/tools/deployer", "foo-internal"},
+		},
+	}
+}
+
+func TestRedactionRules_ApplyNoRulesKeepsEverything(t *testing.T) {
+	snap := redactTestSnapshot()
+
+	out, changes := RedactionRules{Email: EmailKeep}.Apply(snap)
+
+	assert.Empty(t, changes)
+	assert.Equal(t, snap, out)
+}
+
+func TestRedactionRules_Apply(t *testing.T) {
+	snap := redactTestSnapshot()
+	rules := RedactionRules{
+		DropHostname: true,
+		Email:        EmailHash,
+		Exclude:      []string{"acme-corp/*", "*-internal"},
+	}
+
+	out, changes := rules.Apply(snap)
+
+	assert.Empty(t, out.Hostname)
+	assert.Equal(t, HashEmail("alice@example.com"), out.Git.UserEmail)
+	assert.Equal(t, []string{"git"}, out.Packages.Formulae)
+	assert.Equal(t, []string{"homebrew/cask"}, out.Packages.Taps)
+	assert.Equal(t, []string{"firefox"}, out.Packages.Casks)
+	assert.Empty(t, out.CatalogMatch.Unmatched)
+	assert.Len(t, changes, 5)
+
+	assert.Equal(t, "alice-acme-mbp", snap.Hostname, "the original must not be modified")
+	assert.Len(t, snap.Packages.Formulae, 3)
+}
+
+func TestRedactionRules_RemoveEmail(t *testing.T) {
+	out, changes := RedactionRules{Email: EmailRemove}.Apply(redactTestSnapshot())
+
+	assert.Empty(t, out.Git.UserEmail)
+	require.Len(t, changes, 1)
+	assert.Equal(t, Redaction{Field: "git.user_email", Value: "Alice@Example.com", Action: "removed"}, changes[0])
+}
+
+func TestRedactionRules_DropGitNameAndDotfilesRepo(t *testing.T) {
+	snap := redactTestSnapshot()
+	snap.Dotfiles = DotfilesSnapshot{RepoURL: "https://github.com/alice/dotfiles.git", Ref: "acme"}
+
+	out, changes := RedactionRules{DropGitName: true, DropDotfilesRepo: true}.Apply(snap)
+
+	assert.Empty(t, out.Git.UserName)
+	assert.Equal(t, "Alice@Example.com", out.Git.UserEmail)
+	assert.Equal(t, DotfilesSnapshot{}, out.Dotfiles)
+	assert.Equal(t, []Redaction{
+		{Field: "git.user_name", Value: "Alice", Action: "removed"},
+		{Field: "dotfiles.repo_url", Value: "https://github.com/alice/dotfiles.git", Action: "removed"},
+	}, changes)
+	assert.Equal(t, "https://github.com/alice/dotfiles.git", snap.Dotfiles.RepoURL, "the original must not be modified")
+}
+
+func TestRedactionRules_ExcludeMatchesDotfilesRepo(t *testing.T) {
+	tests := []struct {
+		url      string
+		excluded bool
+	}{
+		{"https://github.com/acme-corp/dotfiles.git", true},
+		{"git@github.com:acme-corp/dotfiles.git", true},
+		{"https://gitlab.acme-corp.com/alice/dotfiles", false},
+		{"https://github.com/alice/dotfiles", false},
+	}
+	rules := RedactionRules{Exclude: []string{"acme-corp"}}
+	for _, tt := range tests {
+		snap := redactTestSnapshot()
+		snap.Dotfiles.RepoURL = tt.url
+		out, _ := rules.Apply(snap)
+		assert.Equal(t, tt.excluded, out.Dotfiles.RepoURL == "", tt.url)
+	}
+
+	snap := redactTestSnapshot()
+	snap.Dotfiles.RepoURL = "https://gitlab.acme-corp.com/alice/dotfiles"
+	out, _ := RedactionRules{Exclude: []string{"*.acme-corp.com"}}.Apply(snap)
+	assert.Empty(t, out.Dotfiles.RepoURL)
+}
+
+func TestRedactionRules_Validate(t *testing.T) {
+	assert.NoError(t, RedactionRules{Email: EmailHash, Exclude: []string{"acme/*"}}.Validate())
+	assert.Error(t, RedactionRules{Email: "scramble"}.Validate())
+	assert.Error(t, RedactionRules{Exclude: []string{"["}}.Validate())
+}
+
+func TestLoadRedactionRules(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	rules, err := LoadRedactionRules()
+	require.NoError(t, err)
+	assert.Equal(t, RedactionRules{Email: EmailKeep}, rules)
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(RedactionRulesPath()), 0700))
+	require.NoError(t, os.WriteFile(RedactionRulesPath(), []byte(`{"drop_hostname": true, "exclude": ["acme/*"]}`), 0600))
+
+	rules, err = LoadRedactionRules()
+	require.NoError(t, err)
+	assert.True(t, rules.DropHostname)
+	assert.Equal(t, EmailKeep, rules.Email)
+	assert.Equal(t, []string{"acme/*"}, rules.Exclude)
+}
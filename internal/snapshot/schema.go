@@ -0,0 +1,117 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentVersion is the schema version written by this build. Bump it
+// together with a new entry in migrations whenever a field is added,
+// renamed or changes meaning.
+const CurrentVersion = 3
+
+// migration upgrades a decoded snapshot from version N to N+1 in place.
+type migration func(doc map[string]any) error
+
+// migrations maps each version to the step that upgrades it to the next one.
+var migrations = map[int]migration{
+	1: migrateV1ToV2,
+	2: migrateV2ToV3,
+}
+
+// migrateV1ToV2 adds the dotfiles section, which version 1 didn't have.
+func migrateV1ToV2(doc map[string]any) error {
+	if _, ok := doc["dotfiles"]; !ok {
+		doc["dotfiles"] = map[string]any{"repo_url": "", "ref": ""}
+	}
+	return nil
+}
+
+// migrateV2ToV3 is a no-op: version 3 adds the optional machine section,
+// which older snapshots simply don't have.
+func migrateV2ToV3(doc map[string]any) error {
+	return nil
+}
+
+// Migrate upgrades raw snapshot JSON to CurrentVersion and returns the
+// version it started at. Snapshots without a version (or with version 0)
+// predate versioning and are treated as version 1.
+func Migrate(data []byte) (map[string]any, int, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, 0, err
+	}
+	if doc == nil {
+		return nil, 0, fmt.Errorf("snapshot is empty")
+	}
+
+	from := 1
+	if raw, ok := doc["version"]; ok && raw != nil {
+		v, ok := raw.(float64)
+		if !ok || v != float64(int(v)) {
+			return nil, 0, fmt.Errorf("snapshot version must be an integer, got %v", raw)
+		}
+		if v != 0 {
+			from = int(v)
+		}
+	}
+
+	switch {
+	case from < 1:
+		return nil, from, fmt.Errorf("unknown snapshot version %d", from)
+	case from > CurrentVersion:
+		return nil, from, fmt.Errorf("snapshot version %d is newer than this openboot supports (version %d) — run 'openboot update'", from, CurrentVersion)
+	}
+
+	for v := from; v < CurrentVersion; v++ {
+		step, ok := migrations[v]
+		if !ok {
+			return nil, from, fmt.Errorf("no migration from snapshot version %d", v)
+		}
+		if err := step(doc); err != nil {
+			return nil, from, fmt.Errorf("failed to migrate snapshot from version %d: %w", v, err)
+		}
+		doc["version"] = v + 1
+	}
+	return doc, from, nil
+}
+
+// Parse decodes snapshot JSON of any supported version, migrating it to
+// CurrentVersion and validating the result.
+func Parse(data []byte) (*Snapshot, error) {
+	doc, _, err := Migrate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(migrated, &snap); err != nil {
+		return nil, err
+	}
+	if err := snap.Validate(); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// Validate reports the first structural problem in the snapshot.
+func (s *Snapshot) Validate() error {
+	if s.Version != CurrentVersion {
+		return fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	for i, p := range s.MacOSPrefs {
+		if p.Domain == "" || p.Key == "" {
+			return fmt.Errorf("macos_prefs[%d] needs both a domain and a key", i)
+		}
+	}
+	for i, t := range s.DevTools {
+		if t.Name == "" {
+			return fmt.Errorf("dev_tools[%d] has no name", i)
+		}
+	}
+	return nil
+}
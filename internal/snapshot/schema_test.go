@@ -0,0 +1,95 @@
+package snapshot
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate_V1AddsDotfiles(t *testing.T) {
+	doc, from, err := Migrate([]byte(`{"version": 1, "hostname": "old"}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, from)
+	assert.Equal(t, CurrentVersion, doc["version"])
+	assert.Contains(t, doc, "dotfiles")
+	assert.Equal(t, "old", doc["hostname"])
+}
+
+func TestMigrate_MissingVersionIsV1(t *testing.T) {
+	_, from, err := Migrate([]byte(`{"hostname": "old"}`))
+	require.NoError(t, err)
+	assert.Equal(t, 1, from)
+}
+
+func TestMigrate_CurrentVersionUnchanged(t *testing.T) {
+	_, from, err := Migrate([]byte(`{"version": 3, "dotfiles": {"repo_url": "https://example.com/d.git"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, CurrentVersion, from)
+}
+
+func TestMigrate_RejectsNewerVersion(t *testing.T) {
+	_, _, err := Migrate([]byte(`{"version": 99}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "newer than this openboot supports")
+}
+
+func TestMigrate_RejectsUnknownVersion(t *testing.T) {
+	_, _, err := Migrate([]byte(`{"version": -1}`))
+	assert.ErrorContains(t, err, "unknown snapshot version -1")
+
+	_, _, err = Migrate([]byte(`{"version": "two"}`))
+	assert.ErrorContains(t, err, "must be an integer")
+}
+
+func TestMigrate_EveryVersionHasAStep(t *testing.T) {
+	for v := 1; v < CurrentVersion; v++ {
+		assert.Contains(t, migrations, v, "missing migration from version %d", v)
+	}
+}
+
+func TestParse_Validates(t *testing.T) {
+	_, err := Parse([]byte(`{"version": 2, "macos_prefs": [{"domain": "com.apple.dock"}]}`))
+	assert.ErrorContains(t, err, "macos_prefs[0]")
+
+	_, err = Parse([]byte(`{"version": 2, "dev_tools": [{"version": "1.0"}]}`))
+	assert.ErrorContains(t, err, "dev_tools[0]")
+}
+
+func TestParse_UpgradesV2(t *testing.T) {
+	snap, err := Parse([]byte(`{"version": 2, "hostname": "old"}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, CurrentVersion, snap.Version)
+	assert.Nil(t, snap.Machine)
+}
+
+func TestParse_UpgradesV1(t *testing.T) {
+	snap, err := Parse([]byte(`{"version": 1, "hostname": "old", "packages": {"formulae": ["git"]}}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, CurrentVersion, snap.Version)
+	assert.Equal(t, "old", snap.Hostname)
+	assert.Equal(t, []string{"git"}, snap.Packages.Formulae)
+}
+
+func TestJSONSchema_MatchesPublishedFile(t *testing.T) {
+	generated, err := JSONSchema()
+	require.NoError(t, err)
+
+	published, err := os.ReadFile("../../schemas/snapshot.schema.json")
+	require.NoError(t, err)
+
+	assert.Equal(t, strings.TrimSpace(string(published)), string(generated),
+		"schemas/snapshot.schema.json is out of date — run 'make schema'")
+}
+
+func TestJSONSchema_VersionIsConst(t *testing.T) {
+	data, err := JSONSchema()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"const": 3`)
+	assert.Contains(t, string(data), SchemaID)
+}
@@ -0,0 +1,212 @@
+package snapshot
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SignatureSuffix is appended to a snapshot's path or URL to find its
+// detached signature.
+const SignatureSuffix = ".sig"
+
+// Signature is the detached signature stored next to a snapshot.
+type Signature struct {
+	KeyID     string `json:"key_id"`
+	Signature string `json:"signature"`
+}
+
+// TrustedKey is a public key whose signatures are accepted on import.
+type TrustedKey struct {
+	Key     ed25519.PublicKey
+	Comment string
+}
+
+// SigningKeyPath returns where this machine's private signing key is kept.
+func SigningKeyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".openboot", "signing_key")
+}
+
+// TrustedKeysPath returns the file listing trusted public keys, one per
+// line as "<base64 key> [comment]".
+func TrustedKeysPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".openboot", "trusted_keys")
+}
+
+// LoadOrCreateSigningKey loads the signing key, generating and saving one
+// first if there isn't one yet. created reports whether it was generated.
+func LoadOrCreateSigningKey() (key ed25519.PrivateKey, created bool, err error) {
+	path := SigningKeyPath()
+	data, err := os.ReadFile(path)
+	if err == nil {
+		seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, false, fmt.Errorf("invalid signing key in %s", path)
+		}
+		return ed25519.NewKeyFromSeed(seed), false, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, false, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	_, key, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, false, fmt.Errorf("failed to create directory: %w", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(key.Seed()) + "\n"
+	if err := os.WriteFile(path, []byte(encoded), 0600); err != nil {
+		return nil, false, fmt.Errorf("failed to write signing key: %w", err)
+	}
+	return key, true, nil
+}
+
+// EncodePublicKey formats a public key the way trusted_keys stores it.
+func EncodePublicKey(key ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// ParsePublicKey parses a key produced by EncodePublicKey.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("not an ed25519 public key: %q", s)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// KeyID returns a short fingerprint identifying key.
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Sign returns a detached signature of data.
+func Sign(data []byte, key ed25519.PrivateKey) Signature {
+	return Signature{
+		KeyID:     KeyID(key.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)),
+	}
+}
+
+// ParseSignature decodes a .sig file.
+func ParseSignature(data []byte) (*Signature, error) {
+	var sig Signature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return nil, fmt.Errorf("failed to parse signature: %w", err)
+	}
+	if sig.KeyID == "" || sig.Signature == "" {
+		return nil, fmt.Errorf("signature is missing key_id or signature")
+	}
+	return &sig, nil
+}
+
+// LoadTrustedKeys reads trusted_keys. A missing file means no keys.
+func LoadTrustedKeys() ([]TrustedKey, error) {
+	f, err := os.Open(TrustedKeysPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted keys: %w", err)
+	}
+	defer f.Close()
+
+	var keys []TrustedKey
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		encoded, comment, _ := strings.Cut(text, " ")
+		key, err := ParsePublicKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", TrustedKeysPath(), line, err)
+		}
+		keys = append(keys, TrustedKey{Key: key, Comment: strings.TrimSpace(comment)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read trusted keys: %w", err)
+	}
+	return keys, nil
+}
+
+// AddTrustedKey appends key to trusted_keys unless it is already there.
+func AddTrustedKey(key ed25519.PublicKey, comment string) error {
+	keys, err := LoadTrustedKeys()
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if k.Key.Equal(key) {
+			return nil
+		}
+	}
+
+	path := TrustedKeysPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open trusted keys: %w", err)
+	}
+	defer f.Close()
+
+	line := EncodePublicKey(key)
+	if comment != "" {
+		line += " " + comment
+	}
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		return fmt.Errorf("failed to write trusted keys: %w", err)
+	}
+	return nil
+}
+
+// Verify checks sig over data against the trusted keys and returns the key
+// that made it.
+func Verify(data []byte, sig *Signature, trusted []TrustedKey) (*TrustedKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature: %w", err)
+	}
+	for i, k := range trusted {
+		if KeyID(k.Key) != sig.KeyID {
+			continue
+		}
+		if !ed25519.Verify(k.Key, data, raw) {
+			return nil, fmt.Errorf("signature by key %s does not match the snapshot", sig.KeyID)
+		}
+		return &trusted[i], nil
+	}
+	return nil, fmt.Errorf("snapshot is signed by key %s, which is not in %s", sig.KeyID, TrustedKeysPath())
+}
+
+// VerifySHA256 checks data against a hex-encoded SHA-256 digest.
+func VerifySHA256(data []byte, want string) error {
+	sum := sha256.Sum256(data)
+	got := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(got, strings.TrimSpace(want)) {
+		return fmt.Errorf("sha256 mismatch: expected %s, got %s", want, got)
+	}
+	return nil
+}
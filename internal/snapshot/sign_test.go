@@ -0,0 +1,99 @@
+package snapshot
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify_RoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	key, created, err := LoadOrCreateSigningKey()
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	data := []byte(`{"version": 2}`)
+	sig := Sign(data, key)
+	pub := key.Public().(ed25519.PublicKey)
+	trusted := []TrustedKey{{Key: pub, Comment: "alice"}}
+
+	signer, err := Verify(data, &sig, trusted)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", signer.Comment)
+
+	_, err = Verify([]byte(`{"version": 2, "packages": {"taps": ["evil/tap"]}}`), &sig, trusted)
+	assert.ErrorContains(t, err, "does not match")
+
+	_, err = Verify(data, &sig, nil)
+	assert.ErrorContains(t, err, "not in")
+}
+
+func TestLoadOrCreateSigningKey_ReusesKey(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	first, _, err := LoadOrCreateSigningKey()
+	require.NoError(t, err)
+	second, created, err := LoadOrCreateSigningKey()
+	require.NoError(t, err)
+
+	assert.False(t, created)
+	assert.True(t, first.Equal(second))
+
+	info, err := os.Stat(SigningKeyPath())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestTrustedKeys_AddAndLoad(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	keys, err := LoadTrustedKeys()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	pub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	require.NoError(t, AddTrustedKey(pub, "bob laptop"))
+	require.NoError(t, AddTrustedKey(pub, "duplicate"))
+
+	keys, err = LoadTrustedKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.True(t, keys[0].Key.Equal(pub))
+	assert.Equal(t, "bob laptop", keys[0].Comment)
+}
+
+func TestLoadTrustedKeys_RejectsBadLine(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, os.MkdirAll(filepath.Dir(TrustedKeysPath()), 0700))
+	require.NoError(t, os.WriteFile(TrustedKeysPath(), []byte("# comment\n\nnot-a-key\n"), 0600))
+
+	_, err := LoadTrustedKeys()
+	assert.ErrorContains(t, err, "line 3")
+}
+
+func TestParseSignature(t *testing.T) {
+	_, err := ParseSignature([]byte(`{}`))
+	assert.Error(t, err)
+
+	sig, err := ParseSignature([]byte(`{"key_id": "abc", "signature": "c2ln"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "abc", sig.KeyID)
+}
+
+func TestVerifySHA256(t *testing.T) {
+	data := []byte("snapshot")
+	sum := sha256.Sum256(data)
+	want := hex.EncodeToString(sum[:])
+
+	assert.NoError(t, VerifySHA256(data, want))
+	assert.NoError(t, VerifySHA256(data, "  "+want+"\n"))
+	assert.ErrorContains(t, VerifySHA256([]byte("tampered"), want), "sha256 mismatch")
+}
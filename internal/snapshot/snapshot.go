@@ -3,16 +3,18 @@ package snapshot
 import "time"
 
 type Snapshot struct {
-	Version       int             `json:"version"`
-	CapturedAt    time.Time       `json:"captured_at"`
-	Hostname      string          `json:"hostname"`
-	Packages      PackageSnapshot `json:"packages"`
-	MacOSPrefs    []MacOSPref     `json:"macos_prefs"`
-	Shell         ShellSnapshot   `json:"shell"`
-	Git           GitSnapshot     `json:"git"`
-	DevTools      []DevTool       `json:"dev_tools"`
-	MatchedPreset string          `json:"matched_preset"`
-	CatalogMatch  CatalogMatch    `json:"catalog_match"`
+	Version       int              `json:"version"`
+	CapturedAt    time.Time        `json:"captured_at"`
+	Hostname      string           `json:"hostname"`
+	Machine       *MachineInfo     `json:"machine,omitempty"`
+	Packages      PackageSnapshot  `json:"packages"`
+	MacOSPrefs    []MacOSPref      `json:"macos_prefs"`
+	Shell         ShellSnapshot    `json:"shell"`
+	Git           GitSnapshot      `json:"git"`
+	Dotfiles      DotfilesSnapshot `json:"dotfiles"`
+	DevTools      []DevTool        `json:"dev_tools"`
+	MatchedPreset string           `json:"matched_preset"`
+	CatalogMatch  CatalogMatch     `json:"catalog_match"`
 }
 
 type PackageSnapshot struct {
@@ -41,6 +43,15 @@ type GitSnapshot struct {
 	UserEmail string `json:"user_email"`
 }
 
+// DotfilesSnapshot records where the dotfiles repo came from. Path is only
+// set when the repo is not cloned to ~/.dotfiles.
+type DotfilesSnapshot struct {
+	RepoURL string `json:"repo_url"`
+	Ref     string `json:"ref"`
+	Subdir  string `json:"subdir,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
+
 type DevTool struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
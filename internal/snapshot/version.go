@@ -0,0 +1,172 @@
+package snapshot
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)*`)
+
+// ToolVersion runs name to find its version, parsed the same way as the
+// dev tools in a snapshot. Other commands are run with --version and the
+// first dotted number in the output is taken.
+func ToolVersion(name string) (string, error) {
+	args := []string{"--version"}
+	for _, dt := range devToolCommands {
+		if dt.name == name {
+			args = dt.args
+			break
+		}
+	}
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	version := versionPattern.FindString(parseVersion(name, strings.TrimSpace(string(output))))
+	if version == "" {
+		return "", fmt.Errorf("no version in the output of %s %s", name, strings.Join(args, " "))
+	}
+	return version, nil
+}
+
+// MatchVersion reports whether version satisfies constraint, a semver-style
+// range: space- or comma-separated comparisons that must all hold
+// (">=1.20 <2"), alternatives joined with "||", caret and tilde ranges
+// ("^1.2", "~3.11") and partial or wildcard versions ("20", "3.12.x") that
+// match every release they cover. An empty constraint matches anything.
+func MatchVersion(version, constraint string) (bool, error) {
+	have, err := versionParts(version)
+	if err != nil {
+		return false, err
+	}
+	if strings.TrimSpace(constraint) == "" {
+		return true, nil
+	}
+
+	matched := false
+	for _, alt := range strings.Split(constraint, "||") {
+		terms := strings.FieldsFunc(alt, func(r rune) bool { return r == ' ' || r == ',' })
+		if len(terms) == 0 {
+			return false, fmt.Errorf("invalid version range %q", constraint)
+		}
+		all := true
+		for _, term := range terms {
+			ok, err := matchTerm(have, term)
+			if err != nil {
+				return false, fmt.Errorf("invalid version range %q: %w", constraint, err)
+			}
+			all = all && ok
+		}
+		matched = matched || all
+	}
+	return matched, nil
+}
+
+func matchTerm(have []int, term string) (bool, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(term, prefix) {
+			op = prefix
+			break
+		}
+	}
+	want, err := rangeParts(term[len(op):])
+	if err != nil {
+		return false, err
+	}
+
+	// Comparing only the parts given makes ">1" mean ">=2" and "<=1.2"
+	// cover every 1.2.x release, as in npm.
+	cmp := compareParts(have, want, len(want))
+	switch op {
+	case "", "=":
+		return cmp == 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case "^":
+		// Everything up to the first non-zero part is fixed.
+		fixed := len(want)
+		for i, n := range want {
+			if n != 0 {
+				fixed = i + 1
+				break
+			}
+		}
+		return cmp >= 0 && compareParts(have, want, fixed) == 0, nil
+	default: // "~"
+		fixed := len(want)
+		if fixed > 2 {
+			fixed = 2
+		}
+		return cmp >= 0 && compareParts(have, want, fixed) == 0, nil
+	}
+}
+
+// versionParts parses the leading dotted number of an installed version,
+// so "3.12.0rc1" is [3 12 0].
+func versionParts(version string) ([]int, error) {
+	s := versionPattern.FindString(strings.TrimPrefix(strings.TrimSpace(version), "v"))
+	if s == "" {
+		return nil, fmt.Errorf("can't parse version %q", version)
+	}
+	var parts []int
+	for _, p := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse version %q", version)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
+
+// rangeParts parses the version in a range term. Parsing stops at a
+// wildcard part ("x", "X" or "*").
+func rangeParts(s string) ([]int, error) {
+	s = strings.TrimPrefix(s, "v")
+	if s == "" {
+		return nil, fmt.Errorf("missing version")
+	}
+	var parts []int
+	for _, p := range strings.Split(s, ".") {
+		if p == "x" || p == "X" || p == "*" {
+			break
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("bad version %q", s)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
+
+// compareParts compares the first n parts of a and b, treating missing
+// parts as zero.
+func compareParts(a, b []int, n int) int {
+	for i := 0; i < n; i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchVersion(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"20.11.0", "", true},
+		{"20.11.0", ">=20", true},
+		{"18.19.0", ">=20", false},
+		{"20.11.0", "20", true},
+		{"20.11.0", "=20.11.0", true},
+		{"20.11.1", "20.11.0", false},
+		{"2.0.0", ">1", true},
+		{"1.9.0", ">1", false},
+		{"1.2.9", "<=1.2", true},
+		{"1.3.0", "<=1.2", false},
+		{"1.22.5", "^1.20", true},
+		{"2.0.0", "^1.20", false},
+		{"0.2.5", "^0.2.3", true},
+		{"0.3.0", "^0.2.3", false},
+		{"3.11.8", "~3.11", true},
+		{"3.12.0", "~3.11", false},
+		{"3.12.1", "3.12.x", true},
+		{"3.13.0", "3.12.*", false},
+		{"1.21.0", ">=1.20, <2", true},
+		{"2.1.0", ">=1.20 <2", false},
+		{"16.20.0", "^18 || ^20", false},
+		{"20.1.0", "^18 || ^20", true},
+		{"v21.0.1", ">=21", true},
+		{"3.13.0rc1", ">=3.13", true},
+	}
+	for _, tt := range tests {
+		got, err := MatchVersion(tt.version, tt.constraint)
+		require.NoError(t, err, "%s %s", tt.version, tt.constraint)
+		assert.Equal(t, tt.want, got, "%s %s", tt.version, tt.constraint)
+	}
+}
+
+func TestMatchVersion_Errors(t *testing.T) {
+	_, err := MatchVersion("unknown", ">=1")
+	assert.ErrorContains(t, err, "can't parse version")
+
+	for _, constraint := range []string{">=", "^one", "1 ||", ">=1.2."} {
+		_, err := MatchVersion("1.2.3", constraint)
+		assert.ErrorContains(t, err, "invalid version range", constraint)
+	}
+}
+
+func TestToolVersion(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "node"), []byte("#!/bin/sh\necho v18.19.0\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "terraform"), []byte("#!/bin/sh\nprintf 'Terraform v1.6.2\\non darwin_arm64\\n'\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken"), []byte("#!/bin/sh\necho oops\n"), 0755))
+	t.Setenv("PATH", dir)
+
+	v, err := ToolVersion("node")
+	require.NoError(t, err)
+	assert.Equal(t, "18.19.0", v)
+
+	v, err = ToolVersion("terraform")
+	require.NoError(t, err)
+	assert.Equal(t, "1.6.2", v)
+
+	_, err = ToolVersion("broken")
+	assert.ErrorContains(t, err, "no version")
+
+	_, err = ToolVersion("missing")
+	assert.Error(t, err)
+}
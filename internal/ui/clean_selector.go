@@ -0,0 +1,311 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// CleanItem is one package clean proposes to remove. Size and Modified are
+// zero when unknown.
+type CleanItem struct {
+	Name     string
+	Size     int64
+	Modified time.Time
+}
+
+// CleanGroup is a tab of the clean selector, e.g. "Formulae".
+type CleanGroup struct {
+	Name  string
+	Icon  string
+	Items []CleanItem
+}
+
+type cleanRow struct {
+	item     CleanItem
+	selected bool
+}
+
+type cleanTab struct {
+	group CleanGroup
+	rows  []cleanRow
+}
+
+// CleanSelectorModel is a checklist of packages to remove, one tab per
+// group. Everything starts ticked; unticked packages are kept.
+type CleanSelectorModel struct {
+	tabs         []cleanTab
+	activeTab    int
+	cursor       int
+	scrollOffset int
+	confirmed    bool
+	width        int
+	height       int
+}
+
+func NewCleanSelector(groups []CleanGroup) CleanSelectorModel {
+	var tabs []cleanTab
+	for _, g := range groups {
+		rows := make([]cleanRow, len(g.Items))
+		for i, item := range g.Items {
+			rows[i] = cleanRow{item: item, selected: true}
+		}
+		tabs = append(tabs, cleanTab{group: g, rows: rows})
+	}
+	return CleanSelectorModel{tabs: tabs}
+}
+
+func (m CleanSelectorModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m CleanSelectorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+	case tea.KeyMsg:
+		if len(m.tabs) == 0 {
+			if key.Matches(msg, keys.Quit) || key.Matches(msg, keys.Enter) {
+				m.confirmed = key.Matches(msg, keys.Enter)
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
+		switch {
+		case key.Matches(msg, keys.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, keys.Tab), key.Matches(msg, keys.Right):
+			m.activeTab = (m.activeTab + 1) % len(m.tabs)
+			m.cursor = 0
+			m.scrollOffset = 0
+
+		case key.Matches(msg, keys.ShiftTab), key.Matches(msg, keys.Left):
+			m.activeTab = (m.activeTab - 1 + len(m.tabs)) % len(m.tabs)
+			m.cursor = 0
+			m.scrollOffset = 0
+
+		case key.Matches(msg, keys.Up):
+			if m.cursor > 0 {
+				m.cursor--
+				if m.cursor < m.scrollOffset {
+					m.scrollOffset = m.cursor
+				}
+			}
+
+		case key.Matches(msg, keys.Down):
+			if m.cursor < len(m.tabs[m.activeTab].rows)-1 {
+				m.cursor++
+				visibleItems := m.getVisibleItems()
+				if m.cursor >= m.scrollOffset+visibleItems {
+					m.scrollOffset = m.cursor - visibleItems + 1
+				}
+			}
+
+		case key.Matches(msg, keys.Space):
+			tab := &m.tabs[m.activeTab]
+			if m.cursor < len(tab.rows) {
+				tab.rows[m.cursor].selected = !tab.rows[m.cursor].selected
+			}
+
+		case key.Matches(msg, keys.SelectAll):
+			tab := &m.tabs[m.activeTab]
+			allSelected := true
+			for _, row := range tab.rows {
+				if !row.selected {
+					allSelected = false
+					break
+				}
+			}
+			for i := range tab.rows {
+				tab.rows[i].selected = !allSelected
+			}
+
+		case key.Matches(msg, keys.Enter):
+			m.confirmed = true
+			return m, tea.Quit
+		}
+	}
+
+	return m, nil
+}
+
+func (m CleanSelectorModel) getVisibleItems() int {
+	if m.height == 0 {
+		return 15
+	}
+	// Account for: title(3) + tabs(2) + totals(2) + help(2) + padding(2)
+	available := m.height - 11
+	if available < 5 {
+		available = 5
+	}
+	if available > 20 {
+		available = 20
+	}
+	return available
+}
+
+func (m CleanSelectorModel) View() string {
+	var lines []string
+
+	lines = append(lines, "")
+	lines = append(lines, activeTabStyle.Render("🧹 Clean — Untick anything you want to keep"))
+	lines = append(lines, "")
+
+	var tabs []string
+	for i, tab := range m.tabs {
+		count := 0
+		for _, row := range tab.rows {
+			if row.selected {
+				count++
+			}
+		}
+		label := fmt.Sprintf("%s %s (%d/%d)", tab.group.Icon, tab.group.Name, count, len(tab.rows))
+		if i == m.activeTab {
+			tabs = append(tabs, activeTabStyle.Render(label))
+		} else {
+			tabs = append(tabs, tabStyle.Render(label))
+		}
+	}
+	lines = append(lines, lipgloss.JoinHorizontal(lipgloss.Top, tabs...))
+	lines = append(lines, "")
+
+	visibleItems := m.getVisibleItems()
+	if len(m.tabs) == 0 || len(m.tabs[m.activeTab].rows) == 0 {
+		lines = append(lines, descStyle.Render("  No items"))
+	} else {
+		rows := m.tabs[m.activeTab].rows
+		nameWidth := 0
+		for _, row := range rows {
+			nameWidth = max(nameWidth, len(row.item.Name))
+		}
+
+		scrollOffset := min(m.scrollOffset, len(rows)-visibleItems)
+		scrollOffset = max(scrollOffset, 0)
+		endIdx := min(scrollOffset+visibleItems, len(rows))
+
+		for i := scrollOffset; i < endIdx; i++ {
+			row := rows[i]
+			cursor := "  "
+			if i == m.cursor {
+				cursor = "> "
+			}
+
+			checkbox := "[ ]"
+			style := itemStyle
+			if row.selected {
+				checkbox = "[✓]"
+				style = selectedStyle
+			}
+
+			name := fmt.Sprintf("%-*s", nameWidth, row.item.Name)
+			line := fmt.Sprintf("%s%s %s  %s", cursor, checkbox, style.Render(name), descStyle.Render(cleanItemDetails(row.item)))
+			if m.width > 0 && lipgloss.Width(line) > m.width {
+				line = lipgloss.NewStyle().MaxWidth(m.width).Render(line)
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	clearWidth := 80
+	if m.width > 0 && m.width < 80 {
+		clearWidth = m.width
+	}
+	clearLine := strings.Repeat(" ", clearWidth)
+	for len(lines) < visibleItems+5 {
+		lines = append(lines, clearLine)
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, countStyle.Render(m.totalsSummary()))
+
+	lines = append(lines, "")
+	lines = append(lines, helpStyle.Render("Tab/←→: switch • ↑↓: navigate • Space: toggle • a: all • Enter: remove ticked • q: cancel"))
+
+	return strings.Join(lines, "\n")
+}
+
+func (m CleanSelectorModel) totalsSummary() string {
+	var remove, keep int
+	var size int64
+	for _, tab := range m.tabs {
+		for _, row := range tab.rows {
+			if row.selected {
+				remove++
+				size += row.item.Size
+			} else {
+				keep++
+			}
+		}
+	}
+	return fmt.Sprintf("Removing %d packages (%s) • keeping %d", remove, FormatSize(size), keep)
+}
+
+func cleanItemDetails(item CleanItem) string {
+	size := "—"
+	if item.Size > 0 {
+		size = FormatSize(item.Size)
+	}
+	modified := "—"
+	if !item.Modified.IsZero() {
+		modified = item.Modified.Local().Format("Jan 2, 2006")
+	}
+	return fmt.Sprintf("%9s  modified %s", size, modified)
+}
+
+// FormatSize renders a byte count as e.g. "12.3 MB".
+func FormatSize(bytes int64) string {
+	const unit = 1000
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "kMGTPE"[exp])
+}
+
+// selection returns, per group, the names left ticked and the names
+// unticked.
+func (m CleanSelectorModel) selection() (remove, keep [][]string) {
+	remove = make([][]string, len(m.tabs))
+	keep = make([][]string, len(m.tabs))
+	for i, tab := range m.tabs {
+		for _, row := range tab.rows {
+			if row.selected {
+				remove[i] = append(remove[i], row.item.Name)
+			} else {
+				keep[i] = append(keep[i], row.item.Name)
+			}
+		}
+	}
+	return remove, keep
+}
+
+// RunCleanSelector shows the clean checklist. It returns, for each group
+// in order, the packages to remove and the ones the user chose to keep.
+// If the user cancels, confirmed is false.
+func RunCleanSelector(groups []CleanGroup) (remove, keep [][]string, confirmed bool, err error) {
+	p := tea.NewProgram(NewCleanSelector(groups), tea.WithAltScreen())
+
+	finalModel, err := p.Run()
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	m := finalModel.(CleanSelectorModel)
+	if !m.confirmed {
+		return nil, nil, false, nil
+	}
+	remove, keep = m.selection()
+	return remove, keep, true, nil
+}
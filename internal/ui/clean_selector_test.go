@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cleanGroups() []CleanGroup {
+	return []CleanGroup{
+		{Name: "Formulae", Items: []CleanItem{{Name: "wget"}, {Name: "jq"}, {Name: "htop"}}},
+		{Name: "Casks", Items: []CleanItem{{Name: "firefox"}}},
+	}
+}
+
+// pressKeys feeds key messages to m and reports whether the last one quit.
+func pressKeys(t *testing.T, m CleanSelectorModel, msgs ...tea.KeyMsg) (CleanSelectorModel, bool) {
+	t.Helper()
+	var cmd tea.Cmd
+	for _, msg := range msgs {
+		var model tea.Model
+		model, cmd = m.Update(msg)
+		m = model.(CleanSelectorModel)
+	}
+	if cmd == nil {
+		return m, false
+	}
+	_, quit := cmd().(tea.QuitMsg)
+	return m, quit
+}
+
+func TestCleanSelector_UntickAndConfirm(t *testing.T) {
+	m, quit := pressKeys(t, NewCleanSelector(cleanGroups()),
+		tea.KeyMsg{Type: tea.KeyDown},
+		tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}},
+		tea.KeyMsg{Type: tea.KeyTab},
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}},
+		tea.KeyMsg{Type: tea.KeyEnter},
+	)
+
+	require.True(t, quit)
+	assert.True(t, m.confirmed)
+	remove, keep := m.selection()
+	assert.Equal(t, [][]string{{"wget", "htop"}, nil}, remove)
+	assert.Equal(t, [][]string{{"jq"}, {"firefox"}}, keep)
+}
+
+func TestCleanSelector_Cancel(t *testing.T) {
+	m, quit := pressKeys(t, NewCleanSelector(cleanGroups()),
+		tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}},
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}},
+	)
+
+	require.True(t, quit)
+	assert.False(t, m.confirmed)
+}
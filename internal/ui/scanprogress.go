@@ -148,7 +148,17 @@ func (sp *ScanProgress) renderTTY() {
 	if !sp.rendered {
 		sp.rendered = true
 	}
-	fmt.Fprintf(os.Stderr, "\033[K  Scanning your Mac... [%d/%d]\n", sp.completedCount, sp.totalSteps)
+	running := 0
+	for _, s := range sp.steps {
+		if s.status == "scanning" {
+			running++
+		}
+	}
+	header := fmt.Sprintf("  Scanning your Mac... [%d/%d]", sp.completedCount, sp.totalSteps)
+	if running > 1 {
+		header += scanCountStyle.Render(fmt.Sprintf(" %d in parallel", running))
+	}
+	fmt.Fprintf(os.Stderr, "\033[K%s\n", header)
 
 	for i, s := range sp.steps {
 		fmt.Fprintf(os.Stderr, "\033[K")
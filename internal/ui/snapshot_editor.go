@@ -41,6 +41,23 @@ type SnapshotEditorModel struct {
 	filteredItems []editorItem
 	filteredRefs  []editorFilteredRef
 	snapshot      *snapshot.Snapshot
+	title         string
+}
+
+// EditorHints let a caller hand the editor a superset of items to choose
+// from, such as every package seen across merged snapshots. Items are keyed
+// by package name, or by EditorPrefKey for macOS preferences.
+type EditorHints struct {
+	Title string
+	// Notes are shown next to items, e.g. "3/5".
+	Notes map[string]string
+	// Deselected items start unchecked.
+	Deselected map[string]bool
+}
+
+// EditorPrefKey is the EditorHints key for a macOS preference.
+func EditorPrefKey(p snapshot.MacOSPref) string {
+	return fmt.Sprintf("%s.%s=%s", p.Domain, p.Key, p.Value)
 }
 
 func NewSnapshotEditor(snap *snapshot.Snapshot) SnapshotEditorModel {
@@ -73,9 +90,35 @@ func NewSnapshotEditor(snap *snapshot.Snapshot) SnapshotEditorModel {
 		activeTab: 0,
 		cursor:    0,
 		snapshot:  snap,
+		title:     "📋 Snapshot Editor — Review your captured environment",
 	}
 }
 
+// NewSnapshotEditorWithHints is NewSnapshotEditor with items annotated and
+// pre-selected according to hints.
+func NewSnapshotEditorWithHints(snap *snapshot.Snapshot, hints EditorHints) SnapshotEditorModel {
+	m := NewSnapshotEditor(snap)
+	if hints.Title != "" {
+		m.title = hints.Title
+	}
+
+	keys := [][]string{snap.Packages.Formulae, snap.Packages.Casks, make([]string, len(snap.MacOSPrefs))}
+	for i, p := range snap.MacOSPrefs {
+		keys[2][i] = EditorPrefKey(p)
+	}
+	for t := range m.tabs {
+		for i := range m.tabs[t].items {
+			item := &m.tabs[t].items[i]
+			k := keys[t][i]
+			if note := hints.Notes[k]; note != "" {
+				item.description = strings.TrimSpace(item.description + " " + note)
+			}
+			item.selected = !hints.Deselected[k]
+		}
+	}
+	return m
+}
+
 func (m SnapshotEditorModel) Init() tea.Cmd {
 	return nil
 }
@@ -241,7 +284,7 @@ func (m SnapshotEditorModel) View() string {
 	var lines []string
 
 	lines = append(lines, "")
-	lines = append(lines, activeTabStyle.Render("📋 Snapshot Editor — Review your captured environment"))
+	lines = append(lines, activeTabStyle.Render(m.title))
 	lines = append(lines, "")
 
 	var tabs []string
@@ -459,7 +502,15 @@ func (m SnapshotEditorModel) totalSelected() int {
 // RunSnapshotEditor launches the snapshot editor TUI and returns the edited snapshot.
 // Returns (editedSnapshot, confirmed, error). If the user cancels, confirmed is false.
 func RunSnapshotEditor(snap *snapshot.Snapshot) (*snapshot.Snapshot, bool, error) {
-	model := NewSnapshotEditor(snap)
+	return runSnapshotEditor(snap, NewSnapshotEditor(snap))
+}
+
+// RunSnapshotEditorWithHints is RunSnapshotEditor with EditorHints applied.
+func RunSnapshotEditorWithHints(snap *snapshot.Snapshot, hints EditorHints) (*snapshot.Snapshot, bool, error) {
+	return runSnapshotEditor(snap, NewSnapshotEditorWithHints(snap, hints))
+}
+
+func runSnapshotEditor(snap *snapshot.Snapshot, model SnapshotEditorModel) (*snapshot.Snapshot, bool, error) {
 	p := tea.NewProgram(model, tea.WithAltScreen())
 
 	finalModel, err := p.Run()
@@ -481,8 +532,10 @@ func buildEditedSnapshot(original *snapshot.Snapshot, m *SnapshotEditorModel) *s
 		Version:       original.Version,
 		CapturedAt:    time.Now(),
 		Hostname:      original.Hostname,
+		Machine:       original.Machine,
 		Shell:         original.Shell,
 		Git:           original.Git,
+		Dotfiles:      original.Dotfiles,
 		DevTools:      original.DevTools,
 		MatchedPreset: original.MatchedPreset,
 		CatalogMatch:  original.CatalogMatch,
@@ -0,0 +1,29 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/openbootdotdev/openboot/internal/snapshot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildEditedSnapshot_KeepsMachineInfo(t *testing.T) {
+	original := &snapshot.Snapshot{
+		Version:  snapshot.CurrentVersion,
+		Hostname: "work-mbp",
+		Machine: &snapshot.MachineInfo{
+			OSVersion:      "15.1",
+			Arch:           "arm64",
+			HomebrewPrefix: "/opt/homebrew",
+		},
+		Packages: snapshot.PackageSnapshot{Formulae: []string{"git", "jq"}},
+	}
+
+	m := NewSnapshotEditor(original)
+	edited := buildEditedSnapshot(original, &m)
+
+	require.NotNil(t, edited.Machine)
+	assert.Equal(t, *original.Machine, *edited.Machine)
+	assert.Equal(t, []string{"git", "jq"}, edited.Packages.Formulae)
+}
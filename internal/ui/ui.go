@@ -183,3 +183,19 @@ func Input(title, placeholder string) (string, error) {
 	err := form.Run()
 	return value, err
 }
+
+func Password(title string) (string, error) {
+	var value string
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title(title).
+				EchoMode(huh.EchoModePassword).
+				Value(&value),
+		),
+	)
+
+	err := form.Run()
+	return value, err
+}
@@ -1,7 +1,10 @@
 package updater
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,6 +16,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/openbootdotdev/openboot/internal/snapshot"
 	"github.com/openbootdotdev/openboot/internal/ui"
 )
 
@@ -131,65 +135,202 @@ func AutoUpgrade(currentVersion string) {
 	}
 }
 
+// releaseURL is where the assets of the latest release are downloaded.
+var releaseURL = "https://github.com/openbootdotdev/openboot/releases/latest/download"
+
+// releaseKey is the base64 ed25519 public key that signs checksums.txt,
+// set at build time with
+// -ldflags "-X github.com/openbootdotdev/openboot/internal/updater.releaseKey=...".
+// Without it signatures aren't checked, only checksums.
+var releaseKey = ""
+
+const (
+	checksumsAsset = "checksums.txt"
+	// PrevSuffix is appended to the binary's path to keep the version an
+	// update replaced, for Rollback.
+	PrevSuffix = ".prev"
+)
+
+var errAssetNotFound = errors.New("not found")
+
 func DownloadAndReplace() error {
 	if IsHomebrewInstall() {
 		return fmt.Errorf("openboot is managed by Homebrew — run 'brew upgrade openboot' instead")
 	}
 
+	binPath, err := binaryPath()
+	if err != nil {
+		return err
+	}
+	return downloadAndReplace(binPath)
+}
+
+// downloadAndReplace replaces binPath with the latest release once it
+// matches the release checksums, keeping the old binary next to it.
+func downloadAndReplace(binPath string) error {
 	arch := runtime.GOARCH
 	if arch == "" {
 		arch = "arm64"
 	}
+	asset := fmt.Sprintf("openboot-darwin-%s", arch)
 
-	url := fmt.Sprintf("https://github.com/openbootdotdev/openboot/releases/latest/download/openboot-darwin-%s", arch)
+	checksums, err := fetchAsset(checksumsAsset)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", checksumsAsset, err)
+	}
+	if err := verifyChecksumsSignature(checksums); err != nil {
+		return err
+	}
+	want, err := checksumFor(checksums, asset)
+	if err != nil {
+		return err
+	}
 
-	client := getHTTPClient()
-	resp, err := client.Get(url)
+	data, err := fetchAsset(asset)
 	if err != nil {
 		return fmt.Errorf("download failed: %w", err)
 	}
-	defer resp.Body.Close()
+	if err := snapshot.VerifySHA256(data, want); err != nil {
+		return fmt.Errorf("downloaded %s doesn't match the release checksums: %w", asset, err)
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
+	tmpPath := binPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0755); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write binary: %w", err)
+	}
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to set permissions: %w", err)
 	}
 
+	prevPath := binPath + PrevSuffix
+	if err := os.Rename(binPath, prevPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to keep the previous binary: %w", err)
+	}
+	if err := os.Rename(tmpPath, binPath); err != nil {
+		os.Rename(prevPath, binPath)
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace binary: %w", err)
+	}
+
+	return nil
+}
+
+// Rollback swaps the binary with the one the last update replaced, so
+// rolling back twice returns to the updated version.
+func Rollback() error {
+	if IsHomebrewInstall() {
+		return fmt.Errorf("openboot is managed by Homebrew — use 'brew' to change versions instead")
+	}
+
+	binPath, err := binaryPath()
+	if err != nil {
+		return err
+	}
+	return rollback(binPath)
+}
+
+func rollback(binPath string) error {
+	prevPath := binPath + PrevSuffix
+	if _, err := os.Stat(prevPath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no previous version to roll back to (%s not found)", prevPath)
+		}
+		return fmt.Errorf("failed to read previous binary: %w", err)
+	}
+
+	swapPath := binPath + ".rollback"
+	if err := os.Rename(binPath, swapPath); err != nil {
+		return fmt.Errorf("failed to move the current binary aside: %w", err)
+	}
+	if err := os.Rename(prevPath, binPath); err != nil {
+		os.Rename(swapPath, binPath)
+		return fmt.Errorf("failed to restore the previous binary: %w", err)
+	}
+	if err := os.Rename(swapPath, prevPath); err != nil {
+		return fmt.Errorf("rolled back, but failed to keep the replaced binary: %w", err)
+	}
+	return nil
+}
+
+func binaryPath() (string, error) {
 	binPath, err := os.Executable()
 	if err != nil {
-		return fmt.Errorf("cannot determine binary path: %w", err)
+		return "", fmt.Errorf("cannot determine binary path: %w", err)
 	}
 
 	binPath, err = filepath.EvalSymlinks(binPath)
 	if err != nil {
-		return fmt.Errorf("cannot resolve binary path: %w", err)
+		return "", fmt.Errorf("cannot resolve binary path: %w", err)
 	}
+	return binPath, nil
+}
 
-	tmpPath := binPath + ".tmp"
-	f, err := os.Create(tmpPath)
+// fetchAsset downloads a release asset, returning errAssetNotFound for a
+// 404.
+func fetchAsset(name string) ([]byte, error) {
+	resp, err := getHTTPClient().Get(releaseURL + "/" + name)
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	if _, err := io.Copy(f, resp.Body); err != nil {
-		f.Close()
-		os.Remove(tmpPath)
-		return fmt.Errorf("failed to write binary: %w", err)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errAssetNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
 	}
-	f.Close()
+	return io.ReadAll(resp.Body)
+}
 
-	if err := os.Chmod(tmpPath, 0755); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("failed to set permissions: %w", err)
+// verifyChecksumsSignature checks checksums.txt against its detached
+// signature. Releases aren't required to be signed, but a signature that
+// is there must match releaseKey.
+func verifyChecksumsSignature(checksums []byte) error {
+	if releaseKey == "" {
+		return nil
+	}
+	key, err := snapshot.ParsePublicKey(releaseKey)
+	if err != nil {
+		return fmt.Errorf("invalid release key: %w", err)
 	}
 
-	if err := os.Rename(tmpPath, binPath); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("failed to replace binary: %w", err)
+	data, err := fetchAsset(checksumsAsset + snapshot.SignatureSuffix)
+	if errors.Is(err, errAssetNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download %s%s: %w", checksumsAsset, snapshot.SignatureSuffix, err)
 	}
 
+	sig, err := snapshot.ParseSignature(data)
+	if err != nil {
+		return err
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+	if sig.KeyID != snapshot.KeyID(key) || !ed25519.Verify(key, checksums, raw) {
+		return fmt.Errorf("%s is not signed by the OpenBoot release key", checksumsAsset)
+	}
 	return nil
 }
 
+// checksumFor finds asset's SHA-256 in a sha256sum-style checksums file.
+func checksumFor(checksums []byte, asset string) (string, error) {
+	for _, line := range strings.Split(string(checksums), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("%s has no checksum for %s", checksumsAsset, asset)
+}
+
 func notifyIfUpdateAvailable(currentVersion string) {
 	state, err := loadState()
 	if err != nil {
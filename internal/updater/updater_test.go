@@ -1,9 +1,22 @@
 package updater
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
+	"github.com/openbootdotdev/openboot/internal/snapshot"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIsNewerVersion(t *testing.T) {
@@ -127,3 +140,144 @@ func TestTrimVersionPrefix(t *testing.T) {
 		})
 	}
 }
+
+var releaseAsset = "openboot-darwin-" + runtime.GOARCH
+
+// startReleaseServer serves assets as the latest release and points the
+// updater at it.
+func startReleaseServer(t *testing.T, assets map[string][]byte) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := assets[r.URL.Path[1:]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+
+	origURL, origKey := releaseURL, releaseKey
+	releaseURL, releaseKey = srv.URL, ""
+	t.Cleanup(func() { releaseURL, releaseKey = origURL, origKey })
+}
+
+func sha256Line(data []byte, name string) string {
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), name)
+}
+
+func installedBinary(t *testing.T, content string) string {
+	t.Helper()
+	binPath := filepath.Join(t.TempDir(), "openboot")
+	require.NoError(t, os.WriteFile(binPath, []byte(content), 0755))
+	return binPath
+}
+
+func releaseAssets(binary []byte) map[string][]byte {
+	return map[string][]byte{
+		releaseAsset:    binary,
+		"checksums.txt": []byte(sha256Line([]byte("other"), "openboot-1.0.0.tar.gz") + sha256Line(binary, releaseAsset)),
+	}
+}
+
+func TestDownloadAndReplace_VerifiesAndKeepsPrevious(t *testing.T) {
+	startReleaseServer(t, releaseAssets([]byte("new binary")))
+	binPath := installedBinary(t, "old binary")
+
+	require.NoError(t, downloadAndReplace(binPath))
+
+	data, err := os.ReadFile(binPath)
+	require.NoError(t, err)
+	assert.Equal(t, "new binary", string(data))
+	info, err := os.Stat(binPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	prev, err := os.ReadFile(binPath + PrevSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, "old binary", string(prev))
+	assert.NoFileExists(t, binPath+".tmp")
+}
+
+func TestDownloadAndReplace_RejectsChecksumMismatch(t *testing.T) {
+	assets := releaseAssets([]byte("new binary"))
+	assets[releaseAsset] = []byte("tampered binary")
+	startReleaseServer(t, assets)
+	binPath := installedBinary(t, "old binary")
+
+	err := downloadAndReplace(binPath)
+	assert.ErrorContains(t, err, "doesn't match the release checksums")
+
+	data, readErr := os.ReadFile(binPath)
+	require.NoError(t, readErr)
+	assert.Equal(t, "old binary", string(data))
+	assert.NoFileExists(t, binPath+PrevSuffix)
+}
+
+func TestDownloadAndReplace_RequiresChecksums(t *testing.T) {
+	assets := releaseAssets([]byte("new binary"))
+	delete(assets, "checksums.txt")
+	startReleaseServer(t, assets)
+	assert.ErrorContains(t, downloadAndReplace(installedBinary(t, "old")), "checksums.txt")
+
+	assets = releaseAssets([]byte("new binary"))
+	assets["checksums.txt"] = []byte(sha256Line([]byte("x"), "openboot-linux-riscv"))
+	startReleaseServer(t, assets)
+	assert.ErrorContains(t, downloadAndReplace(installedBinary(t, "old")), "has no checksum for "+releaseAsset)
+}
+
+func TestDownloadAndReplace_Signature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signed := func(key ed25519.PrivateKey) map[string][]byte {
+		assets := releaseAssets([]byte("new binary"))
+		sig, err := json.Marshal(snapshot.Sign(assets["checksums.txt"], key))
+		require.NoError(t, err)
+		assets["checksums.txt.sig"] = sig
+		return assets
+	}
+
+	startReleaseServer(t, signed(priv))
+	releaseKey = snapshot.EncodePublicKey(pub)
+	assert.NoError(t, downloadAndReplace(installedBinary(t, "old")))
+
+	startReleaseServer(t, signed(otherPriv))
+	releaseKey = snapshot.EncodePublicKey(pub)
+	binPath := installedBinary(t, "old")
+	assert.ErrorContains(t, downloadAndReplace(binPath), "not signed by the OpenBoot release key")
+	assert.NoFileExists(t, binPath+PrevSuffix)
+
+	// Unsigned releases are still accepted on their checksums.
+	startReleaseServer(t, releaseAssets([]byte("new binary")))
+	releaseKey = snapshot.EncodePublicKey(pub)
+	assert.NoError(t, downloadAndReplace(installedBinary(t, "old")))
+}
+
+func TestRollback_SwapsWithPrevious(t *testing.T) {
+	startReleaseServer(t, releaseAssets([]byte("new binary")))
+	binPath := installedBinary(t, "old binary")
+	require.NoError(t, downloadAndReplace(binPath))
+
+	require.NoError(t, rollback(binPath))
+	data, err := os.ReadFile(binPath)
+	require.NoError(t, err)
+	assert.Equal(t, "old binary", string(data))
+
+	require.NoError(t, rollback(binPath))
+	data, err = os.ReadFile(binPath)
+	require.NoError(t, err)
+	assert.Equal(t, "new binary", string(data))
+	assert.NoFileExists(t, binPath+".rollback")
+}
+
+func TestRollback_WithoutPrevious(t *testing.T) {
+	binPath := installedBinary(t, "only binary")
+
+	assert.ErrorContains(t, rollback(binPath), "no previous version")
+	data, err := os.ReadFile(binPath)
+	require.NoError(t, err)
+	assert.Equal(t, "only binary", string(data))
+}